@@ -0,0 +1,49 @@
+package main
+
+import (
+	"log/slog"
+	"sync"
+)
+
+// levelToggler flips the log level between the configured level and debug,
+// so verbose logging can be switched on and off without a restart
+type levelToggler struct {
+	level *slog.LevelVar
+	base  slog.Level
+	mu    sync.Mutex
+}
+
+// newLevelToggler creates a levelToggler that reverts to the given base level
+func newLevelToggler(level *slog.LevelVar, base slog.Level) *levelToggler {
+	return &levelToggler{
+		level: level,
+		base:  base,
+	}
+}
+
+// setBase changes the level toggle reverts to, e.g. after a configuration reload
+func (t *levelToggler) setBase(base slog.Level) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.base = base
+}
+
+// toggle switches to debug, or back to the base level when debug is already active.
+// It returns the new level.
+func (t *levelToggler) toggle() slog.Level {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	previous := t.level.Level()
+	next := slog.LevelDebug
+	if previous == slog.LevelDebug {
+		next = t.base
+	}
+
+	t.level.Set(next)
+	// Logged as a warning so the change is visible at any of the usual levels
+	slog.Warn("Log level toggled", "from", previous, "to", next)
+
+	return next
+}
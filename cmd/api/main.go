@@ -3,8 +3,12 @@ package main
 import (
 	"context"
 	"errors"
+	"expvar"
 	"fmt"
+	"io"
+	"io/fs"
 	"log/slog"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
@@ -12,13 +16,25 @@ import (
 	"syscall"
 	"time"
 
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+
 	"github.com/kakkoyun/demo-web-service/config"
 	"github.com/kakkoyun/demo-web-service/handlers"
+	"github.com/kakkoyun/demo-web-service/store"
 )
 
 func main() {
-	// Initialize structured logger
-	logger := setupLogger()
+	cfg, err := loadConfig()
+	if err != nil {
+		slog.Error("Failed to load configuration", "error", err)
+		os.Exit(1)
+	}
+
+	// Initialize structured logger with a level and format that can change at runtime
+	logLevel := new(slog.LevelVar)
+	logLevel.Set(cfg.LogLevel)
+	logHandler := newReloadableHandler(newLogger(os.Stdout, cfg, logLevel).Handler())
+	logger := setupLogger(logHandler)
 
 	// Set up panic recovery for the entire application
 	defer func() {
@@ -43,75 +59,404 @@ func main() {
 	// Log version information
 	logger.Info("Starting application",
 		"version", buildInfo.Version,
+		"commit", buildInfo.Commit,
+		"buildTime", buildInfo.BuildTime,
 		"module", buildInfo.Module,
 		"goVersion", buildInfo.GoVersion,
 	)
 
-	// Load configuration
+	logger.Info("Configuration loaded", "serverPort", cfg.ServerPort, "logLevel", cfg.LogLevel)
+
+	// Shut down gracefully on SIGINT or SIGTERM
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// Maintenance mode can be switched on and off by reloading the configuration
+	maintenance := handlers.NewMaintenance()
+	maintenance.Set(cfg.MaintenanceMode, cfg.MaintenanceRetryAfter)
+
+	// Toggle debug logging on SIGUSR2 and reload the configuration on SIGHUP
+	toggler := newLevelToggler(logLevel, cfg.LogLevel)
+	reloader := &configReloader{w: os.Stdout, level: logLevel, handler: logHandler, toggler: toggler, maintenance: maintenance}
+	stopSignals := handleSignals(toggler, reloader)
+	defer stopSignals()
+
+	if err := run(ctx, cfg, logger, logLevel, maintenance); err != nil {
+		logger.Error("Server exited with an error", "error", err)
+		os.Exit(1)
+	}
+
+	logger.Info("Server exited properly")
+}
+
+// loadConfig loads the configuration from the environment, or from a file when
+// CONFIG_FILE is set, and validates it
+func loadConfig() (*config.Config, error) {
 	cfg := config.LoadConfig()
-	logger.Info("Configuration loaded", "serverPort", cfg.ServerPort)
+	if path := os.Getenv("CONFIG_FILE"); path != "" {
+		var err error
+		if cfg, err = config.LoadConfigFromFile(path); err != nil {
+			return nil, fmt.Errorf("loading configuration file %s: %w", path, err)
+		}
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// handleSignals toggles debug logging on SIGUSR2 and reloads the runtime
+// settings of the configuration on SIGHUP, until the returned function is called.
+// A configuration that fails to load or validate is logged and ignored.
+func handleSignals(toggler *levelToggler, reloader *configReloader) (stop func()) {
+	toggle := make(chan os.Signal, 1)
+	notifyLevelToggle(toggle)
+	reload := make(chan os.Signal, 1)
+	notifyReload(reload)
+
+	done := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case <-toggle:
+				toggler.toggle()
+			case <-reload:
+				cfg, err := loadConfig()
+				if err != nil {
+					slog.Error("Failed to reload configuration, keeping the current one", "error", err)
+					continue
+				}
+				reloader.reload(cfg)
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	return func() {
+		signal.Stop(toggle)
+		signal.Stop(reload)
+		close(done)
+	}
+}
+
+// drainLogInterval is how often the requests still in flight are logged during shutdown
+const drainLogInterval = time.Second
+
+// run serves the API configured by cfg until ctx is done, then shuts down gracefully.
+// Requests are refused while maintenance is enabled, except for health checks.
+// It returns an error when the server can't be set up or fails to start,
+// after running the same shutdown steps.
+func run(ctx context.Context, cfg *config.Config, logger *slog.Logger, logLevel *slog.LevelVar, maintenance *handlers.Maintenance) error {
+	// Simulate random failures for demos unless disabled
+	var faults handlers.FaultInjector = handlers.NoFaults{}
+	if cfg.FaultInjection {
+		faults = handlers.NewProbabilisticFaults(nil)
+		if !cfg.HomeChaos {
+			// Keep the root endpoint stable for uptime checks
+			faults = handlers.WithoutFaults(faults, handlers.OpHome)
+		}
+	}
+
+	// Config.Validate has checked these, so an error means it is out of date
+	duplicatePolicy, err := store.ParseDuplicatePolicy(cfg.ImportDuplicatePolicy)
+	if err != nil {
+		return fmt.Errorf("IMPORT_DUPLICATE_POLICY: %w", err)
+	}
+	ids, err := store.NewIDGenerator(cfg.UserIDStrategy)
+	if err != nil {
+		return fmt.Errorf("USER_ID_STRATEGY: %w", err)
+	}
+	readinessPolicy, err := handlers.ParseReadinessPolicy(cfg.ReadinessPolicy)
+	if err != nil {
+		return fmt.Errorf("READINESS_POLICY: %w", err)
+	}
+
+	// Restore the users of a previous run when snapshots are enabled
+	users := store.NewDemoStoreWithIDs(ids)
+	if cfg.SnapshotFile != "" {
+		err := users.LoadSnapshot(cfg.SnapshotFile)
+		switch {
+		case errors.Is(err, fs.ErrNotExist):
+			logger.Info("No user store snapshot yet, starting with demo users", "path", cfg.SnapshotFile)
+		case err != nil:
+			return fmt.Errorf("loading user store snapshot: %w", err)
+		default:
+			logger.Info("User store restored from snapshot", "path", cfg.SnapshotFile)
+		}
+	}
 
-	// Initialize router using standard lib
-	mux := http.NewServeMux()
+	// Count simulated database errors for chaos-testing dashboards
+	var dbErrors *expvar.Map
+	if cfg.SimulatedErrorMetrics {
+		dbErrors = publishedMap("simulated_db_errors")
+	}
+
+	// Stop querying the database for a while when it keeps failing
+	var breaker *handlers.CircuitBreaker
+	if cfg.BreakerThreshold > 0 {
+		breaker = handlers.NewCircuitBreaker(cfg.BreakerThreshold, cfg.BreakerCooldown)
+	}
+
+	// Cache user lookups, counting cache hits and misses for /debug/vars
+	var apiUsers store.UserStore = users
+	if cfg.UserCacheSize > 0 {
+		cache := store.NewCachingUserStore(users, cfg.UserCacheSize, cfg.UserCacheTTL)
+		stats := publishedMap("user_cache")
+		stats.Set("hits", expvar.Func(func() any { return cache.Stats().Hits }))
+		stats.Set("misses", expvar.Func(func() any { return cache.Stats().Misses }))
+		apiUsers = cache
+	}
+
+	// Trace requests and store calls when an OTLP endpoint is configured
+	var tracerProvider *sdktrace.TracerProvider
+	if cfg.OTLPEndpoint != "" {
+		tracerProvider, err = newTracerProvider(ctx, cfg.OTLPEndpoint, getBuildInfo().Version)
+		if err != nil {
+			return err
+		}
+		apiUsers = store.NewTracingUserStore(apiUsers, tracerProvider)
+		logger.Info("Tracing enabled", "endpoint", cfg.OTLPEndpoint)
+	}
+
+	// Hold user bodies to a stricter contract when a schema is configured
+	var userSchema *handlers.SchemaValidator
+	if cfg.UserSchemaFile != "" {
+		schema, err := os.ReadFile(cfg.UserSchemaFile)
+		if err != nil {
+			return fmt.Errorf("reading user schema: %w", err)
+		}
+		userSchema, err = handlers.NewSchemaValidator(schema)
+		if err != nil {
+			return fmt.Errorf("loading user schema %s: %w", cfg.UserSchemaFile, err)
+		}
+		logger.Info("User schema validation enabled", "path", cfg.UserSchemaFile)
+	}
+
+	// Announce created users to a webhook receiver when one is configured
+	var webhooks *handlers.WebhookDispatcher
+	if cfg.WebhookURL != "" {
+		webhooks = handlers.NewWebhookDispatcher(cfg.WebhookURL, cfg.WebhookQueueSize, cfg.WebhookMaxRetries, cfg.WebhookTimeout)
+		logger.Info("Webhooks enabled", "url", cfg.WebhookURL)
+	}
 
-	// Set up routes with Go 1.22 pattern syntax
-	mux.HandleFunc("GET /", handlers.HomeHandler)
-	mux.HandleFunc("GET /api/health", handlers.HealthCheckHandler)
-	mux.HandleFunc("GET /api/users", handlers.GetUsersHandler)
-	mux.HandleFunc("POST /api/users", handlers.CreateUserHandler)
-	mux.HandleFunc("GET /api/users/{id}", handlers.GetUserHandler)
-	// Add version endpoint
-	mux.HandleFunc("GET /api/version", versionHandler)
+	// Set up the API with its dependencies
+	api := handlers.NewAPI(apiUsers, logger,
+		handlers.WithFaultInjector(faults),
+		handlers.WithDBErrorMetrics(dbErrors),
+		handlers.WithRetries(cfg.MaxRetries, handlers.NewRetryBudget(cfg.RetryBudgetRPS, cfg.RetryBudgetBurst)),
+		handlers.WithRetryBackoff(cfg.RetryBackoff),
+		handlers.WithCircuitBreaker(breaker),
+		handlers.WithVersion(getBuildInfo().Version),
+		handlers.WithDuplicatePolicy(duplicatePolicy),
+		handlers.WithMaxBodyBytes(int64(cfg.MaxBodyBytes)),
+		handlers.WithImportMaxBodyBytes(int64(cfg.ImportMaxBodyBytes)),
+		handlers.WithMaxEventSubscribers(cfg.MaxEventSubscribers),
+		handlers.WithIdempotencyTTL(cfg.IdempotencyTTL),
+		handlers.WithDebugEndpoints(cfg.DebugEndpoints),
+		handlers.WithPublicIDPaths(cfg.UserIDStrategy == "uuid"), // Opaque IDs would be pointless if sequential ones still worked
+		handlers.WithUserSchema(userSchema),
+		handlers.WithWebhooks(webhooks),
+	)
+
+	// Track connections and drain progress, and stop reporting ready on shutdown
+	shutdown := handlers.NewShutdownTracker()
+	api.Readiness().RegisterFatal("shutdown", shutdown) // Even fail-if-all must stop traffic to a draining instance
+
+	// An open circuit only degrades the service, other endpoints keep working
+	if breaker != nil {
+		api.Readiness().RegisterNonCritical("database", breaker)
+	}
+
+	api.Readiness().SetPolicy(readinessPolicy)
+
+	// Add the application-level endpoints to the API routes
+	api.Handle("GET", "/api/version", "VersionHandler", versionHandler)
+	api.Handle("GET", "/api/shutdown-status", "ShutdownStatusHandler", shutdown.ServeHTTP)
+	api.Handle("PUT", "/debug/loglevel", "LogLevelHandler", handlers.LogLevelHandler(logLevel, cfg.DebugEndpoints))
+	if cfg.DebugEndpoints {
+		// expvar also publishes the command line and memory statistics
+		api.Handle("GET", "/debug/vars", "ExpvarHandler", expvar.Handler().ServeHTTP)
+	}
+	if cfg.EnablePprof {
+		registerPprof(api)
+		logger.Warn("Profiling endpoints enabled", "path", "/debug/pprof/")
+	}
 
 	logger.Info("Routes configured")
 
 	// Apply middleware
-	var handler http.Handler = mux
-	handler = handlers.LoggingMiddleware(handler)
-	handler = recoverMiddleware(handler) // Add panic recovery with stack traces
+	handler := api.Routes()
+	handler = handlers.Unless(api.Streaming, handlers.MethodTimeoutMiddleware(cfg.ReadHandlerTimeout, cfg.WriteHandlerTimeout))(handler)
+	handler = handlers.ContentTypeMiddleware("application/json", handlers.JSONPatchContentType)(handler)
+	handler = handlers.PrettyJSONMiddleware(cfg.PrettyJSON)(handler)
+	handler = handlers.HeadMiddleware(handler) // GET routes also serve HEAD
+	handler = handlers.CompressionMiddleware(cfg.CompressionMinSize)(handler)
+	handler = handlers.Unless(api.Streaming, handlers.MaxInFlightMiddleware(cfg.MaxInFlight))(handler) // Inside recovery, which it lets panics through to
+	if len(cfg.BasicAuthUsers) > 0 {
+		handler = handlers.Unless(api.ReadOnly, handlers.BasicAuthMiddleware(cfg.BasicAuthUsers))(handler) // GraphQL queries are reads despite being POSTs
+		logger.Info("Basic authentication required for writes", "users", len(cfg.BasicAuthUsers))
+	}
+	if cfg.JWTSecret != "" {
+		handler = handlers.PathsOnly(cfg.JWTProtectedPaths, handlers.JWTAuthMiddleware([]byte(cfg.JWTSecret)))(handler)
+		logger.Info("JWT authentication required", "paths", cfg.JWTProtectedPaths)
+	}
+	if len(cfg.APIKeys) > 0 {
+		handler = handlers.PathsOnly(cfg.APIKeyProtectedPaths, handlers.APIKeyMiddleware(cfg.APIKeys))(handler)
+		logger.Info("API key authentication required", "paths", cfg.APIKeyProtectedPaths, "keys", len(cfg.APIKeys))
+	}
+	if cfg.RateLimitRPS > 0 {
+		// Outside authentication, so that guessing credentials is throttled too
+		handler = handlers.RateLimitMiddleware(cfg.RateLimitRPS, cfg.RateLimitBurst)(handler)
+	}
+	handler = maintenance.Middleware(handler)                               // Before authentication, which can't help during maintenance
+	handler = handlers.LoggingMiddleware(cfg.SlowRequestThreshold)(handler) // Outside compression, so it logs both wire and uncompressed sizes
+	handler = handlers.SecureHeadersMiddleware(handlers.SecureHeaders{
+		ContentTypeOptions:    cfg.ContentTypeOptions,
+		FrameOptions:          cfg.FrameOptions,
+		ReferrerPolicy:        cfg.ReferrerPolicy,
+		ContentSecurityPolicy: cfg.ContentSecurityPolicy,
+	})(handler)
+	handler = handlers.ResponseTimeMiddleware(handler)          // Time the whole chain inside recovery
+	handler = recoverMiddleware(handler)                        // Add panic recovery with stack traces
+	handler = handlers.ContextLoggerMiddleware(logger)(handler) // Request-scoped logger for handlers
+	if tracerProvider != nil {
+		handler = handlers.TracingMiddleware(tracerProvider, tracePropagator())(handler)
+	}
+	handler = handlers.RequestIDMiddleware(handler)
+	handler = shutdown.Middleware(handler) // Count every request in flight for the shutdown drain
 
 	// Configure server
-	srv := &http.Server{
-		Addr:         ":" + cfg.ServerPort,
-		Handler:      handler,
-		ReadTimeout:  cfg.ReadTimeout,
-		WriteTimeout: cfg.WriteTimeout,
-		IdleTimeout:  cfg.IdleTimeout,
-	}
+	srv := cfg.NewServer(handler)
+	srv.ConnState = shutdown.ConnState
+	srv.RegisterOnShutdown(api.CloseEventStreams)
 
-	// Start server in a goroutine
+	// Start server in a goroutine, reporting why it stopped
+	serveErrs := make(chan error, 1)
 	go func() {
-		logger.Info("Starting server", "port", cfg.ServerPort)
-		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
-			wrappedErr := fmt.Errorf("server failed to start: %w", err)
-			logger.Error("Server failed to start",
-				"error", wrappedErr)
-			os.Exit(1)
-		}
+		logger.Info("Starting server", "network", cfg.ListenNetwork, "addr", srv.Addr, "tls", cfg.TLSEnabled())
+		serveErrs <- listenAndServe(srv, cfg)
 	}()
 
-	// Graceful shutdown
-	c := make(chan os.Signal, 1)
-	signal.Notify(c, os.Interrupt, syscall.SIGTERM)
+	// Save the user store periodically until shutdown
+	snapshotCtx, stopSnapshots := context.WithCancel(context.Background())
+	var snapshotsDone <-chan struct{}
+	if cfg.SnapshotFile != "" {
+		snapshotsDone = runSnapshots(snapshotCtx, users, cfg.SnapshotFile, cfg.SnapshotInterval, logger)
+	}
 
-	// Block until signal is received
-	<-c
-	logger.Info("Server is shutting down...")
+	// Block until asked to shut down, or until the server fails, e.g. because
+	// its port is already in use; either way the shutdown hooks below still run
+	var serveErr error
+	select {
+	case <-ctx.Done():
+		logger.Info("Server is shutting down...")
+	case err := <-serveErrs:
+		serveErr = fmt.Errorf("server failed to start: %w", err)
+	}
+	shutdown.Begin()
+
+	// Keep answering new requests for a while, refusing all but health checks
+	// and the shutdown status, so load balancers see the instance go unready
+	// before its listeners close
+	if serveErr == nil && cfg.ShutdownGracePeriod > 0 {
+		logger.Info("Refusing new requests before closing listeners", "grace_period", cfg.ShutdownGracePeriod)
+		time.Sleep(cfg.ShutdownGracePeriod)
+	}
 
 	// Create a deadline to wait for
-	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
 	defer cancel()
 
+	// Report the requests left to drain until they finish or the deadline passes
+	drainCtx, stopDrainLog := context.WithCancel(shutdownCtx)
+	drainLogged := make(chan struct{})
+	go func() {
+		defer close(drainLogged)
+		shutdown.LogDrain(drainCtx, logger, drainLogInterval)
+	}()
+
 	// Doesn't block if no connections, but will otherwise wait
 	// until the timeout deadline
-	if err := srv.Shutdown(ctx); err != nil {
+	if err := srv.Shutdown(shutdownCtx); err != nil {
 		wrappedErr := fmt.Errorf("server forced to shutdown: %w", err)
 		logger.Error("Server forced to shutdown",
 			"error", wrappedErr)
 	}
+	stopDrainLog()
+	<-drainLogged
 
-	logger.Info("Server exited properly")
-	os.Exit(0)
+	// No requests are left to create users, so let pending webhooks drain
+	// within the same deadline
+	if webhooks != nil {
+		webhooks.Close(shutdownCtx)
+	}
+
+	// Wait for work spawned by requests, within the same deadline
+	if err := api.Background().Wait(shutdownCtx); err != nil {
+		logger.Error("Background work did not finish before shutdown", "error", err)
+	}
+
+	// Save the final state of the user store
+	stopSnapshots()
+	if snapshotsDone != nil {
+		<-snapshotsDone
+	}
+
+	// Export the spans of the last requests
+	if tracerProvider != nil {
+		if err := tracerProvider.Shutdown(shutdownCtx); err != nil {
+			logger.Error("Failed to flush traces", "error", err)
+		}
+	}
+
+	return serveErr
+}
+
+// publishedMap returns the expvar map published under name, publishing a new
+// one unless run has done so before
+func publishedMap(name string) *expvar.Map {
+	if m, ok := expvar.Get(name).(*expvar.Map); ok {
+		return m
+	}
+	return expvar.NewMap(name)
+}
+
+// listenAndServe starts srv on the configured network, serving HTTPS when a
+// TLS certificate and key are configured.
+// Like the http.Server methods it wraps, it returns http.ErrServerClosed after a shutdown.
+func listenAndServe(srv *http.Server, cfg *config.Config) error {
+	listener, err := listen(cfg.ListenNetwork, srv.Addr)
+	if err != nil {
+		return err
+	}
+
+	if cfg.TLSEnabled() {
+		return srv.ServeTLS(listener, cfg.TLSCertFile, cfg.TLSKeyFile)
+	}
+	return srv.Serve(listener)
+}
+
+// listen listens on addr over TCP, or on the Unix domain socket at path addr
+// when network is "unix". A socket file left behind by an earlier run is
+// replaced, and the new one is removed when the listener is closed on shutdown.
+func listen(network, addr string) (net.Listener, error) {
+	if network != "unix" {
+		return net.Listen("tcp", addr)
+	}
+
+	if info, err := os.Lstat(addr); err == nil && info.Mode()&os.ModeSocket != 0 {
+		if err := os.Remove(addr); err != nil {
+			return nil, fmt.Errorf("removing stale socket: %w", err)
+		}
+	}
+
+	listener, err := net.Listen("unix", addr)
+	if err != nil {
+		return nil, err
+	}
+	listener.(*net.UnixListener).SetUnlinkOnClose(true)
+	return listener, nil
 }
 
 // recoverMiddleware is a middleware that recovers from panics and logs the error with stack trace
@@ -119,24 +464,14 @@ func recoverMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		defer func() {
 			if rec := recover(); rec != nil {
-				// Get stack trace
-				stackTrace := string(debug.Stack())
-
-				// Create an error with the panic details
-				err := fmt.Errorf("panic in HTTP handler: %v", rec)
-
 				// Log the error with stack trace
-				slog.Error("HTTP handler panic recovered",
-					"error", err,
-					"panic", rec,
+				handlers.LogPanic(slog.Default(), "HTTP handler panic recovered", "HTTP handler", rec,
+					"request_id", handlers.RequestIDFromContext(r.Context()),
 					"url", r.URL.String(),
-					"method", r.Method,
-					"stack_trace", stackTrace)
+					"method", r.Method)
 
 				// Return a 500 error to the client
 				http.Error(w, "Internal Server Error", http.StatusInternalServerError)
-
-				// In a real app, this would also be sent to your error tracking service
 			}
 		}()
 
@@ -144,21 +479,58 @@ func recoverMiddleware(next http.Handler) http.Handler {
 	})
 }
 
+// Build information set at link time, which takes precedence over the
+// information the go command embeds in the binary, e.g.
+//
+//	go build -ldflags "-X main.Version=v1.2.3 -X main.Commit=$(git rev-parse HEAD)"
+var (
+	Version   string
+	Commit    string
+	BuildTime string
+)
+
 // VersionInfo stores application version information
 type VersionInfo struct {
-	Version   string `json:"version"`
-	Module    string `json:"module"`
-	GoVersion string `json:"goVersion"`
+	// Dependencies maps the path of every module compiled into the binary to
+	// its version; JSON encodes it sorted by path
+	Dependencies map[string]string `json:"dependencies,omitempty"`
+	Version      string            `json:"version"`
+	Module       string            `json:"module"`
+	GoVersion    string            `json:"goVersion"`
+	Commit       string            `json:"commit"`
+	BuildTime    string            `json:"buildTime"`
+	Dirty        bool              `json:"dirty"`
 }
 
 // getBuildInfo retrieves the build information from the binary
 func getBuildInfo() VersionInfo {
-	info, ok := debug.ReadBuildInfo()
-	if !ok {
+	return withLinkTimeInfo(versionInfoFrom(debug.ReadBuildInfo()))
+}
+
+// withLinkTimeInfo overrides version information with the values set at link time
+func withLinkTimeInfo(info VersionInfo) VersionInfo {
+	if Version != "" {
+		info.Version = Version
+	}
+	if Commit != "" {
+		info.Commit = Commit
+	}
+	if BuildTime != "" {
+		info.BuildTime = BuildTime
+	}
+	return info
+}
+
+// versionInfoFrom extracts version information from the given build info,
+// falling back to defaults for anything that isn't available
+func versionInfoFrom(info *debug.BuildInfo, ok bool) VersionInfo {
+	if !ok || info == nil {
 		return VersionInfo{
 			Version:   "dev",
 			Module:    "unknown",
 			GoVersion: "unknown",
+			Commit:    "unknown",
+			BuildTime: "unknown",
 		}
 	}
 
@@ -168,14 +540,55 @@ func getBuildInfo() VersionInfo {
 	versionInfo.Version = info.Main.Version
 	versionInfo.GoVersion = info.GoVersion
 
+	// Extract VCS information stamped by the go command (if built from a checkout)
+	for _, setting := range info.Settings {
+		switch setting.Key {
+		case "vcs.revision":
+			versionInfo.Commit = setting.Value
+		case "vcs.modified":
+			versionInfo.Dirty = setting.Value == "true"
+		}
+	}
+
 	// If version isn't set (common in development builds), use a default
 	if versionInfo.Version == "" {
 		versionInfo.Version = "dev"
 	}
 
+	// Builds outside of a VCS checkout (or with -buildvcs=false) have no revision
+	if versionInfo.Commit == "" {
+		versionInfo.Commit = "unknown"
+	}
+
+	// The go command doesn't record when a binary was built
+	versionInfo.BuildTime = "unknown"
+
+	versionInfo.Dependencies = dependencyVersions(info.Deps)
+
 	return versionInfo
 }
 
+// dependencyVersions maps the paths of deps to their versions, or to those of
+// their replacements; a replacement by a local directory is reported by its path
+func dependencyVersions(deps []*debug.Module) map[string]string {
+	if len(deps) == 0 {
+		return nil
+	}
+
+	versions := make(map[string]string, len(deps))
+	for _, dep := range deps {
+		version := dep.Version
+		if r := dep.Replace; r != nil {
+			version = r.Version
+			if version == "" {
+				version = r.Path
+			}
+		}
+		versions[dep.Path] = version
+	}
+	return versions
+}
+
 // versionHandler returns the application version information
 func versionHandler(w http.ResponseWriter, r *http.Request) {
 	slog.Debug("Version information requested", "remote_addr", r.RemoteAddr)
@@ -185,24 +598,8 @@ func versionHandler(w http.ResponseWriter, r *http.Request) {
 	handlers.JSONResponse(w, http.StatusOK, buildInfo)
 }
 
-// setupLogger configures and returns a structured logger
-func setupLogger() *slog.Logger {
-	// Define log level based on environment (could use an environment variable)
-	var logLevel slog.Level
-	if os.Getenv("APP_ENV") == "production" {
-		logLevel = slog.LevelInfo
-	} else {
-		logLevel = slog.LevelDebug
-	}
-
-	// Create a JSON handler for structured logging
-	opts := &slog.HandlerOptions{
-		Level: logLevel,
-		// Add source code location to log entries in development
-		AddSource: os.Getenv("APP_ENV") != "production",
-	}
-
-	handler := slog.NewJSONHandler(os.Stdout, opts)
+// setupLogger returns a structured logger writing to handler
+func setupLogger(handler slog.Handler) *slog.Logger {
 	logger := slog.New(handler)
 
 	// Set as default logger for compatibility with standard library
@@ -210,3 +607,24 @@ func setupLogger() *slog.Logger {
 
 	return logger
 }
+
+// newLogger creates a logger writing to w in the configured format.
+// JSON is used unless the text format is explicitly requested.
+func newLogger(w io.Writer, cfg *config.Config, level slog.Leveler) *slog.Logger {
+	opts := &slog.HandlerOptions{
+		Level: level,
+		// Add source code location to log entries in development
+		AddSource: !cfg.IsProduction(),
+	}
+
+	var handler slog.Handler
+	if cfg.LogFormat == "text" {
+		// Human-readable output for local development
+		handler = slog.NewTextHandler(w, opts)
+	} else {
+		// Structured JSON output for log aggregation
+		handler = slog.NewJSONHandler(w, opts)
+	}
+
+	return slog.New(handler)
+}
@@ -0,0 +1,257 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"runtime/debug"
+	"strings"
+	"testing"
+
+	"github.com/kakkoyun/demo-web-service/config"
+)
+
+func TestVersionInfoFrom(t *testing.T) {
+	testCases := []struct {
+		name     string
+		info     *debug.BuildInfo
+		ok       bool
+		expected VersionInfo
+	}{
+		{
+			name: "VCS Settings",
+			info: &debug.BuildInfo{
+				GoVersion: "go1.24.0",
+				Main:      debug.Module{Path: "github.com/kakkoyun/demo-web-service", Version: "v1.2.3"},
+				Settings: []debug.BuildSetting{
+					{Key: "vcs", Value: "git"},
+					{Key: "vcs.revision", Value: "0123456789abcdef"},
+					{Key: "vcs.modified", Value: "true"},
+				},
+			},
+			ok: true,
+			expected: VersionInfo{
+				Version:   "v1.2.3",
+				Module:    "github.com/kakkoyun/demo-web-service",
+				GoVersion: "go1.24.0",
+				Commit:    "0123456789abcdef",
+				BuildTime: "unknown",
+				Dirty:     true,
+			},
+		},
+		{
+			name: "Clean Checkout",
+			info: &debug.BuildInfo{
+				GoVersion: "go1.24.0",
+				Main:      debug.Module{Path: "github.com/kakkoyun/demo-web-service"},
+				Settings: []debug.BuildSetting{
+					{Key: "vcs.revision", Value: "fedcba9876543210"},
+					{Key: "vcs.modified", Value: "false"},
+				},
+			},
+			ok: true,
+			expected: VersionInfo{
+				Version:   "dev",
+				Module:    "github.com/kakkoyun/demo-web-service",
+				GoVersion: "go1.24.0",
+				Commit:    "fedcba9876543210",
+				BuildTime: "unknown",
+			},
+		},
+		{
+			name: "No VCS Settings",
+			info: &debug.BuildInfo{
+				GoVersion: "go1.24.0",
+				Main:      debug.Module{Path: "github.com/kakkoyun/demo-web-service"},
+			},
+			ok: true,
+			expected: VersionInfo{
+				Version:   "dev",
+				Module:    "github.com/kakkoyun/demo-web-service",
+				GoVersion: "go1.24.0",
+				Commit:    "unknown",
+				BuildTime: "unknown",
+			},
+		},
+		{
+			name: "Dependencies",
+			info: &debug.BuildInfo{
+				GoVersion: "go1.24.0",
+				Main:      debug.Module{Path: "github.com/kakkoyun/demo-web-service"},
+				Deps: []*debug.Module{
+					{Path: "braces.dev/errtrace", Version: "v0.3.0"},
+					{Path: "golang.org/x/time", Version: "v0.5.0", Replace: &debug.Module{Path: "golang.org/x/time", Version: "v0.6.0"}},
+					{Path: "example.com/local", Version: "v1.0.0", Replace: &debug.Module{Path: "../local"}},
+				},
+			},
+			ok: true,
+			expected: VersionInfo{
+				Version:   "dev",
+				Module:    "github.com/kakkoyun/demo-web-service",
+				GoVersion: "go1.24.0",
+				Commit:    "unknown",
+				BuildTime: "unknown",
+				Dependencies: map[string]string{
+					"braces.dev/errtrace": "v0.3.0",
+					"golang.org/x/time":   "v0.6.0",
+					"example.com/local":   "../local",
+				},
+			},
+		},
+		{
+			name: "No Build Info",
+			info: nil,
+			ok:   false,
+			expected: VersionInfo{
+				Version:   "dev",
+				Module:    "unknown",
+				GoVersion: "unknown",
+				Commit:    "unknown",
+				BuildTime: "unknown",
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := versionInfoFrom(tc.info, tc.ok)
+			if !reflect.DeepEqual(got, tc.expected) {
+				t.Errorf("wrong version info: got %+v want %+v", got, tc.expected)
+			}
+		})
+	}
+}
+
+func TestVersionHandlerLinkTimeInfo(t *testing.T) {
+	for variable, value := range map[*string]string{
+		&Version:   "v9.8.7",
+		&Commit:    "abcdef0123456789",
+		&BuildTime: "2025-01-02T03:04:05Z",
+	} {
+		previous := *variable
+		*variable = value
+		t.Cleanup(func() { *variable = previous })
+	}
+
+	req := httptest.NewRequest("GET", "/api/version", nil)
+	rr := httptest.NewRecorder()
+	versionHandler(rr, req)
+
+	if status := rr.Code; status != http.StatusOK {
+		t.Fatalf("handler returned wrong status code: got %v want %v", status, http.StatusOK)
+	}
+
+	var got VersionInfo
+	if err := json.Unmarshal(rr.Body.Bytes(), &got); err != nil {
+		t.Fatalf("could not parse response body: %v", err)
+	}
+	if got.Version != "v9.8.7" || got.Commit != "abcdef0123456789" || got.BuildTime != "2025-01-02T03:04:05Z" {
+		t.Errorf("handler returned wrong version info: got %+v", got)
+	}
+}
+
+func TestVersionHandlerDependencies(t *testing.T) {
+	req := httptest.NewRequest("GET", "/api/version", nil)
+	rr := httptest.NewRecorder()
+	versionHandler(rr, req)
+
+	var got VersionInfo
+	if err := json.Unmarshal(rr.Body.Bytes(), &got); err != nil {
+		t.Fatalf("could not parse response body: %v", err)
+	}
+	if len(got.Dependencies) == 0 {
+		t.Fatal("handler returned no dependencies")
+	}
+	if version := got.Dependencies["braces.dev/errtrace"]; !strings.HasPrefix(version, "v") {
+		t.Errorf("handler returned wrong version for braces.dev/errtrace: got %q in %v", version, got.Dependencies)
+	}
+}
+
+func TestLevelToggler(t *testing.T) {
+	level := new(slog.LevelVar)
+	level.Set(slog.LevelWarn)
+	toggler := newLevelToggler(level, slog.LevelWarn)
+
+	// First toggle switches to debug
+	if got := toggler.toggle(); got != slog.LevelDebug || level.Level() != slog.LevelDebug {
+		t.Errorf("level did not flip to debug: got %v", level.Level())
+	}
+
+	// Second toggle reverts to the configured level
+	if got := toggler.toggle(); got != slog.LevelWarn || level.Level() != slog.LevelWarn {
+		t.Errorf("level did not revert: got %v want %v", level.Level(), slog.LevelWarn)
+	}
+
+	// And the cycle repeats
+	toggler.toggle()
+	if level.Level() != slog.LevelDebug {
+		t.Errorf("level did not flip to debug again: got %v", level.Level())
+	}
+}
+
+func TestNewLogger(t *testing.T) {
+	testCases := []struct {
+		name        string
+		format      string
+		environment string
+		check       func(t *testing.T, line string)
+	}{
+		{
+			name:   "JSON",
+			format: "json",
+			check: func(t *testing.T, line string) {
+				var record map[string]any
+				if err := json.Unmarshal([]byte(line), &record); err != nil {
+					t.Fatalf("log line is not JSON: %q", line)
+				}
+				if record["msg"] != "hello" || record["key"] != "value" {
+					t.Errorf("log record has wrong fields: %v", record)
+				}
+				if _, ok := record["source"]; !ok {
+					t.Errorf("log record has no source outside production: %v", record)
+				}
+			},
+		},
+		{
+			name:   "Text",
+			format: "text",
+			check: func(t *testing.T, line string) {
+				if json.Valid([]byte(line)) {
+					t.Fatalf("log line is JSON, expected text: %q", line)
+				}
+				if !strings.Contains(line, "level=INFO") || !strings.Contains(line, "msg=hello") || !strings.Contains(line, "key=value") {
+					t.Errorf("log line has wrong shape: %q", line)
+				}
+			},
+		},
+		{
+			name:        "Unknown Format Defaults To JSON",
+			format:      "xml",
+			environment: "production",
+			check: func(t *testing.T, line string) {
+				var record map[string]any
+				if err := json.Unmarshal([]byte(line), &record); err != nil {
+					t.Fatalf("log line is not JSON: %q", line)
+				}
+				if _, ok := record["source"]; ok {
+					t.Errorf("log record has source in production: %v", record)
+				}
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			cfg := &config.Config{LogFormat: tc.format, Environment: tc.environment}
+
+			logger := newLogger(&buf, cfg, slog.LevelInfo)
+			logger.Info("hello", "key", "value")
+
+			tc.check(t, strings.TrimSpace(buf.String()))
+		})
+	}
+}
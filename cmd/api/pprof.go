@@ -0,0 +1,19 @@
+package main
+
+import (
+	"net/http/pprof"
+
+	"github.com/kakkoyun/demo-web-service/handlers"
+)
+
+// registerPprof serves the runtime profiles of net/http/pprof under /debug/pprof/.
+// CPU profiles and traces are bounded by the server's write timeout, so their
+// duration must be shorter, e.g. /debug/pprof/profile?seconds=10.
+func registerPprof(api *handlers.API) {
+	api.Handle("GET", "/debug/pprof/", "PprofIndexHandler", pprof.Index)
+	api.Handle("GET", "/debug/pprof/cmdline", "PprofCmdlineHandler", pprof.Cmdline)
+	api.Handle("GET", "/debug/pprof/profile", "PprofProfileHandler", pprof.Profile)
+	api.Handle("GET", "/debug/pprof/symbol", "PprofSymbolHandler", pprof.Symbol)
+	api.Handle("POST", "/debug/pprof/symbol", "PprofSymbolHandler", pprof.Symbol)
+	api.Handle("GET", "/debug/pprof/trace", "PprofTraceHandler", pprof.Trace)
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"sync/atomic"
+
+	"github.com/kakkoyun/demo-web-service/config"
+	"github.com/kakkoyun/demo-web-service/handlers"
+)
+
+// reloadableHandler is a slog.Handler whose underlying handler can be swapped
+// at runtime, e.g. to change the log format without a restart. Handlers derived
+// from it with WithAttrs or WithGroup follow the swap.
+type reloadableHandler struct {
+	// current is the underlying handler, shared with all derived handlers
+	current *atomic.Pointer[slog.Handler]
+	// derive applies the attributes and groups of a derived handler to the
+	// underlying one; nil for the root handler
+	derive func(slog.Handler) slog.Handler
+	// derived caches derive's result for the current underlying handler
+	derived atomic.Pointer[derivedHandler]
+}
+
+// derivedHandler is the result of deriving a handler from base
+type derivedHandler struct {
+	base    slog.Handler
+	handler slog.Handler
+}
+
+// newReloadableHandler creates a reloadableHandler passing records to handler
+func newReloadableHandler(handler slog.Handler) *reloadableHandler {
+	h := &reloadableHandler{current: new(atomic.Pointer[slog.Handler])}
+	h.swap(handler)
+	return h
+}
+
+// swap makes handler the underlying handler of h and every handler derived from it
+func (h *reloadableHandler) swap(handler slog.Handler) {
+	h.current.Store(&handler)
+}
+
+// handler returns the underlying handler with the derived attributes and groups applied
+func (h *reloadableHandler) handler() slog.Handler {
+	base := *h.current.Load()
+	if h.derive == nil {
+		return base
+	}
+
+	if d := h.derived.Load(); d != nil && d.base == base {
+		return d.handler
+	}
+	d := &derivedHandler{base: base, handler: h.derive(base)}
+	h.derived.Store(d)
+	return d.handler
+}
+
+func (h *reloadableHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.handler().Enabled(ctx, level)
+}
+
+func (h *reloadableHandler) Handle(ctx context.Context, record slog.Record) error {
+	return h.handler().Handle(ctx, record)
+}
+
+func (h *reloadableHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return h.with(func(handler slog.Handler) slog.Handler {
+		return handler.WithAttrs(attrs)
+	})
+}
+
+func (h *reloadableHandler) WithGroup(name string) slog.Handler {
+	return h.with(func(handler slog.Handler) slog.Handler {
+		return handler.WithGroup(name)
+	})
+}
+
+// with returns a handler sharing h's underlying handler that applies step
+// after h's own attributes and groups
+func (h *reloadableHandler) with(step func(slog.Handler) slog.Handler) *reloadableHandler {
+	parent := h.derive
+	return &reloadableHandler{
+		current: h.current,
+		derive: func(handler slog.Handler) slog.Handler {
+			if parent != nil {
+				handler = parent(handler)
+			}
+			return step(handler)
+		},
+	}
+}
+
+// configReloader applies the settings of a reloaded configuration that can
+// change at runtime
+type configReloader struct {
+	w           io.Writer
+	level       *slog.LevelVar
+	handler     *reloadableHandler
+	toggler     *levelToggler
+	maintenance *handlers.Maintenance
+}
+
+// reload switches logging to the level and format of cfg and applies its
+// maintenance mode. Other settings only take effect after a restart.
+func (r *configReloader) reload(cfg *config.Config) {
+	r.handler.swap(newLogger(r.w, cfg, r.level).Handler())
+	r.level.Set(cfg.LogLevel)
+	r.toggler.setBase(cfg.LogLevel)
+	r.maintenance.Set(cfg.MaintenanceMode, cfg.MaintenanceRetryAfter)
+
+	// Logged as a warning so the change is visible at any of the usual levels
+	slog.Warn("Configuration reloaded",
+		"level", cfg.LogLevel,
+		"format", cfg.LogFormat,
+		"maintenance", cfg.MaintenanceMode)
+}
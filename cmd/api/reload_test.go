@@ -0,0 +1,107 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/kakkoyun/demo-web-service/handlers"
+)
+
+func TestConfigReloader(t *testing.T) {
+	t.Setenv("LOG_LEVEL", "info")
+	t.Setenv("LOG_FORMAT", "json")
+
+	cfg, err := loadConfig()
+	if err != nil {
+		t.Fatalf("Failed to load configuration: %v", err)
+	}
+
+	var buf bytes.Buffer
+	level := new(slog.LevelVar)
+	level.Set(cfg.LogLevel)
+	handler := newReloadableHandler(newLogger(&buf, cfg, level).Handler())
+	toggler := newLevelToggler(level, cfg.LogLevel)
+	maintenance := handlers.NewMaintenance()
+	reloader := &configReloader{w: &buf, level: level, handler: handler, toggler: toggler, maintenance: maintenance}
+
+	// Derived before the reload, like the request-scoped loggers of a running server
+	logger := slog.New(handler).With("component", "test")
+
+	logger.Debug("before reload")
+	if buf.Len() != 0 {
+		t.Fatalf("debug line logged at info level: %q", buf.String())
+	}
+
+	// Simulate a SIGHUP after the operator changed the environment
+	t.Setenv("LOG_LEVEL", "debug")
+	t.Setenv("LOG_FORMAT", "text")
+	t.Setenv("MAINTENANCE_MODE", "true")
+	cfg, err = loadConfig()
+	if err != nil {
+		t.Fatalf("Failed to reload configuration: %v", err)
+	}
+	reloader.reload(cfg)
+
+	if !maintenance.Enabled() {
+		t.Error("maintenance mode was not enabled by the reload")
+	}
+
+	if got := level.Level(); got != slog.LevelDebug {
+		t.Errorf("effective log level did not change: got %v want %v", got, slog.LevelDebug)
+	}
+
+	buf.Reset()
+	logger.Debug("after reload")
+	line := buf.String()
+	if json.Valid([]byte(line)) {
+		t.Errorf("log format did not change to text: %q", line)
+	}
+	if !strings.Contains(line, "msg=\"after reload\"") || !strings.Contains(line, "component=test") {
+		t.Errorf("derived logger lost its attributes after the reload: %q", line)
+	}
+
+	// Toggling debug off again reverts to the reloaded level, not the original one
+	t.Setenv("LOG_LEVEL", "warn")
+	t.Setenv("MAINTENANCE_MODE", "false")
+	cfg, err = loadConfig()
+	if err != nil {
+		t.Fatalf("Failed to reload configuration: %v", err)
+	}
+	reloader.reload(cfg)
+
+	if maintenance.Enabled() {
+		t.Error("maintenance mode was not disabled by the reload")
+	}
+	toggler.toggle()
+	toggler.toggle()
+	if got := level.Level(); got != slog.LevelWarn {
+		t.Errorf("toggle did not revert to the reloaded level: got %v want %v", got, slog.LevelWarn)
+	}
+}
+
+func TestReloadableHandlerGroups(t *testing.T) {
+	var first, second bytes.Buffer
+	handler := newReloadableHandler(slog.NewJSONHandler(&first, nil))
+	logger := slog.New(handler).WithGroup("request").With("id", "abc")
+
+	logger.Info("one")
+	handler.swap(slog.NewJSONHandler(&second, nil))
+	logger.Info("two")
+
+	for _, tc := range []struct {
+		buf *bytes.Buffer
+		msg string
+	}{{buf: &first, msg: "one"}, {buf: &second, msg: "two"}} {
+		var record map[string]any
+		if err := json.Unmarshal(tc.buf.Bytes(), &record); err != nil {
+			t.Fatalf("log line is not JSON: %q", tc.buf.String())
+		}
+		group, _ := record["request"].(map[string]any)
+		if record["msg"] != tc.msg || group["id"] != "abc" {
+			t.Errorf("log record has wrong fields: %v", record)
+		}
+	}
+}
@@ -0,0 +1,565 @@
+package main
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"log/slog"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kakkoyun/demo-web-service/config"
+	"github.com/kakkoyun/demo-web-service/handlers"
+)
+
+// writeSelfSignedCert generates a self-signed certificate for 127.0.0.1 and
+// writes it and its key to dir, returning the file paths and the certificate
+func writeSelfSignedCert(t *testing.T, dir string) (certFile, keyFile string, cert *x509.Certificate) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{Organization: []string{"demo-web-service test"}},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cert, err = x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	certFile = filepath.Join(dir, "cert.pem")
+	keyFile = filepath.Join(dir, "key.pem")
+
+	if err := os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	return certFile, keyFile, cert
+}
+
+// freeAddr returns a loopback address with a port that is currently free
+func freeAddr(t *testing.T) string {
+	t.Helper()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	return ln.Addr().String()
+}
+
+func TestListenAndServeTLS(t *testing.T) {
+	certFile, keyFile, cert := writeSelfSignedCert(t, t.TempDir())
+	cfg := &config.Config{TLSCertFile: certFile, TLSKeyFile: keyFile}
+
+	srv := &http.Server{
+		Addr: freeAddr(t),
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+			w.WriteHeader(http.StatusOK)
+		}),
+		ReadHeaderTimeout: time.Second,
+	}
+
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- listenAndServe(srv, cfg)
+	}()
+
+	// Trust the self-signed certificate
+	pool := x509.NewCertPool()
+	pool.AddCert(cert)
+	client := &http.Client{
+		Timeout:   time.Second,
+		Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: pool, MinVersion: tls.VersionTLS12}},
+	}
+
+	// Wait for the server to start accepting connections
+	var resp *http.Response
+	var err error
+	for i := 0; i < 50; i++ {
+		resp, err = client.Get("https://" + srv.Addr + "/")
+		if err == nil {
+			break
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	if err != nil {
+		t.Fatalf("HTTPS request failed: %v", err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("wrong status code: got %v want %v", resp.StatusCode, http.StatusOK)
+	}
+	if resp.TLS == nil {
+		t.Error("response was not served over TLS")
+	}
+
+	// Graceful shutdown still works in TLS mode
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := srv.Shutdown(ctx); err != nil {
+		t.Fatalf("shutdown failed: %v", err)
+	}
+
+	if err := <-serveErr; !errors.Is(err, http.ErrServerClosed) {
+		t.Errorf("wrong error after shutdown: got %v want %v", err, http.ErrServerClosed)
+	}
+}
+
+// runTestConfig loads the default configuration for running the server on port
+func runTestConfig(t *testing.T, port string) *config.Config {
+	t.Helper()
+
+	t.Setenv("SERVER_PORT", port)
+	t.Setenv("FAULT_INJECTION", "false")
+	t.Setenv("SHUTDOWN_TIMEOUT", "1s")
+
+	cfg := config.LoadConfig()
+	if err := cfg.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	return cfg
+}
+
+// waitForServer waits for a server started by run to accept connections on port
+func waitForServer(t *testing.T, port string) {
+	t.Helper()
+
+	client := &http.Client{Timeout: time.Second}
+	for i := 0; ; i++ {
+		resp, err := client.Get("http://127.0.0.1:" + port + "/api/health/live")
+		if err == nil {
+			resp.Body.Close()
+			return
+		}
+		if i == 50 {
+			t.Fatalf("server did not start: %v", err)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
+func TestRunReturnsBindError(t *testing.T) {
+	// Occupy a port so that the server can't bind to it
+	ln, err := net.Listen("tcp", ":0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	_, port, err := net.SplitHostPort(ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := runTestConfig(t, port)
+
+	// run must return on its own, without waiting for a shutdown signal
+	runErr := make(chan error, 1)
+	go func() {
+		runErr <- run(context.Background(), cfg, slog.New(slog.DiscardHandler), new(slog.LevelVar), handlers.NewMaintenance())
+	}()
+
+	select {
+	case err := <-runErr:
+		if err == nil {
+			t.Fatal("run did not report the bind failure")
+		}
+		if !strings.Contains(err.Error(), "server failed to start") {
+			t.Errorf("wrong error: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("run did not return after the bind failure")
+	}
+}
+
+func TestRunShutsDownWhenContextIsDone(t *testing.T) {
+	_, port, err := net.SplitHostPort(freeAddr(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := runTestConfig(t, port)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	runErr := make(chan error, 1)
+	go func() {
+		runErr <- run(ctx, cfg, slog.New(slog.DiscardHandler), new(slog.LevelVar), handlers.NewMaintenance())
+	}()
+
+	waitForServer(t, port)
+	cancel()
+
+	select {
+	case err := <-runErr:
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("run did not return after the context was canceled")
+	}
+}
+
+func TestPprofEndpoints(t *testing.T) {
+	testCases := []struct {
+		name           string
+		enabled        bool
+		expectedStatus int
+	}{
+		{name: "Enabled", enabled: true, expectedStatus: http.StatusOK},
+		{name: "Disabled", enabled: false, expectedStatus: http.StatusNotFound},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, port, err := net.SplitHostPort(freeAddr(t))
+			if err != nil {
+				t.Fatal(err)
+			}
+			cfg := runTestConfig(t, port)
+			cfg.EnablePprof = tc.enabled
+
+			ctx, cancel := context.WithCancel(context.Background())
+			runErr := make(chan error, 1)
+			go func() {
+				runErr <- run(ctx, cfg, slog.New(slog.DiscardHandler), new(slog.LevelVar), handlers.NewMaintenance())
+			}()
+			defer func() {
+				cancel()
+				if err := <-runErr; err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+			}()
+
+			waitForServer(t, port)
+
+			resp, err := http.Get("http://127.0.0.1:" + port + "/debug/pprof/")
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+
+			if resp.StatusCode != tc.expectedStatus {
+				t.Errorf("handler returned wrong status code: got %v want %v", resp.StatusCode, tc.expectedStatus)
+			}
+		})
+	}
+}
+
+func TestExpvarEndpoint(t *testing.T) {
+	testCases := []struct {
+		name           string
+		debugEndpoints bool
+		expectedStatus int
+	}{
+		{name: "Debug Endpoints Enabled", debugEndpoints: true, expectedStatus: http.StatusOK},
+		{name: "Debug Endpoints Disabled", debugEndpoints: false, expectedStatus: http.StatusNotFound},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, port, err := net.SplitHostPort(freeAddr(t))
+			if err != nil {
+				t.Fatal(err)
+			}
+			cfg := runTestConfig(t, port)
+			cfg.DebugEndpoints = tc.debugEndpoints
+			// Publishing counters alone must not expose them
+			cfg.SimulatedErrorMetrics = true
+			cfg.UserCacheSize = 10
+
+			ctx, cancel := context.WithCancel(context.Background())
+			runErr := make(chan error, 1)
+			go func() {
+				runErr <- run(ctx, cfg, slog.New(slog.DiscardHandler), new(slog.LevelVar), handlers.NewMaintenance())
+			}()
+			defer func() {
+				cancel()
+				if err := <-runErr; err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+			}()
+
+			waitForServer(t, port)
+
+			resp, err := http.Get("http://127.0.0.1:" + port + "/debug/vars")
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+
+			if resp.StatusCode != tc.expectedStatus {
+				t.Errorf("handler returned wrong status code: got %v want %v", resp.StatusCode, tc.expectedStatus)
+			}
+		})
+	}
+}
+
+func TestRateLimitThrottlesFailedAuthentication(t *testing.T) {
+	_, port, err := net.SplitHostPort(freeAddr(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := runTestConfig(t, port)
+	cfg.BasicAuthUsers = map[string]string{"admin": "secret"}
+	cfg.RateLimitRPS = 0.1
+	cfg.RateLimitBurst = 3
+
+	ctx, cancel := context.WithCancel(context.Background())
+	runErr := make(chan error, 1)
+	go func() {
+		runErr <- run(ctx, cfg, slog.New(slog.DiscardHandler), new(slog.LevelVar), handlers.NewMaintenance())
+	}()
+	defer func() {
+		cancel()
+		if err := <-runErr; err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+	}()
+
+	waitForServer(t, port)
+
+	// Every guess is refused, until the client runs out of requests altogether
+	for i := 0; ; i++ {
+		req, err := http.NewRequest("POST", "http://127.0.0.1:"+port+"/api/users", strings.NewReader(`{"name":"Mallory"}`))
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("Content-Type", "application/json")
+		req.SetBasicAuth("admin", "guess-"+strconv.Itoa(i))
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+
+		if resp.StatusCode == http.StatusTooManyRequests {
+			return
+		}
+		if resp.StatusCode != http.StatusUnauthorized {
+			t.Fatalf("handler returned wrong status code: got %v want %v", resp.StatusCode, http.StatusUnauthorized)
+		}
+		if i == cfg.RateLimitBurst {
+			t.Fatal("failed authentication attempts were not rate limited")
+		}
+	}
+}
+
+func TestRunOnUnixSocket(t *testing.T) {
+	socket := filepath.Join(t.TempDir(), "api.sock")
+
+	// A socket file left behind by an earlier run doesn't stop the server from starting
+	stale, err := net.Listen("unix", socket)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stale.(*net.UnixListener).SetUnlinkOnClose(false)
+	stale.Close()
+
+	t.Setenv("LISTEN_NETWORK", "unix")
+	t.Setenv("LISTEN_ADDR", socket)
+	cfg := runTestConfig(t, "8080")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	runErr := make(chan error, 1)
+	go func() {
+		runErr <- run(ctx, cfg, slog.New(slog.DiscardHandler), new(slog.LevelVar), handlers.NewMaintenance())
+	}()
+
+	client := &http.Client{
+		Timeout: time.Second,
+		Transport: &http.Transport{
+			DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+				return (&net.Dialer{}).DialContext(ctx, "unix", socket)
+			},
+		},
+	}
+
+	var resp *http.Response
+	for i := 0; i < 50; i++ {
+		resp, err = client.Get("http://unix/api/health/live")
+		if err == nil {
+			break
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+	if err != nil {
+		t.Fatalf("request over the unix socket failed: %v", err)
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("wrong status code: got %v want %v", resp.StatusCode, http.StatusOK)
+	}
+
+	cancel()
+	select {
+	case err := <-runErr:
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("run did not return after the context was canceled")
+	}
+
+	if _, err := os.Stat(socket); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("socket file was not removed on shutdown: %v", err)
+	}
+}
+
+func TestMaxHeaderBytes(t *testing.T) {
+	_, port, err := net.SplitHostPort(freeAddr(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("MAX_HEADER_BYTES", "1024")
+	cfg := runTestConfig(t, port)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	runErr := make(chan error, 1)
+	go func() {
+		runErr <- run(ctx, cfg, slog.New(slog.DiscardHandler), new(slog.LevelVar), handlers.NewMaintenance())
+	}()
+	defer func() {
+		cancel()
+		if err := <-runErr; err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+	}()
+
+	waitForServer(t, port)
+
+	testCases := []struct {
+		name           string
+		headerSize     int
+		expectedStatus int
+	}{
+		{name: "Small Headers", headerSize: 100, expectedStatus: http.StatusOK},
+		// The server allows some slack over the limit before rejecting headers
+		{name: "Large Headers", headerSize: 16 << 10, expectedStatus: http.StatusRequestHeaderFieldsTooLarge},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req, err := http.NewRequest("GET", "http://127.0.0.1:"+port+"/api/health/live", nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			req.Header.Set("X-Padding", strings.Repeat("a", tc.headerSize))
+
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			resp.Body.Close()
+
+			if resp.StatusCode != tc.expectedStatus {
+				t.Errorf("wrong status code: got %v want %v", resp.StatusCode, tc.expectedStatus)
+			}
+		})
+	}
+}
+
+func TestRunAnswersDuringShutdownGracePeriod(t *testing.T) {
+	_, port, err := net.SplitHostPort(freeAddr(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cfg := runTestConfig(t, port)
+	cfg.ShutdownGracePeriod = time.Second
+
+	ctx, cancel := context.WithCancel(context.Background())
+	runErr := make(chan error, 1)
+	go func() {
+		runErr <- run(ctx, cfg, slog.New(slog.DiscardHandler), new(slog.LevelVar), handlers.NewMaintenance())
+	}()
+
+	waitForServer(t, port)
+	cancel()
+
+	// Wait for the shutdown to begin while the listener is still open
+	base := "http://127.0.0.1:" + port
+	client := &http.Client{Timeout: time.Second}
+	for i := 0; ; i++ {
+		resp, err := client.Get(base + "/api/health/ready")
+		if err != nil {
+			t.Fatalf("server stopped answering during the grace period: %v", err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode == http.StatusServiceUnavailable {
+			break
+		}
+		if i == 20 {
+			t.Fatal("shutdown did not begin")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	testCases := []struct {
+		path           string
+		expectedStatus int
+	}{
+		{path: "/api/users", expectedStatus: http.StatusServiceUnavailable},
+		{path: "/api/shutdown-status", expectedStatus: http.StatusOK},
+	}
+	for _, tc := range testCases {
+		resp, err := client.Get(base + tc.path)
+		if err != nil {
+			t.Fatalf("GET %s: %v", tc.path, err)
+		}
+		resp.Body.Close()
+
+		if resp.StatusCode != tc.expectedStatus {
+			t.Errorf("GET %s returned wrong status code: got %v want %v", tc.path, resp.StatusCode, tc.expectedStatus)
+		}
+		if !resp.Close {
+			t.Errorf("GET %s did not ask to close the connection", tc.path)
+		}
+	}
+
+	select {
+	case err := <-runErr:
+		if err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("run did not return after the grace period")
+	}
+}
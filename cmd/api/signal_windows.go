@@ -0,0 +1,11 @@
+//go:build windows
+
+package main
+
+import "os"
+
+// notifyLevelToggle is a no-op on Windows, which has no SIGUSR2
+func notifyLevelToggle(_ chan<- os.Signal) {}
+
+// notifyReload is a no-op on Windows, where services aren't sent SIGHUP
+func notifyReload(_ chan<- os.Signal) {}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/kakkoyun/demo-web-service/store"
+)
+
+// runSnapshots saves the store to path every interval until ctx is done,
+// then saves it one last time so that no changes are lost on shutdown.
+// The returned channel is closed once the final snapshot is written.
+func runSnapshots(ctx context.Context, s *store.MemoryStore, path string, interval time.Duration, logger *slog.Logger) <-chan struct{} {
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ticker.C:
+				saveSnapshot(s, path, logger)
+			case <-ctx.Done():
+				saveSnapshot(s, path, logger)
+				return
+			}
+		}
+	}()
+
+	return done
+}
+
+// saveSnapshot saves the store to path, logging failures, which are retried on the next tick
+func saveSnapshot(s *store.MemoryStore, path string, logger *slog.Logger) {
+	if err := s.SaveSnapshot(path); err != nil {
+		logger.Error("Failed to save user store snapshot", "path", path, "error", err)
+		return
+	}
+	logger.Debug("User store snapshot saved", "path", path)
+}
@@ -1,60 +1,671 @@
 package config
 
 import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/url"
 	"os"
+	"slices"
+	"strconv"
 	"strings"
 	"time"
 )
 
 // Config holds the application configuration
 type Config struct {
-	ServerPort     string
-	AllowedOrigins []string
+	// ServerHost is the interface the server listens on, an IP address or a
+	// hostname; empty means all interfaces
+	ServerHost string
+	ServerPort string
+	// ListenNetwork is "tcp", or "unix" to listen on a Unix domain socket
+	ListenNetwork string
+	// ListenAddr is the socket path to listen on with the unix network; with
+	// tcp it overrides ServerHost and ServerPort when set, e.g. "127.0.0.1:9000"
+	ListenAddr string
+	// TLSCertFile and TLSKeyFile enable HTTPS when both are set
+	TLSCertFile string
+	TLSKeyFile  string
+	// MaxHeaderBytes is the maximum size of request headers in bytes
+	MaxHeaderBytes int
 	ReadTimeout    time.Duration
 	WriteTimeout   time.Duration
 	IdleTimeout    time.Duration
+	// ShutdownTimeout bounds how long a graceful shutdown waits for requests to drain
+	ShutdownTimeout time.Duration
+	// ShutdownGracePeriod is how long new requests are still answered, with a
+	// 503 and Connection: close, after a shutdown begins and before the
+	// listeners close, so load balancers stop routing to the instance; 0
+	// closes them right away
+	ShutdownGracePeriod time.Duration
+
+	AllowedOrigins []string
+	// Security headers set on every response; an empty value disables the header
+	ContentTypeOptions    string
+	FrameOptions          string
+	ReferrerPolicy        string
+	ContentSecurityPolicy string
+
+	// BasicAuthUsers maps usernames to the passwords that may change state
+	// through the API; empty disables basic authentication
+	BasicAuthUsers map[string]string
+	// JWTSecret is the HMAC secret bearer tokens are signed with; empty
+	// disables JWT authentication
+	JWTSecret string
+	// JWTProtectedPaths are the path prefixes that require a bearer token
+	// when JWTSecret is set
+	JWTProtectedPaths []string
+	// APIKeys maps API keys to the identity of their owner; empty disables
+	// API key authentication
+	APIKeys map[string]string
+	// APIKeyProtectedPaths are the path prefixes that require an API key
+	// when APIKeys is set
+	APIKeyProtectedPaths []string
+
+	// Environment is the deployment environment, e.g. "production"
+	Environment string
+	LogLevel    slog.Level
+	// LogFormat is the log output format, either "json" or "text"
+	LogFormat string
+	// SlowRequestThreshold is how long a request may take before its access log
+	// line is escalated to a warning; 0 never escalates
+	SlowRequestThreshold time.Duration
+	// PrettyJSON indents JSON responses by default; it is enabled outside of production
+	PrettyJSON bool
+
+	// HandlerTimeout bounds the time a handler may spend on a request; 0 disables it
+	HandlerTimeout time.Duration
+	// ReadHandlerTimeout and WriteHandlerTimeout replace HandlerTimeout for
+	// GET, HEAD and OPTIONS requests and for all other requests respectively
+	ReadHandlerTimeout  time.Duration
+	WriteHandlerTimeout time.Duration
+	// MaxBodyBytes is the maximum size of request bodies in bytes
+	MaxBodyBytes int
+	// ImportMaxBodyBytes is the maximum size of bulk import bodies in bytes
+	ImportMaxBodyBytes int
+	// CompressionMinSize is the smallest response body in bytes that is gzipped
+	CompressionMinSize int
+	// MaxInFlight limits how many requests are served at once; 0 means unlimited
+	MaxInFlight    int
+	RateLimitRPS   float64
+	RateLimitBurst int
+
+	// FaultInjection enables randomly simulated failures for demos
+	FaultInjection bool
+	// HomeChaos keeps simulated failures of the root endpoint when FaultInjection is enabled
+	HomeChaos bool
+	// SimulatedErrorMetrics publishes counters of simulated database errors at /debug/vars
+	SimulatedErrorMetrics bool
+	// MaxRetries is how many times a failed database query is retried; 0 disables retries
+	MaxRetries int
+	// RetryBackoff is the delay before the first retry of a failed database query,
+	// doubling with every further retry
+	RetryBackoff time.Duration
+	// RetryBudgetRPS is how many database query retries per second are allowed
+	// across all requests
+	RetryBudgetRPS float64
+	// RetryBudgetBurst is the largest burst of retries the retry budget allows
+	RetryBudgetBurst int
+	// BreakerThreshold is how many consecutive database failures open the
+	// circuit breaker; 0 disables it
+	BreakerThreshold int
+	// BreakerCooldown is how long the database circuit breaker stays open
+	BreakerCooldown time.Duration
+
+	// UserIDStrategy is how public user IDs are generated: "sequential" or "uuid"
+	UserIDStrategy string
+	// ImportDuplicatePolicy is how imports handle users whose ID is already
+	// taken: "skip", "overwrite" or "error"
+	ImportDuplicatePolicy string
+	// IdempotencyTTL is how long responses to requests with an Idempotency-Key are replayed
+	IdempotencyTTL time.Duration
+	// UserSchemaFile is a JSON Schema that user bodies must conform to; empty
+	// disables schema validation
+	UserSchemaFile string
+	// UserCacheSize is how many users the user cache holds; 0 disables the cache
+	UserCacheSize int
+	// UserCacheTTL is how long a user stays in the user cache
+	UserCacheTTL time.Duration
+	// SnapshotFile is where the user store is periodically saved and restored
+	// from on startup; empty disables snapshots
+	SnapshotFile string
+	// SnapshotInterval is how often the user store is saved to SnapshotFile
+	SnapshotInterval time.Duration
+	// MaxEventSubscribers limits concurrent event stream subscribers; 0 means unlimited
+	MaxEventSubscribers int
+	// ReadinessPolicy is how failing readiness checks are aggregated:
+	// "fail-if-any" or "fail-if-all"
+	ReadinessPolicy string
+	// OTLPEndpoint is the URL traces are exported to over OTLP/HTTP; empty disables tracing
+	OTLPEndpoint string
+
+	// WebhookURL receives a POST with every created user; empty disables webhooks
+	WebhookURL string
+	// WebhookTimeout bounds a single webhook delivery attempt
+	WebhookTimeout time.Duration
+	// WebhookMaxRetries is how many times a failed webhook delivery is retried
+	WebhookMaxRetries int
+	// WebhookQueueSize is how many webhooks wait for delivery before new ones are dropped
+	WebhookQueueSize int
+
+	// MaintenanceMode answers every request except health checks with a 503;
+	// it can be toggled by reloading the configuration
+	MaintenanceMode bool
+	// MaintenanceRetryAfter is how long clients are told to wait in maintenance mode
+	MaintenanceRetryAfter time.Duration
+	// DebugEndpoints enables endpoints meant for demos and tests only
+	DebugEndpoints bool
+	// EnablePprof serves runtime profiles under /debug/pprof/
+	EnablePprof bool
+
+	// loadErrs are the environment variables LoadConfig couldn't parse
+	loadErrs []error
 }
 
 // LoadConfig loads the configuration from environment variables
 // with sensible defaults
 func LoadConfig() *Config {
-	return &Config{
-		ServerPort:     env("SERVER_PORT", "8080"),
-		ReadTimeout:    durationEnv("READ_TIMEOUT", "15s"),
-		WriteTimeout:   durationEnv("WRITE_TIMEOUT", "15s"),
-		IdleTimeout:    durationEnv("IDLE_TIMEOUT", "60s"),
-		AllowedOrigins: sliceEnv("ALLOWED_ORIGINS", "http://localhost:3000,http://localhost:8080"),
+	return load(&loader{})
+}
+
+// load builds the configuration from the values the loader finds
+func load(l *loader) *Config {
+	environment := l.env("APP_ENV", "development")
+
+	cfg := &Config{
+		ServerHost:            l.env("SERVER_HOST", ""),
+		ServerPort:            l.env("SERVER_PORT", "8080"),
+		ListenNetwork:         l.env("LISTEN_NETWORK", "tcp"),
+		ListenAddr:            l.env("LISTEN_ADDR", ""),
+		ReadTimeout:           l.durationEnv("READ_TIMEOUT", "15s"),
+		WriteTimeout:          l.durationEnv("WRITE_TIMEOUT", "15s"),
+		IdleTimeout:           l.durationEnv("IDLE_TIMEOUT", "60s"),
+		HandlerTimeout:        l.durationEnv("HANDLER_TIMEOUT", "10s"),
+		ShutdownTimeout:       l.durationEnv("SHUTDOWN_TIMEOUT", "15s"),
+		ShutdownGracePeriod:   l.durationEnv("SHUTDOWN_GRACE_PERIOD", defaultShutdownGracePeriod(environment)),
+		AllowedOrigins:        l.sliceEnv("ALLOWED_ORIGINS", "http://localhost:3000,http://localhost:8080"),
+		BasicAuthUsers:        l.pairsEnv("BASIC_AUTH_USERS"),
+		JWTSecret:             l.env("JWT_SECRET", ""),
+		JWTProtectedPaths:     l.sliceEnv("JWT_PROTECTED_PATHS", "/api/users,/api/graphql,/api/admin"),
+		APIKeys:               l.pairsEnv("API_KEYS"),
+		APIKeyProtectedPaths:  l.sliceEnv("API_KEY_PROTECTED_PATHS", "/api/users,/api/graphql,/api/admin"),
+		TLSCertFile:           l.env("TLS_CERT_FILE", ""),
+		TLSKeyFile:            l.env("TLS_KEY_FILE", ""),
+		RateLimitRPS:          l.floatEnv("RATE_LIMIT_RPS", "100"),
+		RateLimitBurst:        l.intEnv("RATE_LIMIT_BURST", "200"),
+		MaxRetries:            l.intEnv("DB_MAX_RETRIES", "2"),
+		RetryBudgetRPS:        l.floatEnv("RETRY_BUDGET_RPS", "10"),
+		RetryBudgetBurst:      l.intEnv("RETRY_BUDGET_BURST", "20"),
+		RetryBackoff:          l.durationEnv("RETRY_BACKOFF", "50ms"),
+		BreakerThreshold:      l.intEnv("BREAKER_THRESHOLD", "5"),
+		BreakerCooldown:       l.durationEnv("BREAKER_COOLDOWN", "30s"),
+		MaxBodyBytes:          l.intEnv("MAX_BODY_BYTES", "1048576"),
+		ImportMaxBodyBytes:    l.intEnv("IMPORT_MAX_BODY_BYTES", "10485760"),
+		MaxHeaderBytes:        l.intEnv("MAX_HEADER_BYTES", strconv.Itoa(http.DefaultMaxHeaderBytes)),
+		CompressionMinSize:    l.intEnv("COMPRESSION_MIN_SIZE", "1024"),
+		MaxEventSubscribers:   l.intEnv("MAX_EVENT_SUBSCRIBERS", "100"),
+		MaxInFlight:           l.intEnv("MAX_IN_FLIGHT", "0"),
+		ContentTypeOptions:    l.env("X_CONTENT_TYPE_OPTIONS", "nosniff"),
+		FrameOptions:          l.env("X_FRAME_OPTIONS", "DENY"),
+		ReferrerPolicy:        l.env("REFERRER_POLICY", "no-referrer"),
+		ContentSecurityPolicy: l.env("CONTENT_SECURITY_POLICY", ""),
+		Environment:           environment,
+		LogLevel:              l.logLevelEnv("LOG_LEVEL", defaultLogLevel(environment)),
+		LogFormat:             strings.ToLower(l.env("LOG_FORMAT", "json")),
+		PrettyJSON:            l.boolEnv("PRETTY_JSON", strconv.FormatBool(environment != "production")),
+		UserIDStrategy:        strings.ToLower(l.env("USER_ID_STRATEGY", "sequential")),
+		ReadinessPolicy:       strings.ToLower(l.env("READINESS_POLICY", "fail-if-any")),
+		ImportDuplicatePolicy: strings.ToLower(l.env("IMPORT_DUPLICATE_POLICY", "error")),
+		DebugEndpoints:        l.boolEnv("ENABLE_DEBUG_ENDPOINTS", "false"),
+		EnablePprof:           l.boolEnv("ENABLE_PPROF", "false"),
+		FaultInjection:        l.boolEnv("FAULT_INJECTION", "true"),
+		HomeChaos:             l.boolEnv("HOME_CHAOS", "true"),
+		SimulatedErrorMetrics: l.boolEnv("SIMULATED_ERROR_METRICS", "false"),
+		UserCacheSize:         l.intEnv("USER_CACHE_SIZE", "0"),
+		UserCacheTTL:          l.durationEnv("USER_CACHE_TTL", "1m"),
+		IdempotencyTTL:        l.durationEnv("IDEMPOTENCY_TTL", "24h"),
+		OTLPEndpoint:          l.env("OTEL_EXPORTER_OTLP_ENDPOINT", ""),
+		SnapshotFile:          l.env("SNAPSHOT_FILE", ""),
+		UserSchemaFile:        l.env("USER_SCHEMA_FILE", ""),
+		SnapshotInterval:      l.durationEnv("SNAPSHOT_INTERVAL", "1m"),
+		SlowRequestThreshold:  l.durationEnv("SLOW_REQUEST_THRESHOLD", "1s"),
+		MaintenanceMode:       l.boolEnv("MAINTENANCE_MODE", "false"),
+		MaintenanceRetryAfter: l.durationEnv("MAINTENANCE_RETRY_AFTER", "5m"),
+		WebhookURL:            l.env("WEBHOOK_URL", ""),
+		WebhookTimeout:        l.durationEnv("WEBHOOK_TIMEOUT", "5s"),
+		WebhookMaxRetries:     l.intEnv("WEBHOOK_MAX_RETRIES", "3"),
+		WebhookQueueSize:      l.intEnv("WEBHOOK_QUEUE_SIZE", "100"),
 	}
+
+	// Reads and writes fall back to the shared handler timeout
+	cfg.ReadHandlerTimeout = l.durationEnv("READ_HANDLER_TIMEOUT", cfg.HandlerTimeout.String())
+	cfg.WriteHandlerTimeout = l.durationEnv("WRITE_HANDLER_TIMEOUT", cfg.HandlerTimeout.String())
+
+	cfg.loadErrs = l.errs
+
+	return cfg
 }
 
-// env gets an environment variable or returns a fallback value
-func env(key, fallback string) string {
+// IsProduction reports whether the application runs in the production environment
+func (c *Config) IsProduction() bool {
+	return c.Environment == "production"
+}
+
+// Addr returns the address the server listens on: ListenAddr when set, else
+// e.g. "127.0.0.1:8080", or ":8080" to listen on all interfaces
+func (c *Config) Addr() string {
+	if c.ListenAddr != "" {
+		return c.ListenAddr
+	}
+	return net.JoinHostPort(c.ServerHost, c.ServerPort)
+}
+
+// NewServer creates an http.Server serving handler on the configured address,
+// with the configured timeouts and header size limit
+func (c *Config) NewServer(handler http.Handler) *http.Server {
+	return &http.Server{
+		Addr:           c.Addr(),
+		Handler:        handler,
+		ReadTimeout:    c.ReadTimeout,
+		WriteTimeout:   c.WriteTimeout,
+		IdleTimeout:    c.IdleTimeout,
+		MaxHeaderBytes: c.MaxHeaderBytes,
+	}
+}
+
+// TLSEnabled reports whether both a TLS certificate and key are configured
+func (c *Config) TLSEnabled() bool {
+	return c.TLSCertFile != "" && c.TLSKeyFile != ""
+}
+
+// ErrInvalidConfig is returned by Validate when the configuration has invalid values
+var ErrInvalidConfig = errors.New("invalid configuration")
+
+// minJWTSecretLength is the shortest HMAC secret accepted for signing bearer
+// tokens; RFC 7518 requires a key at least as long as the SHA-256 output
+const minJWTSecretLength = 32
+
+// Validate checks the configuration for invalid values, including environment
+// variables that LoadConfig couldn't parse, and returns an error listing all of them
+func (c *Config) Validate() error {
+	problems := append([]error(nil), c.loadErrs...)
+
+	if port, err := strconv.Atoi(c.ServerPort); err != nil || port < 1 || port > 65535 {
+		problems = append(problems, fmt.Errorf("SERVER_PORT: %q is not a port number between 1 and 65535", c.ServerPort))
+	}
+	if c.ServerHost != "" && !validHost(c.ServerHost) {
+		problems = append(problems, fmt.Errorf("SERVER_HOST: %q is not an IP address or hostname", c.ServerHost))
+	}
+
+	switch c.ListenNetwork {
+	case "", "tcp":
+		if _, _, err := net.SplitHostPort(c.ListenAddr); c.ListenAddr != "" && err != nil {
+			problems = append(problems, fmt.Errorf("LISTEN_ADDR: %q is not a host:port address: %w", c.ListenAddr, err))
+		}
+	case "unix":
+		if c.ListenAddr == "" {
+			problems = append(problems, errors.New("LISTEN_ADDR: must be the socket path when LISTEN_NETWORK is unix"))
+		}
+	default:
+		problems = append(problems, fmt.Errorf("LISTEN_NETWORK: %q is not tcp or unix", c.ListenNetwork))
+	}
+
+	// The packages using these settings parse them, but only once the server
+	// is being set up; checking them here refuses to start with a typo
+	choices := []struct {
+		key     string
+		value   string
+		allowed []string
+	}{
+		{key: "USER_ID_STRATEGY", value: c.UserIDStrategy, allowed: []string{"sequential", "uuid"}},
+		{key: "READINESS_POLICY", value: c.ReadinessPolicy, allowed: []string{"fail-if-any", "fail-if-all"}},
+		{key: "IMPORT_DUPLICATE_POLICY", value: c.ImportDuplicatePolicy, allowed: []string{"skip", "overwrite", "error"}},
+	}
+	for _, choice := range choices {
+		if !slices.Contains(choice.allowed, choice.value) {
+			problems = append(problems, fmt.Errorf("%s: %q is not one of %s", choice.key, choice.value, strings.Join(choice.allowed, ", ")))
+		}
+	}
+
+	timeouts := []struct {
+		key   string
+		value time.Duration
+	}{
+		{key: "READ_TIMEOUT", value: c.ReadTimeout},
+		{key: "WRITE_TIMEOUT", value: c.WriteTimeout},
+		{key: "IDLE_TIMEOUT", value: c.IdleTimeout},
+		{key: "SHUTDOWN_TIMEOUT", value: c.ShutdownTimeout},
+		{key: "IDEMPOTENCY_TTL", value: c.IdempotencyTTL},
+	}
+	for _, t := range timeouts {
+		if t.value <= 0 {
+			problems = append(problems, fmt.Errorf("%s: must be positive, got %v", t.key, t.value))
+		}
+	}
+
+	// A zero handler timeout disables it
+	handlerTimeouts := []struct {
+		key   string
+		value time.Duration
+	}{
+		{key: "HANDLER_TIMEOUT", value: c.HandlerTimeout},
+		{key: "READ_HANDLER_TIMEOUT", value: c.ReadHandlerTimeout},
+		{key: "WRITE_HANDLER_TIMEOUT", value: c.WriteHandlerTimeout},
+	}
+	for _, t := range handlerTimeouts {
+		if t.value < 0 {
+			problems = append(problems, fmt.Errorf("%s: must not be negative, got %v", t.key, t.value))
+		}
+	}
+
+	if c.ShutdownGracePeriod < 0 {
+		problems = append(problems, fmt.Errorf("SHUTDOWN_GRACE_PERIOD: must not be negative, got %v", c.ShutdownGracePeriod))
+	}
+
+	if c.SlowRequestThreshold < 0 {
+		problems = append(problems, fmt.Errorf("SLOW_REQUEST_THRESHOLD: must not be negative, got %v", c.SlowRequestThreshold))
+	}
+
+	// Zero disables the limit
+	if c.MaxEventSubscribers < 0 {
+		problems = append(problems, fmt.Errorf("MAX_EVENT_SUBSCRIBERS: must not be negative, got %d", c.MaxEventSubscribers))
+	}
+	if c.MaxHeaderBytes <= 0 {
+		problems = append(problems, fmt.Errorf("MAX_HEADER_BYTES: must be positive, got %d", c.MaxHeaderBytes))
+	}
+	if c.MaxInFlight < 0 {
+		problems = append(problems, fmt.Errorf("MAX_IN_FLIGHT: must not be negative, got %d", c.MaxInFlight))
+	}
+
+	if c.MaxRetries < 0 {
+		problems = append(problems, fmt.Errorf("DB_MAX_RETRIES: must not be negative, got %d", c.MaxRetries))
+	}
+	if c.RetryBackoff < 0 {
+		problems = append(problems, fmt.Errorf("RETRY_BACKOFF: must not be negative, got %v", c.RetryBackoff))
+	}
+	if c.RetryBudgetRPS < 0 {
+		problems = append(problems, fmt.Errorf("RETRY_BUDGET_RPS: must not be negative, got %v", c.RetryBudgetRPS))
+	}
+	if c.RetryBudgetBurst < 0 {
+		problems = append(problems, fmt.Errorf("RETRY_BUDGET_BURST: must not be negative, got %d", c.RetryBudgetBurst))
+	}
+
+	if c.BreakerThreshold < 0 {
+		problems = append(problems, fmt.Errorf("BREAKER_THRESHOLD: must not be negative, got %d", c.BreakerThreshold))
+	}
+	if c.BreakerThreshold > 0 && c.BreakerCooldown <= 0 {
+		problems = append(problems, fmt.Errorf("BREAKER_COOLDOWN: must be positive, got %v", c.BreakerCooldown))
+	}
+
+	if c.UserCacheSize < 0 {
+		problems = append(problems, fmt.Errorf("USER_CACHE_SIZE: must not be negative, got %d", c.UserCacheSize))
+	}
+	if c.UserCacheSize > 0 && c.UserCacheTTL <= 0 {
+		problems = append(problems, fmt.Errorf("USER_CACHE_TTL: must be positive, got %v", c.UserCacheTTL))
+	}
+
+	if c.SnapshotFile != "" && c.SnapshotInterval <= 0 {
+		problems = append(problems, fmt.Errorf("SNAPSHOT_INTERVAL: must be positive, got %v", c.SnapshotInterval))
+	}
+
+	if c.MaintenanceMode && c.MaintenanceRetryAfter <= 0 {
+		problems = append(problems, fmt.Errorf("MAINTENANCE_RETRY_AFTER: must be positive, got %v", c.MaintenanceRetryAfter))
+	}
+
+	if c.OTLPEndpoint != "" {
+		if u, err := url.Parse(c.OTLPEndpoint); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			problems = append(problems, fmt.Errorf("OTEL_EXPORTER_OTLP_ENDPOINT: %q is not an http(s) URL", c.OTLPEndpoint))
+		}
+	}
+
+	if c.WebhookURL != "" {
+		if u, err := url.Parse(c.WebhookURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			problems = append(problems, fmt.Errorf("WEBHOOK_URL: %q is not an http(s) URL", c.WebhookURL))
+		}
+		if c.WebhookTimeout <= 0 {
+			problems = append(problems, fmt.Errorf("WEBHOOK_TIMEOUT: must be positive, got %v", c.WebhookTimeout))
+		}
+		if c.WebhookMaxRetries < 0 {
+			problems = append(problems, fmt.Errorf("WEBHOOK_MAX_RETRIES: must not be negative, got %d", c.WebhookMaxRetries))
+		}
+		if c.WebhookQueueSize <= 0 {
+			problems = append(problems, fmt.Errorf("WEBHOOK_QUEUE_SIZE: must be positive, got %d", c.WebhookQueueSize))
+		}
+	}
+
+	if c.JWTSecret != "" && len(c.JWTSecret) < minJWTSecretLength {
+		problems = append(problems, fmt.Errorf("JWT_SECRET: must be at least %d bytes, got %d", minJWTSecretLength, len(c.JWTSecret)))
+	}
+	protectedPaths := []struct {
+		key   string
+		paths []string
+	}{
+		{key: "JWT_PROTECTED_PATHS", paths: c.JWTProtectedPaths},
+		{key: "API_KEY_PROTECTED_PATHS", paths: c.APIKeyProtectedPaths},
+	}
+	for _, p := range protectedPaths {
+		for _, path := range p.paths {
+			if !strings.HasPrefix(path, "/") {
+				problems = append(problems, fmt.Errorf("%s: %q is not an absolute path", p.key, path))
+			}
+		}
+	}
+
+	for _, origin := range c.AllowedOrigins {
+		if err := validateOrigin(origin); err != nil {
+			problems = append(problems, fmt.Errorf("ALLOWED_ORIGINS: %w", err))
+		}
+	}
+
+	if len(problems) == 0 {
+		return nil
+	}
+	return fmt.Errorf("%w: %w", ErrInvalidConfig, errors.Join(problems...))
+}
+
+// validateOrigin checks that an allowed origin is "*" or a bare http(s) scheme and host
+func validateOrigin(origin string) error {
+	if origin == "*" {
+		return nil
+	}
+
+	u, err := url.Parse(origin)
+	if err != nil {
+		return fmt.Errorf("invalid origin %q: %w", origin, err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("invalid origin %q: scheme must be http or https", origin)
+	}
+	if u.Host == "" || u.User != nil || (u.Path != "" && u.Path != "/") || u.RawQuery != "" || u.Fragment != "" {
+		return fmt.Errorf("invalid origin %q: must be a scheme and host only", origin)
+	}
+	return nil
+}
+
+// ParseLogLevel parses one of debug, info, warn or error (case-insensitive) into a log level
+func ParseLogLevel(value string) (slog.Level, error) {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "debug":
+		return slog.LevelDebug, nil
+	case "info":
+		return slog.LevelInfo, nil
+	case "warn":
+		return slog.LevelWarn, nil
+	case "error":
+		return slog.LevelError, nil
+	default:
+		return slog.LevelInfo, fmt.Errorf("invalid log level %q: must be one of debug, info, warn, error", value)
+	}
+}
+
+// defaultLogLevel returns the log level used when LOG_LEVEL is not set:
+// info in production and debug everywhere else
+func defaultLogLevel(environment string) slog.Level {
+	if environment == "production" {
+		return slog.LevelInfo
+	}
+	return slog.LevelDebug
+}
+
+// defaultShutdownGracePeriod returns the shutdown grace period used when
+// SHUTDOWN_GRACE_PERIOD is not set: 5s in production, where instances sit
+// behind load balancers, and none everywhere else
+func defaultShutdownGracePeriod(environment string) string {
+	if environment == "production" {
+		return "5s"
+	}
+	return "0s"
+}
+
+// loader reads configuration values from environment variables, falling back
+// to the values of a configuration file, and records the ones that can't be
+// parsed, so that Validate can report them
+type loader struct {
+	// file holds the values of a configuration file by lowercase key
+	file map[string]string
+	// keys are the keys looked up so far
+	keys map[string]bool
+	errs []error
+}
+
+// lookup gets the value of key from the environment or the configuration file
+func (l *loader) lookup(key string) (string, bool) {
+	if l.keys == nil {
+		l.keys = make(map[string]bool)
+	}
+	l.keys[strings.ToLower(key)] = true
+
 	if value, exists := os.LookupEnv(key); exists {
+		return value, true
+	}
+	value, exists := l.file[strings.ToLower(key)]
+	return value, exists
+}
+
+// invalid records that the value of an environment variable couldn't be parsed
+func (l *loader) invalid(key string, err error) {
+	l.errs = append(l.errs, fmt.Errorf("%s: %w", key, err))
+}
+
+// env gets an environment variable or returns a fallback value
+func (l *loader) env(key, fallback string) string {
+	if value, exists := l.lookup(key); exists {
 		return value
 	}
 	return fallback
 }
 
-// durationEnv gets a duration environment variable or returns a fallback value
-func durationEnv(key, fallback string) time.Duration {
-	if value, exists := os.LookupEnv(key); exists {
-		if duration, err := time.ParseDuration(value); err == nil {
+// durationEnv gets a duration environment variable or returns a fallback value.
+// Plain integers are accepted as seconds for compatibility, but are deprecated.
+func (l *loader) durationEnv(key, fallback string) time.Duration {
+	if value, exists := l.lookup(key); exists {
+		duration, err := time.ParseDuration(value)
+		if err == nil {
 			return duration
 		}
+		if seconds, convErr := strconv.Atoi(strings.TrimSpace(value)); convErr == nil {
+			duration = time.Duration(seconds) * time.Second
+			slog.Warn("Durations without a unit are deprecated, use a Go duration such as \"15s\"",
+				"key", key, "value", value, "duration", duration)
+			return duration
+		}
+		l.invalid(key, err)
 	}
 
 	duration, _ := time.ParseDuration(fallback)
 	return duration
 }
 
+// logLevelEnv gets a log level environment variable or returns a fallback value.
+// Invalid values are recorded and fall back to info.
+func (l *loader) logLevelEnv(key string, fallback slog.Level) slog.Level {
+	value, exists := l.lookup(key)
+	if !exists {
+		return fallback
+	}
+
+	level, err := ParseLogLevel(value)
+	if err != nil {
+		l.invalid(key, err)
+		return slog.LevelInfo
+	}
+	return level
+}
+
+// floatEnv gets a float environment variable or returns a fallback value
+func (l *loader) floatEnv(key, fallback string) float64 {
+	if value, exists := l.lookup(key); exists {
+		f, err := strconv.ParseFloat(value, 64)
+		if err == nil {
+			return f
+		}
+		l.invalid(key, err)
+	}
+
+	f, _ := strconv.ParseFloat(fallback, 64)
+	return f
+}
+
+// intEnv gets an integer environment variable or returns a fallback value
+func (l *loader) intEnv(key, fallback string) int {
+	if value, exists := l.lookup(key); exists {
+		i, err := strconv.Atoi(value)
+		if err == nil {
+			return i
+		}
+		l.invalid(key, err)
+	}
+
+	i, _ := strconv.Atoi(fallback)
+	return i
+}
+
+// boolEnv gets a boolean environment variable or returns a fallback value
+func (l *loader) boolEnv(key, fallback string) bool {
+	if value, exists := l.lookup(key); exists {
+		b, err := strconv.ParseBool(value)
+		if err == nil {
+			return b
+		}
+		l.invalid(key, err)
+	}
+
+	b, _ := strconv.ParseBool(fallback)
+	return b
+}
+
 // sliceEnv gets a slice from a comma-separated environment variable or returns a fallback
-func sliceEnv(key, fallback string) []string {
-	if value, exists := os.LookupEnv(key); exists {
+func (l *loader) sliceEnv(key, fallback string) []string {
+	if value, exists := l.lookup(key); exists {
 		return parseSlice(value)
 	}
 	return parseSlice(fallback)
 }
 
+// pairsEnv gets a map from a comma-separated list of name:value pairs, e.g.
+// "alice:secret,bob:hunter2", or returns an empty map. Names are trimmed; values
+// are kept as-is and may contain colons.
+func (l *loader) pairsEnv(key string) map[string]string {
+	pairs := map[string]string{}
+
+	value, exists := l.lookup(key)
+	if !exists {
+		return pairs
+	}
+	for _, pair := range parseSlice(value) {
+		name, value, ok := strings.Cut(pair, ":")
+		name = strings.TrimSpace(name)
+		if !ok || name == "" || value == "" {
+			l.invalid(key, fmt.Errorf("%q is not a name:value pair", name))
+			continue
+		}
+		pairs[name] = value
+	}
+	return pairs
+}
+
 // parseSlice parses a comma-separated string into a slice
 func parseSlice(value string) []string {
 	if value == "" {
@@ -81,3 +692,26 @@ func splitString(s, sep string) []string {
 	}
 	return strings.Split(s, sep)
 }
+
+// validHost reports whether host is an IP address or a hostname made of
+// dot-separated labels of letters, digits and inner hyphens
+func validHost(host string) bool {
+	if net.ParseIP(host) != nil {
+		return true
+	}
+	if len(host) > 253 {
+		return false
+	}
+
+	for _, label := range strings.Split(strings.TrimSuffix(host, "."), ".") {
+		if label == "" || len(label) > 63 || label[0] == '-' || label[len(label)-1] == '-' {
+			return false
+		}
+		for _, r := range label {
+			if (r < 'a' || r > 'z') && (r < 'A' || r > 'Z') && (r < '0' || r > '9') && r != '-' {
+				return false
+			}
+		}
+	}
+	return true
+}
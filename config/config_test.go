@@ -0,0 +1,384 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"log/slog"
+	"maps"
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+// unsetenv unsets an environment variable for the duration of a test
+func unsetenv(t *testing.T, key string) {
+	t.Helper()
+
+	// Setenv registers the cleanup that restores the original value
+	t.Setenv(key, "")
+	if err := os.Unsetenv(key); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestLogLevel(t *testing.T) {
+	testCases := []struct {
+		name        string
+		logLevel    string
+		environment string
+		expected    slog.Level
+	}{
+		{name: "Debug", logLevel: "debug", expected: slog.LevelDebug},
+		{name: "Info", logLevel: "info", expected: slog.LevelInfo},
+		{name: "Warn", logLevel: "warn", expected: slog.LevelWarn},
+		{name: "Error", logLevel: "error", expected: slog.LevelError},
+		{name: "Uppercase", logLevel: "WARN", expected: slog.LevelWarn},
+		{name: "Mixed Case", logLevel: "Error", expected: slog.LevelError},
+		{name: "Overrides Production Default", logLevel: "debug", environment: "production", expected: slog.LevelDebug},
+		{name: "Invalid", logLevel: "verbose", expected: slog.LevelInfo},
+		{name: "Invalid In Development", logLevel: "trace", environment: "development", expected: slog.LevelInfo},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Setenv("LOG_LEVEL", tc.logLevel)
+			if tc.environment != "" {
+				t.Setenv("APP_ENV", tc.environment)
+			}
+
+			cfg := LoadConfig()
+			if cfg.LogLevel != tc.expected {
+				t.Errorf("wrong log level: got %v want %v", cfg.LogLevel, tc.expected)
+			}
+		})
+	}
+}
+
+func TestLogLevelDefaultsToEnvironment(t *testing.T) {
+	testCases := []struct {
+		environment string
+		expected    slog.Level
+	}{
+		{environment: "production", expected: slog.LevelInfo},
+		{environment: "development", expected: slog.LevelDebug},
+		{environment: "staging", expected: slog.LevelDebug},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.environment, func(t *testing.T) {
+			t.Setenv("APP_ENV", tc.environment)
+			unsetenv(t, "LOG_LEVEL")
+
+			cfg := LoadConfig()
+			if cfg.LogLevel != tc.expected {
+				t.Errorf("wrong log level: got %v want %v", cfg.LogLevel, tc.expected)
+			}
+		})
+	}
+}
+
+// validConfig returns a configuration that passes validation
+func validConfig() *Config {
+	return &Config{
+		ServerPort:            "8080",
+		AllowedOrigins:        []string{"http://localhost:3000", "https://example.com"},
+		ReadTimeout:           15 * time.Second,
+		WriteTimeout:          15 * time.Second,
+		IdleTimeout:           60 * time.Second,
+		ShutdownTimeout:       15 * time.Second,
+		HandlerTimeout:        10 * time.Second,
+		IdempotencyTTL:        24 * time.Hour,
+		MaxHeaderBytes:        1 << 20,
+		WebhookTimeout:        5 * time.Second,
+		WebhookQueueSize:      100,
+		UserIDStrategy:        "sequential",
+		ReadinessPolicy:       "fail-if-any",
+		ImportDuplicatePolicy: "error",
+	}
+}
+
+func TestValidate(t *testing.T) {
+	testCases := []struct {
+		modify   func(*Config)
+		name     string
+		problems []string
+	}{
+		{name: "Valid", modify: func(*Config) {}},
+		{name: "Handler Timeout Disabled", modify: func(c *Config) { c.HandlerTimeout = 0 }},
+		{name: "Wildcard Origin", modify: func(c *Config) { c.AllowedOrigins = []string{"*"} }},
+		{name: "No Origins", modify: func(c *Config) { c.AllowedOrigins = nil }},
+		{name: "Non-Numeric Port", modify: func(c *Config) { c.ServerPort = "http" }, problems: []string{"SERVER_PORT"}},
+		{name: "Port Zero", modify: func(c *Config) { c.ServerPort = "0" }, problems: []string{"SERVER_PORT"}},
+		{name: "Port Too Large", modify: func(c *Config) { c.ServerPort = "65536" }, problems: []string{"SERVER_PORT"}},
+		{name: "Loopback Host", modify: func(c *Config) { c.ServerHost = "127.0.0.1" }},
+		{name: "IPv6 Host", modify: func(c *Config) { c.ServerHost = "::1" }},
+		{name: "Hostname Host", modify: func(c *Config) { c.ServerHost = "api.internal" }},
+		{name: "Host With Port", modify: func(c *Config) { c.ServerHost = "localhost:8080" }, problems: []string{"SERVER_HOST"}},
+		{name: "Host With Invalid Label", modify: func(c *Config) { c.ServerHost = "-api.internal" }, problems: []string{"SERVER_HOST"}},
+		{name: "TCP Listen Address", modify: func(c *Config) { c.ListenAddr = "127.0.0.1:9000" }},
+		{name: "TCP Listen Address Without Port", modify: func(c *Config) { c.ListenAddr = "127.0.0.1" }, problems: []string{"LISTEN_ADDR"}},
+		{name: "Unix Socket", modify: func(c *Config) { c.ListenNetwork = "unix"; c.ListenAddr = "/run/api.sock" }},
+		{name: "Unix Socket Without Path", modify: func(c *Config) { c.ListenNetwork = "unix" }, problems: []string{"LISTEN_ADDR"}},
+		{name: "Unknown Listen Network", modify: func(c *Config) { c.ListenNetwork = "udp" }, problems: []string{"LISTEN_NETWORK"}},
+		{name: "UUID Strategy", modify: func(c *Config) { c.UserIDStrategy = "uuid" }},
+		{name: "Unknown User ID Strategy", modify: func(c *Config) { c.UserIDStrategy = "snowflake" }, problems: []string{"USER_ID_STRATEGY"}},
+		{name: "Unknown Readiness Policy", modify: func(c *Config) { c.ReadinessPolicy = "fail-if-some" }, problems: []string{"READINESS_POLICY"}},
+		{name: "Unknown Import Duplicate Policy", modify: func(c *Config) { c.ImportDuplicatePolicy = "merge" }, problems: []string{"IMPORT_DUPLICATE_POLICY"}},
+		{name: "Zero Read Timeout", modify: func(c *Config) { c.ReadTimeout = 0 }, problems: []string{"READ_TIMEOUT"}},
+		{name: "Zero Idempotency TTL", modify: func(c *Config) { c.IdempotencyTTL = 0 }, problems: []string{"IDEMPOTENCY_TTL"}},
+		{name: "Negative Handler Timeout", modify: func(c *Config) { c.HandlerTimeout = -time.Second }, problems: []string{"HANDLER_TIMEOUT"}},
+		{name: "Negative Write Handler Timeout", modify: func(c *Config) { c.WriteHandlerTimeout = -time.Second }, problems: []string{"WRITE_HANDLER_TIMEOUT"}},
+		{name: "Unlimited Event Subscribers", modify: func(c *Config) { c.MaxEventSubscribers = 0 }},
+		{name: "Negative Max Retries", modify: func(c *Config) { c.MaxRetries = -1 }, problems: []string{"DB_MAX_RETRIES"}},
+		{name: "Negative Retry Backoff", modify: func(c *Config) { c.RetryBackoff = -time.Millisecond }, problems: []string{"RETRY_BACKOFF"}},
+		{name: "Negative Retry Budget", modify: func(c *Config) { c.RetryBudgetRPS = -1; c.RetryBudgetBurst = -1 }, problems: []string{"RETRY_BUDGET_RPS", "RETRY_BUDGET_BURST"}},
+		{name: "Breaker Disabled", modify: func(c *Config) { c.BreakerThreshold = 0; c.BreakerCooldown = 0 }},
+		{name: "Negative Breaker Threshold", modify: func(c *Config) { c.BreakerThreshold = -1 }, problems: []string{"BREAKER_THRESHOLD"}},
+		{name: "Zero Breaker Cooldown", modify: func(c *Config) { c.BreakerThreshold = 5; c.BreakerCooldown = 0 }, problems: []string{"BREAKER_COOLDOWN"}},
+		{name: "Negative User Cache Size", modify: func(c *Config) { c.UserCacheSize = -1 }, problems: []string{"USER_CACHE_SIZE"}},
+		{name: "Zero User Cache TTL", modify: func(c *Config) { c.UserCacheSize = 100; c.UserCacheTTL = 0 }, problems: []string{"USER_CACHE_TTL"}},
+		{name: "Negative Event Subscribers", modify: func(c *Config) { c.MaxEventSubscribers = -1 }, problems: []string{"MAX_EVENT_SUBSCRIBERS"}},
+		{name: "Zero Max Header Bytes", modify: func(c *Config) { c.MaxHeaderBytes = 0 }, problems: []string{"MAX_HEADER_BYTES"}},
+		{name: "Negative Max In Flight", modify: func(c *Config) { c.MaxInFlight = -1 }, problems: []string{"MAX_IN_FLIGHT"}},
+		{name: "Snapshot Interval Unused Without File", modify: func(c *Config) { c.SnapshotInterval = 0 }},
+		{name: "Zero Snapshot Interval", modify: func(c *Config) { c.SnapshotFile = "users.json"; c.SnapshotInterval = 0 }, problems: []string{"SNAPSHOT_INTERVAL"}},
+		{name: "Slow Request Threshold Disabled", modify: func(c *Config) { c.SlowRequestThreshold = 0 }},
+		{name: "Negative Slow Request Threshold", modify: func(c *Config) { c.SlowRequestThreshold = -time.Second }, problems: []string{"SLOW_REQUEST_THRESHOLD"}},
+		{name: "Maintenance Mode", modify: func(c *Config) { c.MaintenanceMode = true; c.MaintenanceRetryAfter = time.Minute }},
+		{name: "Zero Maintenance Retry After", modify: func(c *Config) { c.MaintenanceMode = true; c.MaintenanceRetryAfter = 0 }, problems: []string{"MAINTENANCE_RETRY_AFTER"}},
+		{name: "OTLP Endpoint", modify: func(c *Config) { c.OTLPEndpoint = "http://localhost:4318" }},
+		{name: "OTLP Endpoint Without Scheme", modify: func(c *Config) { c.OTLPEndpoint = "localhost:4318" }, problems: []string{"OTEL_EXPORTER_OTLP_ENDPOINT"}},
+		{name: "Webhook URL", modify: func(c *Config) { c.WebhookURL = "https://hooks.example.com/users" }},
+		{name: "Webhook URL Without Scheme", modify: func(c *Config) { c.WebhookURL = "hooks.example.com/users" }, problems: []string{"WEBHOOK_URL"}},
+		{name: "Zero Webhook Timeout", modify: func(c *Config) { c.WebhookURL = "http://localhost:9000"; c.WebhookTimeout = 0 }, problems: []string{"WEBHOOK_TIMEOUT"}},
+		{name: "Negative Webhook Max Retries", modify: func(c *Config) { c.WebhookURL = "http://localhost:9000"; c.WebhookMaxRetries = -1 }, problems: []string{"WEBHOOK_MAX_RETRIES"}},
+		{name: "Zero Webhook Queue Size", modify: func(c *Config) { c.WebhookURL = "http://localhost:9000"; c.WebhookQueueSize = 0 }, problems: []string{"WEBHOOK_QUEUE_SIZE"}},
+		{name: "Webhook Settings Ignored Without URL", modify: func(c *Config) { c.WebhookTimeout = 0; c.WebhookQueueSize = 0 }},
+		{name: "Short JWT Secret", modify: func(c *Config) { c.JWTSecret = "too-short" }, problems: []string{"JWT_SECRET"}},
+		{name: "JWT Secret", modify: func(c *Config) { c.JWTSecret = strings.Repeat("s", 32) }},
+		{name: "Relative JWT Protected Path", modify: func(c *Config) { c.JWTProtectedPaths = []string{"api/users"} }, problems: []string{"JWT_PROTECTED_PATHS"}},
+		{name: "Relative API Key Protected Path", modify: func(c *Config) { c.APIKeyProtectedPaths = []string{"api/users"} }, problems: []string{"API_KEY_PROTECTED_PATHS"}},
+		{name: "Origin Without Scheme", modify: func(c *Config) { c.AllowedOrigins = []string{"localhost:3000"} }, problems: []string{"ALLOWED_ORIGINS"}},
+		{name: "Origin With Path", modify: func(c *Config) { c.AllowedOrigins = []string{"http://example.com/app"} }, problems: []string{"ALLOWED_ORIGINS"}},
+		{
+			name: "Several Problems",
+			modify: func(c *Config) {
+				c.ServerPort = "-1"
+				c.WriteTimeout = -time.Second
+				c.IdleTimeout = 0
+				c.AllowedOrigins = []string{"ftp://example.com"}
+			},
+			problems: []string{"SERVER_PORT", "WRITE_TIMEOUT", "IDLE_TIMEOUT", "ALLOWED_ORIGINS"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := validConfig()
+			tc.modify(cfg)
+
+			err := cfg.Validate()
+			if len(tc.problems) == 0 {
+				if err != nil {
+					t.Fatalf("unexpected error: %v", err)
+				}
+				return
+			}
+
+			if !errors.Is(err, ErrInvalidConfig) {
+				t.Fatalf("wrong error: got %v want %v", err, ErrInvalidConfig)
+			}
+			for _, problem := range tc.problems {
+				if !strings.Contains(err.Error(), problem) {
+					t.Errorf("error does not mention %s: %v", problem, err)
+				}
+			}
+		})
+	}
+}
+
+func TestValidateReportsUnparsableEnvironment(t *testing.T) {
+	t.Setenv("READ_TIMEOUT", "fifteen")
+	t.Setenv("RATE_LIMIT_BURST", "lots")
+	t.Setenv("LOG_LEVEL", "verbose")
+
+	cfg := LoadConfig()
+
+	// Unparsable values still fall back to their defaults
+	if cfg.ReadTimeout != 15*time.Second {
+		t.Errorf("wrong read timeout: got %v want %v", cfg.ReadTimeout, 15*time.Second)
+	}
+
+	err := cfg.Validate()
+	if !errors.Is(err, ErrInvalidConfig) {
+		t.Fatalf("wrong error: got %v want %v", err, ErrInvalidConfig)
+	}
+	for _, key := range []string{"READ_TIMEOUT", "RATE_LIMIT_BURST", "LOG_LEVEL"} {
+		if !strings.Contains(err.Error(), key) {
+			t.Errorf("error does not mention %s: %v", key, err)
+		}
+	}
+}
+
+func TestHandlerTimeoutsFallBackToSharedTimeout(t *testing.T) {
+	t.Setenv("HANDLER_TIMEOUT", "5s")
+	t.Setenv("WRITE_HANDLER_TIMEOUT", "30s")
+
+	cfg := LoadConfig()
+
+	if cfg.ReadHandlerTimeout != 5*time.Second {
+		t.Errorf("wrong read handler timeout: got %v want %v", cfg.ReadHandlerTimeout, 5*time.Second)
+	}
+	if cfg.WriteHandlerTimeout != 30*time.Second {
+		t.Errorf("wrong write handler timeout: got %v want %v", cfg.WriteHandlerTimeout, 30*time.Second)
+	}
+}
+
+func TestDurationEnv(t *testing.T) {
+	testCases := []struct {
+		name       string
+		value      string
+		expected   time.Duration
+		deprecated bool
+		invalid    bool
+	}{
+		{name: "Plain Seconds", value: "15", expected: 15 * time.Second, deprecated: true},
+		{name: "Duration String", value: "15s", expected: 15 * time.Second},
+		{name: "Minutes", value: "2m", expected: 2 * time.Minute},
+		{name: "Invalid", value: "fifteen", expected: 30 * time.Second, invalid: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var logs bytes.Buffer
+			previous := slog.Default()
+			slog.SetDefault(slog.New(slog.NewTextHandler(&logs, nil)))
+			t.Cleanup(func() { slog.SetDefault(previous) })
+
+			t.Setenv("TEST_TIMEOUT", tc.value)
+
+			l := &loader{}
+			if got := l.durationEnv("TEST_TIMEOUT", "30s"); got != tc.expected {
+				t.Errorf("wrong duration: got %v want %v", got, tc.expected)
+			}
+			if deprecated := strings.Contains(logs.String(), "deprecated"); deprecated != tc.deprecated {
+				t.Errorf("wrong deprecation warning: got %v want %v: %s", deprecated, tc.deprecated, logs.String())
+			}
+			if invalid := len(l.errs) > 0; invalid != tc.invalid {
+				t.Errorf("wrong validation errors: got %v", l.errs)
+			}
+		})
+	}
+}
+
+func TestPrettyJSONDefaultsToEnvironment(t *testing.T) {
+	testCases := []struct {
+		environment string
+		expected    bool
+	}{
+		{environment: "production", expected: false},
+		{environment: "development", expected: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.environment, func(t *testing.T) {
+			t.Setenv("APP_ENV", tc.environment)
+			unsetenv(t, "PRETTY_JSON")
+
+			if cfg := LoadConfig(); cfg.PrettyJSON != tc.expected {
+				t.Errorf("wrong pretty JSON default: got %v want %v", cfg.PrettyJSON, tc.expected)
+			}
+		})
+	}
+}
+
+func TestAddr(t *testing.T) {
+	testCases := []struct {
+		name       string
+		host       string
+		listenAddr string
+		expected   string
+	}{
+		{name: "All Interfaces", host: "", expected: ":8080"},
+		{name: "Loopback", host: "127.0.0.1", expected: "127.0.0.1:8080"},
+		{name: "IPv6 Loopback", host: "::1", expected: "[::1]:8080"},
+		{name: "Hostname", host: "localhost", expected: "localhost:8080"},
+		{name: "Listen Address", host: "localhost", listenAddr: "127.0.0.1:9000", expected: "127.0.0.1:9000"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := validConfig()
+			cfg.ServerHost = tc.host
+			cfg.ListenAddr = tc.listenAddr
+
+			if addr := cfg.Addr(); addr != tc.expected {
+				t.Errorf("Addr returned wrong address: got %q want %q", addr, tc.expected)
+			}
+		})
+	}
+}
+
+func TestNewServer(t *testing.T) {
+	cfg := validConfig()
+	cfg.ServerHost = "127.0.0.1"
+	cfg.ServerPort = "9090"
+	cfg.ReadTimeout = 5 * time.Second
+	cfg.WriteTimeout = 7 * time.Second
+	cfg.IdleTimeout = 11 * time.Second
+	cfg.MaxHeaderBytes = 4096
+
+	handler := http.NotFoundHandler()
+	srv := cfg.NewServer(handler)
+
+	if srv.Addr != "127.0.0.1:9090" {
+		t.Errorf("server has wrong address: got %q want %q", srv.Addr, "127.0.0.1:9090")
+	}
+	if srv.Handler == nil {
+		t.Error("server has no handler")
+	}
+	if srv.ReadTimeout != cfg.ReadTimeout {
+		t.Errorf("server has wrong read timeout: got %v want %v", srv.ReadTimeout, cfg.ReadTimeout)
+	}
+	if srv.WriteTimeout != cfg.WriteTimeout {
+		t.Errorf("server has wrong write timeout: got %v want %v", srv.WriteTimeout, cfg.WriteTimeout)
+	}
+	if srv.IdleTimeout != cfg.IdleTimeout {
+		t.Errorf("server has wrong idle timeout: got %v want %v", srv.IdleTimeout, cfg.IdleTimeout)
+	}
+	if srv.MaxHeaderBytes != cfg.MaxHeaderBytes {
+		t.Errorf("server has wrong header size limit: got %v want %v", srv.MaxHeaderBytes, cfg.MaxHeaderBytes)
+	}
+}
+
+func TestPairsEnv(t *testing.T) {
+	testCases := []struct {
+		expected map[string]string
+		name     string
+		value    string
+		invalid  bool
+	}{
+		{name: "Empty", value: "", expected: map[string]string{}},
+		{name: "Pairs", value: "alice:secret, bob:hunter2", expected: map[string]string{"alice": "secret", "bob": "hunter2"}},
+		{name: "Value With Colon", value: "alice:se:cret", expected: map[string]string{"alice": "se:cret"}},
+		{name: "Missing Value", value: "alice,bob:hunter2", expected: map[string]string{"bob": "hunter2"}, invalid: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Setenv("BASIC_AUTH_USERS", tc.value)
+
+			cfg := LoadConfig()
+			if !maps.Equal(cfg.BasicAuthUsers, tc.expected) {
+				t.Errorf("wrong pairs: got %v want %v", cfg.BasicAuthUsers, tc.expected)
+			}
+
+			err := cfg.Validate()
+			if invalid := err != nil && strings.Contains(err.Error(), "BASIC_AUTH_USERS"); invalid != tc.invalid {
+				t.Errorf("wrong validation result: got %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,93 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ErrConfigFile is returned when a configuration file can't be loaded
+var ErrConfigFile = errors.New("invalid configuration file")
+
+// LoadConfigFromFile loads the configuration from a JSON or YAML file,
+// chosen by its extension. Its keys are the names of the environment
+// variables in lowercase, e.g. "server_port", and environment variables
+// override the values in the file.
+func LoadConfigFromFile(path string) (*Config, error) {
+	data, err := os.ReadFile(filepath.Clean(path))
+	if err != nil {
+		return nil, fmt.Errorf("reading configuration file: %w", err)
+	}
+
+	var raw map[string]any
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".json":
+		err = json.Unmarshal(data, &raw)
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &raw)
+	default:
+		return nil, fmt.Errorf("%w: %s: unsupported extension %q, must be .json, .yaml or .yml", ErrConfigFile, path, ext)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s: %w", ErrConfigFile, path, err)
+	}
+
+	file := make(map[string]string, len(raw))
+	for key, value := range raw {
+		s, err := fileValue(value)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s: %s: %w", ErrConfigFile, path, key, err)
+		}
+		file[strings.ToLower(key)] = s
+	}
+
+	l := &loader{file: file}
+	cfg := load(l)
+
+	var unknown []string
+	for key := range file {
+		if !l.keys[key] {
+			unknown = append(unknown, key)
+		}
+	}
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return nil, fmt.Errorf("%w: %s: unknown keys %s", ErrConfigFile, path, strings.Join(unknown, ", "))
+	}
+
+	return cfg, nil
+}
+
+// fileValue converts a value of a configuration file to the string form of
+// the corresponding environment variable; lists become comma-separated
+func fileValue(value any) (string, error) {
+	switch v := value.(type) {
+	case string:
+		return v, nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	case int:
+		return strconv.Itoa(v), nil
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), nil
+	case []any:
+		parts := make([]string, 0, len(v))
+		for _, item := range v {
+			s, err := fileValue(item)
+			if err != nil {
+				return "", err
+			}
+			parts = append(parts, s)
+		}
+		return strings.Join(parts, ","), nil
+	default:
+		return "", fmt.Errorf("unsupported value of type %T", value)
+	}
+}
@@ -0,0 +1,158 @@
+package config
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// writeFile writes a configuration file into a temporary directory
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadConfigFromFile(t *testing.T) {
+	testCases := []struct {
+		name    string
+		file    string
+		content string
+	}{
+		{
+			name: "JSON",
+			file: "config.json",
+			content: `{
+				"server_port": "9090",
+				"read_timeout": "5s",
+				"rate_limit_rps": 2.5,
+				"max_body_bytes": 2048,
+				"enable_debug_endpoints": true,
+				"allowed_origins": ["https://example.com", "https://example.org"]
+			}`,
+		},
+		{
+			name: "YAML",
+			file: "config.yaml",
+			content: `server_port: 9090
+read_timeout: 5s
+rate_limit_rps: 2.5
+max_body_bytes: 2048
+enable_debug_endpoints: true
+allowed_origins:
+  - https://example.com
+  - https://example.org
+`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			for _, key := range []string{"SERVER_PORT", "READ_TIMEOUT", "RATE_LIMIT_RPS", "MAX_BODY_BYTES", "ENABLE_DEBUG_ENDPOINTS", "ALLOWED_ORIGINS", "WRITE_TIMEOUT"} {
+				unsetenv(t, key)
+			}
+
+			cfg, err := LoadConfigFromFile(writeFile(t, tc.file, tc.content))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if cfg.ServerPort != "9090" {
+				t.Errorf("wrong server port: got %v want %v", cfg.ServerPort, "9090")
+			}
+			if cfg.ReadTimeout != 5*time.Second {
+				t.Errorf("wrong read timeout: got %v want %v", cfg.ReadTimeout, 5*time.Second)
+			}
+			if cfg.RateLimitRPS != 2.5 {
+				t.Errorf("wrong rate limit: got %v want %v", cfg.RateLimitRPS, 2.5)
+			}
+			if cfg.MaxBodyBytes != 2048 {
+				t.Errorf("wrong max body bytes: got %v want %v", cfg.MaxBodyBytes, 2048)
+			}
+			if !cfg.DebugEndpoints {
+				t.Error("debug endpoints should be enabled")
+			}
+			if len(cfg.AllowedOrigins) != 2 || cfg.AllowedOrigins[1] != "https://example.org" {
+				t.Errorf("wrong allowed origins: got %v", cfg.AllowedOrigins)
+			}
+
+			// Values missing from the file keep their defaults
+			if cfg.WriteTimeout != 15*time.Second {
+				t.Errorf("wrong write timeout: got %v want %v", cfg.WriteTimeout, 15*time.Second)
+			}
+
+			if err := cfg.Validate(); err != nil {
+				t.Errorf("unexpected validation error: %v", err)
+			}
+		})
+	}
+}
+
+func TestLoadConfigFromFileEnvOverrides(t *testing.T) {
+	path := writeFile(t, "config.json", `{"server_port": "9090", "read_timeout": "5s"}`)
+	t.Setenv("SERVER_PORT", "7070")
+	unsetenv(t, "READ_TIMEOUT")
+
+	cfg, err := LoadConfigFromFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if cfg.ServerPort != "7070" {
+		t.Errorf("environment should override the file: got %v want %v", cfg.ServerPort, "7070")
+	}
+	if cfg.ReadTimeout != 5*time.Second {
+		t.Errorf("wrong read timeout: got %v want %v", cfg.ReadTimeout, 5*time.Second)
+	}
+}
+
+func TestLoadConfigFromFileErrors(t *testing.T) {
+	testCases := []struct {
+		expected error
+		name     string
+		file     string
+		content  string
+	}{
+		{name: "Malformed JSON", file: "config.json", content: `{"server_port":`, expected: ErrConfigFile},
+		{name: "Malformed YAML", file: "config.yaml", content: "server_port: [", expected: ErrConfigFile},
+		{name: "Unknown Key", file: "config.json", content: `{"server_prot": "9090"}`, expected: ErrConfigFile},
+		{name: "Unsupported Extension", file: "config.toml", content: `server_port = "9090"`, expected: ErrConfigFile},
+		{name: "Unsupported Value", file: "config.json", content: `{"server_port": {"value": 9090}}`, expected: ErrConfigFile},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if _, err := LoadConfigFromFile(writeFile(t, tc.file, tc.content)); !errors.Is(err, tc.expected) {
+				t.Errorf("wrong error: got %v want %v", err, tc.expected)
+			}
+		})
+	}
+
+	t.Run("Missing File", func(t *testing.T) {
+		_, err := LoadConfigFromFile(filepath.Join(t.TempDir(), "missing.json"))
+		if !errors.Is(err, fs.ErrNotExist) {
+			t.Errorf("wrong error: got %v want %v", err, fs.ErrNotExist)
+		}
+	})
+}
+
+func TestLoadConfigFromFileReportsUnparsableValues(t *testing.T) {
+	unsetenv(t, "IDLE_TIMEOUT")
+	path := writeFile(t, "config.yaml", "idle_timeout: forever\n")
+
+	cfg, err := LoadConfigFromFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := cfg.Validate(); !errors.Is(err, ErrInvalidConfig) {
+		t.Errorf("wrong error: got %v want %v", err, ErrInvalidConfig)
+	}
+}
@@ -0,0 +1,354 @@
+package handlers
+
+import (
+	"context"
+	"expvar"
+	"log/slog"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/graphql-go/graphql"
+
+	"github.com/kakkoyun/demo-web-service/store"
+)
+
+// API serves the application's HTTP endpoints.
+// All of its dependencies are injected, so tests can swap them for fakes.
+type API struct {
+	users              store.UserStore
+	logger             *slog.Logger
+	faults             FaultInjector
+	readiness          *Readiness
+	background         *Background
+	events             *broadcast
+	idempotency        *idempotencyCache
+	dbErrors           *expvar.Map
+	retryBudget        *RetryBudget
+	breaker            *CircuitBreaker
+	userSchema         *SchemaValidator
+	webhooks           *WebhookDispatcher
+	version            string
+	duplicatePolicy    store.DuplicatePolicy
+	maxBodyBytes       int64
+	importMaxBodyBytes int64
+	retryBackoff       time.Duration
+	maxRetries         int
+	debugEndpoints     bool
+	publicIDPaths      bool
+
+	// graphQLSchema returns the GraphQL schema, building it on first use
+	graphQLSchema func() (graphql.Schema, error)
+	// streamingRoutes matches the requests for streaming routes
+	streamingRoutes *http.ServeMux
+	// readOnlyRoutes matches the requests for routes that only read, whatever their method
+	readOnlyRoutes *http.ServeMux
+	// extraRoutes are the application's own endpoints added with Handle
+	extraRoutes []route
+}
+
+const (
+	// DefaultMaxBodyBytes is the default limit on the size of request bodies
+	DefaultMaxBodyBytes = 1 << 20
+	// DefaultImportMaxBodyBytes is the default limit on the size of bulk import bodies
+	DefaultImportMaxBodyBytes = 10 << 20
+)
+
+// Option configures optional API dependencies and settings
+type Option func(*API)
+
+// WithFaultInjector sets the FaultInjector that decides when failures are simulated
+func WithFaultInjector(f FaultInjector) Option {
+	return func(a *API) {
+		a.faults = f
+	}
+}
+
+// WithDuplicatePolicy sets how imports handle users whose ID is already taken
+func WithDuplicatePolicy(p store.DuplicatePolicy) Option {
+	return func(a *API) {
+		a.duplicatePolicy = p
+	}
+}
+
+// WithMaxBodyBytes sets the maximum size of request bodies in bytes
+func WithMaxBodyBytes(n int64) Option {
+	return func(a *API) {
+		a.maxBodyBytes = n
+	}
+}
+
+// WithImportMaxBodyBytes sets the maximum size of bulk import bodies in bytes
+func WithImportMaxBodyBytes(n int64) Option {
+	return func(a *API) {
+		a.importMaxBodyBytes = n
+	}
+}
+
+// WithMaxEventSubscribers sets the maximum number of concurrent event stream
+// subscribers; 0 means unlimited
+func WithMaxEventSubscribers(n int) Option {
+	return func(a *API) {
+		a.events.limit = n
+	}
+}
+
+// WithDBErrorMetrics counts simulated database errors in m, keyed by error type
+// such as "query_timeout"; nil disables the metrics
+func WithDBErrorMetrics(m *expvar.Map) Option {
+	return func(a *API) {
+		a.dbErrors = m
+	}
+}
+
+// WithVersion sets the application version reported by the health check
+func WithVersion(version string) Option {
+	return func(a *API) {
+		a.version = version
+	}
+}
+
+// WithDebugEndpoints enables endpoints meant for demos and tests only
+func WithDebugEndpoints(enabled bool) Option {
+	return func(a *API) {
+		a.debugEndpoints = enabled
+	}
+}
+
+// WithPublicIDPaths looks up the users in /api/users/{id} paths by their
+// public IDs, so that opaque public IDs can't be bypassed with internal ones
+func WithPublicIDPaths(enabled bool) Option {
+	return func(a *API) {
+		a.publicIDPaths = enabled
+	}
+}
+
+// WithUserSchema validates user bodies against a JSON Schema before they are
+// decoded; nil disables schema validation
+func WithUserSchema(v *SchemaValidator) Option {
+	return func(a *API) {
+		a.userSchema = v
+	}
+}
+
+// WithWebhooks sends created users to the webhook URL of d and starts its
+// delivery worker; nil disables webhooks
+func WithWebhooks(d *WebhookDispatcher) Option {
+	return func(a *API) {
+		a.webhooks = d
+	}
+}
+
+// NewAPI creates a new API backed by the given user store.
+// A nil logger uses the default slog logger at the time of logging.
+// Unless configured otherwise, failures are simulated at random and imports
+// reject duplicate IDs. The readiness endpoint pings the store.
+func NewAPI(users store.UserStore, logger *slog.Logger, opts ...Option) *API {
+	a := &API{
+		users:              users,
+		logger:             logger,
+		faults:             NewProbabilisticFaults(nil),
+		readiness:          NewReadiness(),
+		background:         NewBackground(logger),
+		events:             newBroadcast(DefaultMaxEventSubscribers),
+		idempotency:        newIdempotencyCache(DefaultIdempotencyTTL),
+		version:            "unknown",
+		duplicatePolicy:    store.DuplicateError,
+		maxBodyBytes:       DefaultMaxBodyBytes,
+		importMaxBodyBytes: DefaultImportMaxBodyBytes,
+		retryBackoff:       DefaultRetryBackoff,
+	}
+
+	for _, opt := range opts {
+		opt(a)
+	}
+
+	if a.webhooks != nil {
+		a.webhooks.start(a.background)
+	}
+
+	a.streamingRoutes = http.NewServeMux()
+	a.readOnlyRoutes = http.NewServeMux()
+	for _, rt := range a.routes() {
+		if rt.streaming {
+			a.streamingRoutes.HandleFunc(rt.method+" "+rt.pattern, rt.handler)
+		}
+		if rt.readOnly {
+			a.readOnlyRoutes.HandleFunc(rt.method+" "+rt.pattern, rt.handler)
+		}
+	}
+
+	a.graphQLSchema = sync.OnceValues(a.newGraphQLSchema)
+
+	// The service can't serve users without its store
+	a.readiness.Register("store", ReadinessCheckerFunc(users.Ping))
+
+	return a
+}
+
+// Readiness returns the registry of readiness checks served by the readiness endpoint
+func (a *API) Readiness() *Readiness {
+	return a.readiness
+}
+
+// Background returns the tracker of goroutines spawned while handling requests,
+// which a graceful shutdown waits for
+func (a *API) Background() *Background {
+	return a.background
+}
+
+// route is a single endpoint served by the API
+type route struct {
+	handler http.HandlerFunc
+	method  string
+	pattern string
+	// name identifies the handler in access logs
+	name string
+	// maxBodyBytes limits the size of the request body; 0 uses the API's default
+	maxBodyBytes int64
+	// streaming routes keep their response open for as long as the client
+	// stays connected, so they are exempt from handler timeouts and the
+	// in-flight limit, see Streaming
+	streaming bool
+	// readOnly routes change nothing even though their method may, see ReadOnly
+	readOnly bool
+}
+
+// routes returns the table of endpoints served by the API
+func (a *API) routes() []route {
+	return []route{
+		{method: "GET", pattern: "/{$}", name: "HomeHandler", handler: a.Home},
+		{method: "GET", pattern: "/api/health", name: "HealthCheckHandler", handler: a.HealthCheck},
+		{method: "GET", pattern: "/api/health/live", name: "LivenessHandler", handler: LivenessHandler},
+		{method: "GET", pattern: "/api/health/ready", name: "ReadinessHandler", handler: a.readiness.ServeHTTP},
+		{method: "GET", pattern: "/api/users", name: "GetUsersHandler", handler: a.GetUsers},
+		{method: "POST", pattern: "/api/users", name: "CreateUserHandler", handler: a.idempotent(a.CreateUser)},
+		{method: "GET", pattern: "/api/users/count", name: "CountUsersHandler", handler: a.CountUsers},
+		{method: "GET", pattern: "/api/users/stream", name: "StreamUsersHandler", handler: a.StreamUsers},
+		{method: "GET", pattern: "/api/users/events", name: "UserEventsHandler", handler: a.UserEvents, streaming: true},
+		{method: "POST", pattern: "/api/users/import", name: "ImportUsersHandler", handler: a.ImportUsers, maxBodyBytes: a.importMaxBodyBytes},
+		{method: "GET", pattern: "/api/users/{id}", name: "GetUserHandler", handler: a.GetUser},
+		{method: "PATCH", pattern: "/api/users/{id}", name: "PatchUserHandler", handler: a.PatchUser},
+		{method: "POST", pattern: "/api/graphql", name: "GraphQLHandler", handler: a.GraphQL, readOnly: true}, // The schema has no mutations
+		{method: "POST", pattern: "/api/admin/reset", name: "ResetHandler", handler: a.Reset},
+		{method: "GET", pattern: "/openapi.json", name: "OpenAPIHandler", handler: OpenAPI},
+		{method: "GET", pattern: "/docs", name: "DocsHandler", handler: Docs},
+	}
+}
+
+// Handle adds an endpoint served alongside the API's own, such as the
+// application's version, so that it gets the same 404 and 405 responses.
+// It must be called before Routes.
+func (a *API) Handle(method, pattern, name string, handler http.HandlerFunc) {
+	a.extraRoutes = append(a.extraRoutes, route{method: method, pattern: pattern, name: name, handler: handler})
+}
+
+// Routes returns a handler that serves all of the API's endpoints.
+// Requests for unknown paths get a JSON 404 from NotFoundHandler.
+func (a *API) Routes() http.Handler {
+	mux := http.NewServeMux()
+	var methods []string
+
+	// Set up routes with Go 1.22 pattern syntax
+	for _, rt := range slices.Concat(a.routes(), a.extraRoutes) {
+		limit := rt.maxBodyBytes
+		if limit == 0 {
+			limit = a.maxBodyBytes
+		}
+		mux.HandleFunc(rt.method+" "+rt.pattern, named(rt.name, limitBody(rt.handler, limit)))
+
+		if !slices.Contains(methods, rt.method) {
+			methods = append(methods, rt.method)
+		}
+	}
+
+	return withFallback(mux, methods)
+}
+
+// Streaming reports whether r is for a streaming route, such as the user event
+// stream, which is long-lived by design. Middlewares bounding the time or
+// number of requests should skip such requests, see Unless.
+func (a *API) Streaming(r *http.Request) bool {
+	_, pattern := a.streamingRoutes.Handler(r)
+	return pattern != ""
+}
+
+// ReadOnly reports whether r can't change state: it uses a read method such as
+// GET, or is for a route that only reads whatever its method, such as GraphQL
+// queries. Middlewares guarding writes only should skip such requests, see Unless.
+func (a *API) ReadOnly(r *http.Request) bool {
+	if isReadMethod(r.Method) {
+		return true
+	}
+	_, pattern := a.readOnlyRoutes.Handler(r)
+	return pattern != ""
+}
+
+// named reports the name of the handler serving a request for access logs,
+// and names the request's trace span after the route's pattern
+func named(name string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		recordHandlerName(r.Context(), name)
+		nameSpan(r)
+		next(w, r)
+	}
+}
+
+// limitBody refuses to read more than limit bytes of a request body
+func limitBody(next http.HandlerFunc, limit int64) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Body != nil {
+			r.Body = http.MaxBytesReader(w, r.Body, limit)
+		}
+		next(w, r)
+	}
+}
+
+// log returns the logger for a request: the request-scoped logger stored in ctx
+// by ContextLoggerMiddleware, else the API's logger, else the current default logger,
+// carrying the IDs of the request's trace span if there is one
+func (a *API) log(ctx context.Context) *slog.Logger {
+	logger, ok := contextLogger(ctx)
+	switch {
+	case ok:
+	case a.logger != nil:
+		logger = a.logger
+	default:
+		logger = slog.Default()
+	}
+	return withTraceIDs(ctx, logger)
+}
+
+// defaultAPI backs the package-level handler functions
+var defaultAPI = NewAPI(store.NewDemoStore(), nil)
+
+// HomeHandler handles the root endpoint using the default API
+func HomeHandler(w http.ResponseWriter, r *http.Request) {
+	defaultAPI.Home(w, r)
+}
+
+// HealthCheckHandler returns the API health status using the default API
+func HealthCheckHandler(w http.ResponseWriter, r *http.Request) {
+	defaultAPI.HealthCheck(w, r)
+}
+
+// GetUsersHandler returns a list of users using the default API
+func GetUsersHandler(w http.ResponseWriter, r *http.Request) {
+	defaultAPI.GetUsers(w, r)
+}
+
+// CreateUserHandler creates a new user using the default API
+func CreateUserHandler(w http.ResponseWriter, r *http.Request) {
+	defaultAPI.CreateUser(w, r)
+}
+
+// GetUserHandler returns a specific user by ID using the default API
+func GetUserHandler(w http.ResponseWriter, r *http.Request) {
+	defaultAPI.GetUser(w, r)
+}
+
+// PatchUserHandler partially updates a specific user by ID using the default API
+func PatchUserHandler(w http.ResponseWriter, r *http.Request) {
+	defaultAPI.PatchUser(w, r)
+}
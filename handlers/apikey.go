@@ -0,0 +1,61 @@
+package handlers
+
+import (
+	"context"
+	"crypto/subtle"
+	"net/http"
+
+	"github.com/kakkoyun/demo-web-service/internal/ctxkeys"
+)
+
+// APIKeyHeader is the header clients send their API key in
+const APIKeyHeader = "X-API-Key"
+
+// APIKeyMiddleware creates a middleware that only lets through requests with
+// an X-API-Key header holding one of the keys in keys, which maps API keys to
+// the identity of their owner. The owner is stored in the request context, see
+// OwnerFromContext, and added to the request's log lines. Other requests get a 401.
+func APIKeyMiddleware(keys map[string]string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			owner, ok := lookupAPIKey(keys, r.Header.Get(APIKeyHeader))
+			if !ok {
+				LoggerFromContext(r.Context()).Warn("API key authentication failed",
+					"key_present", r.Header.Get(APIKeyHeader) != "")
+
+				errorResponse(w, r, http.StatusUnauthorized, "A valid API key is required")
+				return
+			}
+
+			recordOwner(r.Context(), owner)
+			ctx := ctxkeys.WithOwner(r.Context(), owner)
+			if logger, ok := contextLogger(ctx); ok {
+				ctx = ctxkeys.WithLogger(ctx, logger.With("owner", owner))
+			}
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// OwnerFromContext returns the API key owner stored in ctx by
+// APIKeyMiddleware, or an empty string if there is none
+func OwnerFromContext(ctx context.Context) string {
+	return ctxkeys.Owner(ctx)
+}
+
+// lookupAPIKey returns the owner of key. Every configured key is compared in
+// constant time, so the response time doesn't reveal how close a guess was.
+func lookupAPIKey(keys map[string]string, key string) (string, bool) {
+	if key == "" {
+		return "", false
+	}
+
+	var owner string
+	found := false
+	for candidate, candidateOwner := range keys {
+		if subtle.ConstantTimeCompare([]byte(key), []byte(candidate)) == 1 {
+			owner, found = candidateOwner, true
+		}
+	}
+	return owner, found
+}
@@ -0,0 +1,59 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestAPIKeyMiddleware(t *testing.T) {
+	keys := map[string]string{"key-123": "billing-service"}
+
+	testCases := []struct {
+		name           string
+		key            string
+		expectedOwner  string
+		expectedStatus int
+	}{
+		{name: "Recognized Key", key: "key-123", expectedStatus: http.StatusOK, expectedOwner: "billing-service"},
+		{name: "Unrecognized Key", key: "key-456", expectedStatus: http.StatusUnauthorized},
+		{name: "Missing Key", expectedStatus: http.StatusUnauthorized},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			logs := captureLogs(t)
+
+			var owner string
+			handler := LoggingMiddleware(0)(ContextLoggerMiddleware(nil)(APIKeyMiddleware(keys)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				owner = OwnerFromContext(r.Context())
+				LoggerFromContext(r.Context()).Info("Handled")
+			}))))
+
+			req := httptest.NewRequest("GET", "/api/users", nil)
+			if tc.key != "" {
+				req.Header.Set(APIKeyHeader, tc.key)
+			}
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			if status := rr.Code; status != tc.expectedStatus {
+				t.Errorf("handler returned wrong status code: got %v want %v", status, tc.expectedStatus)
+			}
+			if owner != tc.expectedOwner {
+				t.Errorf("handler saw wrong owner: got %q want %q", owner, tc.expectedOwner)
+			}
+
+			for _, line := range strings.Split(strings.TrimSpace(logs.String()), "\n") {
+				hasOwner := strings.Contains(line, `"owner":"billing-service"`)
+				if tc.expectedOwner != "" && !hasOwner {
+					t.Errorf("log line lacks the key owner: %s", line)
+				}
+				if strings.Contains(line, tc.key) && tc.key != "" {
+					t.Errorf("log line leaks the API key: %s", line)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,109 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestBasicAuthMiddleware(t *testing.T) {
+	users := map[string]string{"alice": "secret"}
+
+	testCases := []struct {
+		name           string
+		username       string
+		password       string
+		setAuth        bool
+		expectedStatus int
+	}{
+		{name: "Valid Credentials", username: "alice", password: "secret", setAuth: true, expectedStatus: http.StatusCreated},
+		{name: "Wrong Password", username: "alice", password: "guess", setAuth: true, expectedStatus: http.StatusUnauthorized},
+		{name: "Unknown User", username: "mallory", password: "secret", setAuth: true, expectedStatus: http.StatusUnauthorized},
+		{name: "Empty Password For Unknown User", username: "mallory", password: "", setAuth: true, expectedStatus: http.StatusUnauthorized},
+		{name: "Missing Header", expectedStatus: http.StatusUnauthorized},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			api, _ := newTestAPI(t, nil)
+			handler := BasicAuthMiddleware(users)(api.Routes())
+
+			req := httptest.NewRequest("POST", "/api/users", strings.NewReader(`{"name":"New User"}`))
+			if tc.setAuth {
+				req.SetBasicAuth(tc.username, tc.password)
+			}
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			if status := rr.Code; status != tc.expectedStatus {
+				t.Errorf("handler returned wrong status code: got %v want %v", status, tc.expectedStatus)
+			}
+
+			challenge := rr.Header().Get("WWW-Authenticate")
+			if tc.expectedStatus == http.StatusUnauthorized && !strings.HasPrefix(challenge, "Basic ") {
+				t.Errorf("handler returned wrong WWW-Authenticate header: got %q", challenge)
+			}
+			if tc.expectedStatus != http.StatusUnauthorized && challenge != "" {
+				t.Errorf("handler challenged an authenticated request: %q", challenge)
+			}
+		})
+	}
+}
+
+func TestReadOnlySkipsWriteAuthentication(t *testing.T) {
+	api, _ := newTestAPI(t, nil)
+	handler := Unless(api.ReadOnly, BasicAuthMiddleware(map[string]string{"alice": "secret"}))(api.Routes())
+
+	testCases := []struct {
+		name           string
+		method         string
+		path           string
+		body           string
+		expectedStatus int
+	}{
+		{name: "Read", method: "GET", path: "/api/users", expectedStatus: http.StatusOK},
+		{name: "Write", method: "POST", path: "/api/users", body: `{"name":"New User"}`, expectedStatus: http.StatusUnauthorized},
+		{name: "GraphQL Query", method: "POST", path: "/api/graphql", body: `{"query":"{ users { name } }"}`, expectedStatus: http.StatusOK},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(tc.method, tc.path, strings.NewReader(tc.body))
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			if status := rr.Code; status != tc.expectedStatus {
+				t.Errorf("handler returned wrong status code: got %v want %v", status, tc.expectedStatus)
+			}
+		})
+	}
+}
+
+func TestPathsOnly(t *testing.T) {
+	api, _ := newTestAPI(t, nil)
+	handler := PathsOnly([]string{"/api/users/"}, BasicAuthMiddleware(map[string]string{"alice": "secret"}))(api.Routes())
+
+	testCases := []struct {
+		name           string
+		path           string
+		expectedStatus int
+	}{
+		{name: "Prefix", path: "/api/users", expectedStatus: http.StatusUnauthorized},
+		{name: "Below Prefix", path: "/api/users/1", expectedStatus: http.StatusUnauthorized},
+		{name: "Other Path", path: "/api/health/live", expectedStatus: http.StatusOK},
+		{name: "Sibling Path", path: "/api/users-export", expectedStatus: http.StatusNotFound},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", tc.path, nil)
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			if status := rr.Code; status != tc.expectedStatus {
+				t.Errorf("handler returned wrong status code: got %v want %v", status, tc.expectedStatus)
+			}
+		})
+	}
+}
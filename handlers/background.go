@@ -0,0 +1,155 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"runtime/debug"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrBackgroundPending is returned by Background.Wait when background work
+// hasn't finished before the deadline
+var ErrBackgroundPending = errors.New("background work still pending")
+
+// Background tracks goroutines spawned while handling requests, such as
+// webhook dispatch, so that a graceful shutdown can wait for them to finish
+type Background struct {
+	logger  *slog.Logger
+	pending map[string]int
+	wg      sync.WaitGroup
+	mu      sync.Mutex
+}
+
+// NewBackground creates a Background with no work in progress.
+// A nil logger uses the default slog logger at the time of logging.
+func NewBackground(logger *slog.Logger) *Background {
+	return &Background{
+		logger:  logger,
+		pending: make(map[string]int),
+	}
+}
+
+// Go runs fn in a tracked goroutine, recovering any panic.
+// The category describes the kind of work when it is reported as pending.
+func (b *Background) Go(category string, fn func()) {
+	b.mu.Lock()
+	b.pending[category]++
+	b.mu.Unlock()
+	b.wg.Add(1)
+
+	safeGo(context.Background(), b.log(), category, false, func(context.Context) {
+		defer b.done(category)
+		fn()
+	})
+}
+
+// done records that a goroutine of the given category has finished
+func (b *Background) done(category string) {
+	b.mu.Lock()
+	b.pending[category]--
+	if b.pending[category] == 0 {
+		delete(b.pending, category)
+	}
+	b.mu.Unlock()
+	b.wg.Done()
+}
+
+// Wait blocks until all tracked goroutines have finished or ctx is done.
+// In the latter case the error lists how much work of each category is still pending.
+func (b *Background) Wait(ctx context.Context) error {
+	finished := make(chan struct{})
+	go func() {
+		b.wg.Wait()
+		close(finished)
+	}()
+
+	select {
+	case <-finished:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("%w: %s", ErrBackgroundPending, b.describePending())
+	}
+}
+
+// describePending lists the pending work by category, e.g. "webhook=2"
+func (b *Background) describePending() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	pending := make([]string, 0, len(b.pending))
+	for category, n := range b.pending {
+		pending = append(pending, fmt.Sprintf("%s=%d", category, n))
+	}
+	sort.Strings(pending)
+
+	return strings.Join(pending, ", ")
+}
+
+// log returns the logger, falling back to the current default logger
+func (b *Background) log() *slog.Logger {
+	if b.logger != nil {
+		return b.logger
+	}
+	return slog.Default()
+}
+
+// Delays before restarting a worker that panicked, doubling with every
+// consecutive panic up to maxRestartDelay
+var (
+	restartDelay    = time.Second
+	maxRestartDelay = 30 * time.Second
+)
+
+// safeGo runs fn in a new goroutine named for logging, recovering and
+// reporting any panic so that a failing background worker can't crash the
+// process. With restart set, fn is started again after a panic, with an
+// exponential backoff, until ctx is done.
+// The returned channel is closed once the worker has stopped for good.
+func safeGo(ctx context.Context, logger *slog.Logger, name string, restart bool, fn func(context.Context)) <-chan struct{} {
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+
+		delay := restartDelay
+		for runRecovered(ctx, logger, name, fn) && restart {
+			logger.Info("Restarting background worker", "worker", name, "delay", delay)
+			if err := sleepContext(ctx, delay); err != nil {
+				logger.Info("Background worker not restarted", "worker", name, "reason", err)
+				return
+			}
+			delay = min(2*delay, maxRestartDelay)
+		}
+	}()
+
+	return done
+}
+
+// runRecovered runs fn, reporting whether it panicked
+func runRecovered(ctx context.Context, logger *slog.Logger, name string, fn func(context.Context)) (panicked bool) {
+	defer func() {
+		if rec := recover(); rec != nil {
+			LogPanic(logger, "Background worker panic recovered", "background worker "+name, rec, "worker", name)
+			panicked = true
+		}
+	}()
+
+	fn(ctx)
+	return false
+}
+
+// LogPanic logs a recovered panic at error level with msg, the panic value,
+// its stack trace and attrs, as an error saying it happened in where.
+// In a real app, this would also be sent to your error tracking service.
+func LogPanic(logger *slog.Logger, msg, where string, rec any, attrs ...any) {
+	logger.Error(msg, append([]any{
+		"error", fmt.Errorf("panic in %s: %v", where, rec),
+		"panic", rec,
+		"stack_trace", string(debug.Stack()),
+	}, attrs...)...)
+}
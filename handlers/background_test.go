@@ -0,0 +1,151 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSafeGoRecoversPanics(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, nil))
+
+	done := safeGo(context.Background(), logger, "exploding", false, func(context.Context) {
+		panic("boom")
+	})
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("worker did not stop after panicking")
+	}
+
+	logs := buf.String()
+	for _, want := range []string{"Background worker panic recovered", `"worker":"exploding"`, `"panic":"boom"`, "stack_trace"} {
+		if !strings.Contains(logs, want) {
+			t.Errorf("log does not contain %s: %s", want, logs)
+		}
+	}
+}
+
+func TestSafeGoRestartsWorker(t *testing.T) {
+	previous := restartDelay
+	restartDelay = 0
+	t.Cleanup(func() { restartDelay = previous })
+
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, nil))
+
+	// The worker panics on its first two runs and returns normally on the third
+	runs := 0
+	done := safeGo(context.Background(), logger, "flaky", true, func(context.Context) {
+		runs++
+		if runs < 3 {
+			panic("boom")
+		}
+	})
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("worker did not stop")
+	}
+
+	if runs != 3 {
+		t.Errorf("worker ran wrong number of times: got %v want %v", runs, 3)
+	}
+	if got := strings.Count(buf.String(), "Background worker panic recovered"); got != 2 {
+		t.Errorf("wrong number of panics logged: got %v want %v", got, 2)
+	}
+}
+
+func TestSafeGoStopsRestartingWhenCanceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	// The worker always panics, and the first restart is canceled during its backoff
+	runs := 0
+	done := safeGo(ctx, slog.New(slog.DiscardHandler), "broken", true, func(context.Context) {
+		runs++
+		cancel()
+		panic("boom")
+	})
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("worker kept restarting after cancellation")
+	}
+
+	if runs != 1 {
+		t.Errorf("worker ran wrong number of times: got %v want %v", runs, 1)
+	}
+}
+
+func TestBackgroundWait(t *testing.T) {
+	testCases := []struct {
+		expectedErr error
+		name        string
+		task        time.Duration
+		deadline    time.Duration
+	}{
+		{name: "Finishes Before Deadline", task: 50 * time.Millisecond, deadline: time.Second},
+		{name: "Exceeds Deadline", task: time.Second, deadline: 50 * time.Millisecond, expectedErr: ErrBackgroundPending},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			b := NewBackground(slog.New(slog.DiscardHandler))
+
+			finished := make(chan struct{})
+			b.Go("webhook", func() {
+				time.Sleep(tc.task)
+				close(finished)
+			})
+			b.Go("quick", func() {})
+
+			ctx, cancel := context.WithTimeout(context.Background(), tc.deadline)
+			defer cancel()
+
+			start := time.Now()
+			err := b.Wait(ctx)
+			elapsed := time.Since(start)
+
+			if !errors.Is(err, tc.expectedErr) {
+				t.Fatalf("wrong error: got %v want %v", err, tc.expectedErr)
+			}
+
+			if tc.expectedErr == nil {
+				// Wait returned only after the slow task finished
+				select {
+				case <-finished:
+				default:
+					t.Error("wait returned before the task finished")
+				}
+				return
+			}
+
+			if elapsed >= tc.task {
+				t.Errorf("wait outlasted its deadline: took %v", elapsed)
+			}
+			if !strings.Contains(err.Error(), "webhook=1") || strings.Contains(err.Error(), "quick") {
+				t.Errorf("error does not list exactly the pending work: %v", err)
+			}
+		})
+	}
+}
+
+func TestBackgroundPanicDoesNotBlockWait(t *testing.T) {
+	b := NewBackground(slog.New(slog.DiscardHandler))
+	b.Go("exploding", func() { panic("boom") })
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	if err := b.Wait(ctx); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
@@ -0,0 +1,184 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// ErrCircuitOpen is returned by CircuitBreaker.Do while the circuit is open
+var ErrCircuitOpen = errors.New("circuit breaker is open")
+
+// BreakerState is the state of a CircuitBreaker
+type BreakerState int
+
+// Circuit breaker states
+const (
+	// BreakerClosed lets all calls through
+	BreakerClosed BreakerState = iota
+	// BreakerOpen fails all calls fast until the cooldown has passed
+	BreakerOpen
+	// BreakerHalfOpen lets a single trial call through to test recovery
+	BreakerHalfOpen
+)
+
+// String returns the name of the state
+func (s BreakerState) String() string {
+	switch s {
+	case BreakerClosed:
+		return "closed"
+	case BreakerOpen:
+		return "open"
+	case BreakerHalfOpen:
+		return "half-open"
+	default:
+		return fmt.Sprintf("BreakerState(%d)", int(s))
+	}
+}
+
+// CircuitBreaker stops calling a failing dependency for a while.
+// After threshold consecutive failures it opens, and calls fail fast with
+// ErrCircuitOpen. Once the cooldown has passed it is half-open: the next call
+// is let through as a trial, closing the circuit again if it succeeds and
+// reopening it if it fails.
+type CircuitBreaker struct {
+	openedAt time.Time
+	now      func() time.Time
+	cooldown time.Duration
+	mu       sync.Mutex
+	// failures counts consecutive failures while closed
+	failures  int
+	threshold int
+	state     BreakerState
+	// probing is set while the half-open trial call is in flight
+	probing bool
+}
+
+// NewCircuitBreaker creates a closed CircuitBreaker that opens after threshold
+// consecutive failures and stays open for cooldown
+func NewCircuitBreaker(threshold int, cooldown time.Duration) *CircuitBreaker {
+	return &CircuitBreaker{
+		now:       time.Now,
+		cooldown:  cooldown,
+		threshold: threshold,
+	}
+}
+
+// WithCircuitBreaker routes database queries through cb; nil disables it
+func WithCircuitBreaker(cb *CircuitBreaker) Option {
+	return func(a *API) {
+		a.breaker = cb
+	}
+}
+
+// State returns the current state of the circuit
+func (cb *CircuitBreaker) State() BreakerState {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	return cb.currentState()
+}
+
+// currentState returns the state of the circuit, which turns half-open once
+// the cooldown has passed. cb.mu must be held.
+func (cb *CircuitBreaker) currentState() BreakerState {
+	if cb.state == BreakerOpen && cb.now().Sub(cb.openedAt) >= cb.cooldown {
+		cb.state = BreakerHalfOpen
+	}
+	return cb.state
+}
+
+// Do calls fn unless the circuit is open, and records whether it failed.
+// Failures caused by ctx being done say nothing about the dependency, so they
+// aren't counted.
+func (cb *CircuitBreaker) Do(ctx context.Context, fn func(context.Context) error) error {
+	if err := cb.acquire(); err != nil {
+		return err
+	}
+
+	err := fn(ctx)
+	if err != nil && ctx.Err() != nil {
+		cb.release()
+		return err
+	}
+
+	cb.record(ctx, err)
+	return err
+}
+
+// acquire reports whether a call may go ahead, claiming the trial call when
+// the circuit is half-open
+func (cb *CircuitBreaker) acquire() error {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	switch cb.currentState() {
+	case BreakerOpen:
+		return ErrCircuitOpen
+	case BreakerHalfOpen:
+		if cb.probing {
+			return ErrCircuitOpen
+		}
+		cb.probing = true
+	}
+	return nil
+}
+
+// release gives up the trial call without recording an outcome
+func (cb *CircuitBreaker) release() {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	cb.probing = false
+}
+
+// record updates the circuit with the outcome of a call
+func (cb *CircuitBreaker) record(ctx context.Context, err error) {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	wasProbing := cb.probing
+	cb.probing = false
+
+	if err == nil {
+		if wasProbing {
+			LoggerFromContext(ctx).Info("Circuit breaker closed")
+		}
+		cb.state = BreakerClosed
+		cb.failures = 0
+		return
+	}
+
+	cb.failures++
+	if wasProbing || cb.failures >= cb.threshold {
+		LoggerFromContext(ctx).Warn("Circuit breaker opened",
+			"consecutive_failures", cb.failures,
+			"cooldown", cb.cooldown,
+			"error", err)
+		cb.state = BreakerOpen
+		cb.openedAt = cb.now()
+		cb.failures = 0
+	}
+}
+
+// retryAfter returns how long until the circuit turns half-open
+func (cb *CircuitBreaker) retryAfter() time.Duration {
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	if cb.currentState() != BreakerOpen {
+		return 0
+	}
+	return cb.cooldown - cb.now().Sub(cb.openedAt)
+}
+
+// Check fails while the circuit is open, so the readiness endpoint reports the
+// dependency as unavailable
+func (cb *CircuitBreaker) Check(_ context.Context) error {
+	if state := cb.State(); state == BreakerOpen {
+		return fmt.Errorf("%w: retrying in %v", ErrCircuitOpen, cb.retryAfter().Round(time.Second))
+	}
+	return nil
+}
@@ -0,0 +1,164 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newTestBreaker returns a CircuitBreaker on a fake clock, and a function to advance it
+func newTestBreaker(threshold int, cooldown time.Duration) (*CircuitBreaker, func(time.Duration)) {
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	cb := NewCircuitBreaker(threshold, cooldown)
+	cb.now = func() time.Time { return now }
+	return cb, func(d time.Duration) { now = now.Add(d) }
+}
+
+func TestCircuitBreakerTransitions(t *testing.T) {
+	cb, advance := newTestBreaker(2, time.Minute)
+	ctx := context.Background()
+
+	errDB := errors.New("connection timeout")
+	calls := 0
+	fail := func(context.Context) error { calls++; return errDB }
+	succeed := func(context.Context) error { calls++; return nil }
+
+	expectState := func(want BreakerState) {
+		t.Helper()
+		if got := cb.State(); got != want {
+			t.Fatalf("wrong circuit state: got %v want %v", got, want)
+		}
+	}
+
+	// Failures below the threshold keep the circuit closed
+	if err := cb.Do(ctx, fail); !errors.Is(err, errDB) {
+		t.Fatalf("wrong error: got %v want %v", err, errDB)
+	}
+	expectState(BreakerClosed)
+
+	// Reaching the threshold opens it
+	_ = cb.Do(ctx, fail)
+	expectState(BreakerOpen)
+
+	// While open, calls fail fast without reaching the dependency
+	calls = 0
+	if err := cb.Do(ctx, succeed); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("wrong error while open: got %v want %v", err, ErrCircuitOpen)
+	}
+	if calls != 0 {
+		t.Errorf("dependency called while the circuit was open: %d calls", calls)
+	}
+
+	// After the cooldown it is half-open, and a failed trial reopens it
+	advance(time.Minute)
+	expectState(BreakerHalfOpen)
+	_ = cb.Do(ctx, fail)
+	expectState(BreakerOpen)
+
+	// A successful trial closes it again
+	advance(time.Minute)
+	expectState(BreakerHalfOpen)
+	if err := cb.Do(ctx, succeed); err != nil {
+		t.Fatalf("trial call failed: %v", err)
+	}
+	expectState(BreakerClosed)
+}
+
+func TestCircuitBreakerAllowsOneTrialWhileHalfOpen(t *testing.T) {
+	cb, advance := newTestBreaker(1, time.Minute)
+	ctx := context.Background()
+
+	_ = cb.Do(ctx, func(context.Context) error { return errors.New("connection timeout") })
+	advance(time.Minute)
+
+	// Another call arriving during the trial is short-circuited
+	err := cb.Do(ctx, func(context.Context) error {
+		return cb.Do(ctx, func(context.Context) error { return nil })
+	})
+	if !errors.Is(err, ErrCircuitOpen) {
+		t.Errorf("wrong error for concurrent call: got %v want %v", err, ErrCircuitOpen)
+	}
+}
+
+func TestCircuitBreakerIgnoresCanceledCalls(t *testing.T) {
+	cb, _ := newTestBreaker(1, time.Minute)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_ = cb.Do(ctx, func(ctx context.Context) error { return ctx.Err() })
+
+	if got := cb.State(); got != BreakerClosed {
+		t.Errorf("wrong circuit state: got %v want %v", got, BreakerClosed)
+	}
+}
+
+func TestGetUserWithOpenCircuit(t *testing.T) {
+	cb, _ := newTestBreaker(1, 30*time.Second)
+	api, _ := newTestAPI(t, []Option{
+		WithFaultInjector(stubFaults{OpQueryTimeout: true}),
+		WithCircuitBreaker(cb),
+	})
+	api.Readiness().RegisterNonCritical("database", cb)
+	handler := api.Routes()
+
+	testCases := []struct {
+		name           string
+		expectedStatus int
+	}{
+		{name: "Failure Opens Circuit", expectedStatus: http.StatusInternalServerError},
+		{name: "Open Circuit", expectedStatus: http.StatusServiceUnavailable},
+	}
+
+	for _, tc := range testCases {
+		req := httptest.NewRequest("GET", "/api/users/5", nil)
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+
+		if status := rr.Code; status != tc.expectedStatus {
+			t.Errorf("%s: handler returned wrong status code: got %v want %v", tc.name, status, tc.expectedStatus)
+		}
+		if tc.expectedStatus == http.StatusServiceUnavailable && rr.Header().Get("Retry-After") != "30" {
+			t.Errorf("%s: handler returned wrong Retry-After: got %q want %q", tc.name, rr.Header().Get("Retry-After"), "30")
+		}
+	}
+
+	if err := cb.Check(context.Background()); !errors.Is(err, ErrCircuitOpen) {
+		t.Errorf("readiness check returned wrong error: got %v want %v", err, ErrCircuitOpen)
+	}
+
+	req := httptest.NewRequest("GET", "/api/health/ready", nil)
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	if !strings.Contains(rr.Body.String(), statusDegraded) {
+		t.Errorf("readiness endpoint did not report degraded: %s", rr.Body.String())
+	}
+}
+
+func TestCircuitBreakerCountsRetriedRequestOnce(t *testing.T) {
+	cb, _ := newTestBreaker(2, time.Minute)
+	api, _ := newTestAPI(t, []Option{
+		WithFaultInjector(stubFaults{OpQueryTimeout: true}),
+		WithRetries(3, NewRetryBudget(100, 100)),
+		WithRetryBackoff(0),
+		WithCircuitBreaker(cb),
+	})
+	handler := api.Routes()
+
+	// Each request makes four failed attempts, but only counts as one failure
+	for i, want := range []BreakerState{BreakerClosed, BreakerOpen} {
+		req := httptest.NewRequest("GET", "/api/users/5", nil)
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+
+		if status := rr.Code; status != http.StatusInternalServerError {
+			t.Errorf("request %d: handler returned wrong status code: got %v want %v", i, status, http.StatusInternalServerError)
+		}
+		if got := cb.State(); got != want {
+			t.Errorf("request %d: wrong circuit state: got %v want %v", i, got, want)
+		}
+	}
+}
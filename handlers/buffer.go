@@ -0,0 +1,91 @@
+package handlers
+
+import (
+	"bytes"
+	"net/http"
+	"strconv"
+	"sync"
+)
+
+// maxPooledBufferSize is the largest buffer returned to bufferPool. Buffers
+// that grew beyond it (for example while encoding a large user list) are left
+// to the garbage collector rather than pinning their memory in the pool.
+const maxPooledBufferSize = 64 << 10
+
+// bufferPool holds reusable buffers for encoding response bodies
+var bufferPool = sync.Pool{
+	New: func() any { return new(bytes.Buffer) },
+}
+
+// getBuffer returns an empty buffer from bufferPool
+func getBuffer() *bytes.Buffer {
+	buf := bufferPool.Get().(*bytes.Buffer)
+	buf.Reset()
+	return buf
+}
+
+// putBuffer returns buf to bufferPool. The caller must not use buf, or any
+// slice obtained from it, afterwards.
+func putBuffer(buf *bytes.Buffer) {
+	if buf.Cap() > maxPooledBufferSize {
+		return
+	}
+	buf.Reset()
+	bufferPool.Put(buf)
+}
+
+// bufferedWriter is an http.ResponseWriter that holds back the status code and
+// body until commit is called. Headers stay mutable in the meantime, so a
+// handler can set headers (such as an ETag) derived from the finished body.
+type bufferedWriter struct {
+	w      http.ResponseWriter
+	body   bytes.Buffer
+	status int
+}
+
+// newBufferedWriter creates a bufferedWriter that eventually writes to w
+func newBufferedWriter(w http.ResponseWriter) *bufferedWriter {
+	return &bufferedWriter{w: w}
+}
+
+// Header returns the header map of the underlying writer
+func (bw *bufferedWriter) Header() http.Header {
+	return bw.w.Header()
+}
+
+// WriteHeader records the status code; only the first call takes effect
+func (bw *bufferedWriter) WriteHeader(statusCode int) {
+	if bw.status == 0 {
+		bw.status = statusCode
+	}
+}
+
+// Write appends to the buffered body, implying a 200 status like net/http does
+func (bw *bufferedWriter) Write(b []byte) (int, error) {
+	if bw.status == 0 {
+		bw.status = http.StatusOK
+	}
+	return bw.body.Write(b)
+}
+
+// Body returns the buffered body
+func (bw *bufferedWriter) Body() []byte {
+	return bw.body.Bytes()
+}
+
+// Status returns the buffered status code, or 200 if none has been set
+func (bw *bufferedWriter) Status() int {
+	if bw.status == 0 {
+		return http.StatusOK
+	}
+	return bw.status
+}
+
+// commit sends the status code, headers and buffered body to the underlying writer
+func (bw *bufferedWriter) commit() error {
+	bw.w.Header().Set("Content-Length", strconv.Itoa(bw.body.Len()))
+	bw.w.WriteHeader(bw.Status())
+
+	_, err := bw.w.Write(bw.body.Bytes())
+	return err
+}
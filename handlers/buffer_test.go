@@ -0,0 +1,174 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/kakkoyun/demo-web-service/models"
+)
+
+func TestBufferedWriterDefersHeaders(t *testing.T) {
+	rr := httptest.NewRecorder()
+	bw := newBufferedWriter(rr)
+
+	// Build the body first, as a handler computing an ETag would
+	bw.Header().Set("Content-Type", "text/plain")
+	bw.WriteHeader(http.StatusAccepted)
+	if _, err := bw.Write([]byte("hello")); err != nil {
+		t.Fatal(err)
+	}
+
+	// Nothing has reached the client yet
+	if rr.Body.Len() != 0 {
+		t.Fatalf("body was written before commit: %q", rr.Body.String())
+	}
+
+	// A header derived from the body is set after writing it
+	bw.Header().Set("X-Body-Length", "5")
+
+	if err := bw.commit(); err != nil {
+		t.Fatal(err)
+	}
+
+	if rr.Code != http.StatusAccepted {
+		t.Errorf("wrong status code: got %v want %v", rr.Code, http.StatusAccepted)
+	}
+	if got := rr.Header().Get("X-Body-Length"); got != "5" {
+		t.Errorf("late header missing: got %q want %q", got, "5")
+	}
+	if got := rr.Header().Get("Content-Length"); got != "5" {
+		t.Errorf("wrong content length: got %q want %q", got, "5")
+	}
+	if rr.Body.String() != "hello" {
+		t.Errorf("wrong body: got %q want %q", rr.Body.String(), "hello")
+	}
+}
+
+func TestBufferedWriterImplicitStatus(t *testing.T) {
+	rr := httptest.NewRecorder()
+	bw := newBufferedWriter(rr)
+
+	// Writing implies a 200 status, so a later WriteHeader is ignored
+	if _, err := bw.Write([]byte("{}")); err != nil {
+		t.Fatal(err)
+	}
+	bw.WriteHeader(http.StatusTeapot)
+
+	if err := bw.commit(); err != nil {
+		t.Fatal(err)
+	}
+
+	if rr.Code != http.StatusOK {
+		t.Errorf("wrong status code: got %v want %v", rr.Code, http.StatusOK)
+	}
+}
+
+func TestGetBufferIsEmpty(t *testing.T) {
+	buf := getBuffer()
+	buf.WriteString("left over from a previous response")
+	putBuffer(buf)
+
+	// Whichever buffer the pool hands out next, it must not carry old data
+	for range 10 {
+		if buf := getBuffer(); buf.Len() != 0 {
+			t.Fatalf("pooled buffer was not reset: %q", buf.String())
+		}
+	}
+}
+
+func TestPutBufferDropsOversizedBuffers(t *testing.T) {
+	buf := getBuffer()
+	buf.Grow(maxPooledBufferSize + 1)
+	putBuffer(buf)
+
+	// An oversized buffer is left untouched, not reset and pooled
+	buf.WriteString("still usable")
+	if buf.String() != "still usable" {
+		t.Errorf("oversized buffer was modified: %q", buf.String())
+	}
+}
+
+func TestConcurrentJSONResponsesDontBleed(t *testing.T) {
+	const workers, iterations = 16, 200
+
+	var wg sync.WaitGroup
+	errs := make(chan error, workers)
+	for i := range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			// Vary the body size so that shorter responses would pick up the
+			// tail of longer ones if buffers weren't reset
+			want := models.User{ID: i, Name: "User " + strings.Repeat("x", i*50)}
+			for range iterations {
+				rr := httptest.NewRecorder()
+				jsonResponse(rr, http.StatusOK, want)
+
+				var got models.User
+				if err := json.Unmarshal(rr.Body.Bytes(), &got); err != nil {
+					errs <- fmt.Errorf("worker %d: invalid body %q: %w", i, rr.Body.String(), err)
+					return
+				}
+				if got.ID != want.ID || got.Name != want.Name {
+					errs <- fmt.Errorf("worker %d: got user %d %q", i, got.ID, got.Name)
+					return
+				}
+				if cl := rr.Header().Get("Content-Length"); cl != fmt.Sprint(rr.Body.Len()) {
+					errs <- fmt.Errorf("worker %d: Content-Length %s for a %d byte body", i, cl, rr.Body.Len())
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		t.Error(err)
+	}
+}
+
+// discardWriter is a minimal http.ResponseWriter that drops the body, so that
+// benchmarks measure encoding rather than the recorder
+type discardWriter struct {
+	header http.Header
+}
+
+func (d *discardWriter) Header() http.Header         { return d.header }
+func (d *discardWriter) Write(b []byte) (int, error) { return len(b), nil }
+func (d *discardWriter) WriteHeader(int)             {}
+
+func BenchmarkJSONResponse(b *testing.B) {
+	users := make([]models.User, 50)
+	for i := range users {
+		users[i] = models.User{ID: i + 1, Name: fmt.Sprintf("User %d", i+1)}
+	}
+	data := models.UserListResponse{Users: users, Count: len(users)}
+
+	b.Run("pooled", func(b *testing.B) {
+		w := &discardWriter{header: make(http.Header)}
+		b.ReportAllocs()
+		for b.Loop() {
+			jsonResponse(w, http.StatusOK, data)
+		}
+	})
+
+	// unpooled mirrors encodeJSON before buffers were pooled, for comparison
+	b.Run("unpooled", func(b *testing.B) {
+		w := &discardWriter{header: make(http.Header)}
+		b.ReportAllocs()
+		for b.Loop() {
+			var buf bytes.Buffer
+			if err := json.NewEncoder(&buf).Encode(data); err != nil {
+				b.Fatal(err)
+			}
+			writeBody(w, http.StatusOK, "application/json", &buf)
+		}
+	})
+}
@@ -0,0 +1,233 @@
+package handlers
+
+import (
+	"bytes"
+	"compress/gzip"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+const (
+	// maxAcceptEncodingLength is the longest Accept-Encoding header that is parsed.
+	// Legitimate clients send a handful of codings, anything longer is treated as garbage.
+	maxAcceptEncodingLength = 512
+	// maxAcceptEncodingEntries is the maximum number of codings examined
+	maxAcceptEncodingEntries = 16
+)
+
+// CompressionMiddleware creates a middleware that gzips response bodies for
+// clients that accept it. Bodies smaller than minSize bytes are sent
+// uncompressed, since gzip would only add overhead to them.
+func CompressionMiddleware(minSize int) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			// Caches must keep compressed and uncompressed variants apart
+			w.Header().Add("Vary", "Accept-Encoding")
+
+			if !acceptsGzip(r.Header.Get("Accept-Encoding")) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			gw := &gzipResponseWriter{ResponseWriter: w, minSize: minSize}
+			defer func() {
+				if err := gw.close(); err != nil {
+					slog.Debug("Failed to write compressed response", "error", err)
+				}
+				if gw.gz != nil {
+					recordUncompressedSize(r.Context(), gw.written)
+				}
+			}()
+
+			next.ServeHTTP(gw, r)
+		})
+	}
+}
+
+// gzipResponseWriter is a wrapper for http.ResponseWriter that holds back the
+// status code and the start of the body until it knows whether the body reaches
+// the compression threshold. The status code is passed on to the wrapped writer,
+// so wrappers further out such as LoggingMiddleware's responseWriter still see it.
+type gzipResponseWriter struct {
+	http.ResponseWriter
+	gz      *gzip.Writer
+	buf     bytes.Buffer
+	minSize int
+	status  int
+	written int64
+	passed  bool
+}
+
+// WriteHeader records the status code; only the first call takes effect
+func (gw *gzipResponseWriter) WriteHeader(statusCode int) {
+	if gw.status == 0 {
+		gw.status = statusCode
+	}
+}
+
+// Write buffers the body until the threshold is reached, then compresses it
+func (gw *gzipResponseWriter) Write(b []byte) (int, error) {
+	if gw.status == 0 {
+		gw.status = http.StatusOK
+	}
+	gw.written += int64(len(b))
+
+	switch {
+	case gw.gz != nil:
+		return gw.gz.Write(b)
+	case gw.passed:
+		return gw.ResponseWriter.Write(b)
+	}
+
+	gw.buf.Write(b)
+	if gw.buf.Len() < gw.minSize {
+		return len(b), nil
+	}
+
+	if err := gw.start(); err != nil {
+		return 0, err
+	}
+	return len(b), nil
+}
+
+// start sends the headers and the buffered body, compressing it when the
+// response isn't already encoded
+func (gw *gzipResponseWriter) start() error {
+	h := gw.Header()
+	if h.Get("Content-Encoding") != "" {
+		gw.passed = true
+		gw.ResponseWriter.WriteHeader(gw.status)
+		_, err := gw.ResponseWriter.Write(gw.buf.Bytes())
+		return err
+	}
+
+	// The length of the compressed body isn't known up front
+	h.Del("Content-Length")
+	h.Set("Content-Encoding", "gzip")
+	gw.ResponseWriter.WriteHeader(gw.status)
+
+	gw.gz = gzip.NewWriter(gw.ResponseWriter)
+	_, err := gw.gz.Write(gw.buf.Bytes())
+	return err
+}
+
+// close flushes the compressed body, or sends a body below the threshold as is
+func (gw *gzipResponseWriter) close() error {
+	switch {
+	case gw.gz != nil:
+		return gw.gz.Close()
+	case gw.passed, gw.status == 0:
+		return nil
+	}
+
+	gw.ResponseWriter.WriteHeader(gw.status)
+	_, err := gw.ResponseWriter.Write(gw.buf.Bytes())
+	return err
+}
+
+// Flush sends what has been written so far to the client. A response that is
+// flushed before it reaches the compression threshold, such as an event
+// stream, is sent uncompressed.
+func (gw *gzipResponseWriter) Flush() {
+	switch {
+	case gw.gz != nil:
+		if err := gw.gz.Flush(); err != nil {
+			slog.Debug("Failed to flush compressed response", "error", err)
+			return
+		}
+	case !gw.passed:
+		if gw.status == 0 {
+			gw.status = http.StatusOK
+		}
+		gw.passed = true
+		gw.ResponseWriter.WriteHeader(gw.status)
+		if _, err := gw.ResponseWriter.Write(gw.buf.Bytes()); err != nil {
+			slog.Debug("Failed to write response", "error", err)
+			return
+		}
+	}
+
+	if f, ok := gw.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Unwrap returns the underlying writer, for use by http.ResponseController
+func (gw *gzipResponseWriter) Unwrap() http.ResponseWriter {
+	return gw.ResponseWriter
+}
+
+// acceptsGzip reports whether an Accept-Encoding header value allows a gzip response.
+// Parsing is bounded in both length and number of entries, entries with malformed
+// quality values are ignored, and garbage input falls back to no compression.
+func acceptsGzip(header string) bool {
+	if header == "" || len(header) > maxAcceptEncodingLength {
+		return false
+	}
+
+	gzipQ, wildcardQ := -1.0, -1.0
+	rest := header
+	for i := 0; i < maxAcceptEncodingEntries && rest != ""; i++ {
+		var entry string
+		entry, rest, _ = strings.Cut(rest, ",")
+
+		coding, q, ok := parseEncodingEntry(entry)
+		if !ok {
+			continue
+		}
+
+		switch coding {
+		case "gzip", "x-gzip":
+			gzipQ = q
+		case "*":
+			wildcardQ = q
+		}
+	}
+
+	// An explicit gzip entry takes precedence over the wildcard
+	if gzipQ >= 0 {
+		return gzipQ > 0
+	}
+	return wildcardQ > 0
+}
+
+// parseEncodingEntry parses a single Accept-Encoding entry such as "gzip;q=0.8".
+// It returns the lowercased coding and its quality value, or false if the entry is malformed.
+func parseEncodingEntry(entry string) (string, float64, bool) {
+	coding, params, _ := strings.Cut(entry, ";")
+	coding = strings.ToLower(strings.TrimSpace(coding))
+	if coding == "" || !isToken(coding) {
+		return "", 0, false
+	}
+
+	q := 1.0
+	for params != "" {
+		var param string
+		param, params, _ = strings.Cut(params, ";")
+
+		name, value, found := strings.Cut(strings.TrimSpace(param), "=")
+		if !found || !strings.EqualFold(strings.TrimSpace(name), "q") {
+			continue
+		}
+
+		parsed, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		if err != nil || parsed < 0 || parsed > 1 {
+			return "", 0, false
+		}
+		q = parsed
+	}
+
+	return coding, q, true
+}
+
+// isToken reports whether s only contains characters allowed in an HTTP token
+func isToken(s string) bool {
+	for _, c := range s {
+		if c > 0x7e || c <= ' ' || strings.ContainsRune(`"(),/:;<=>?@[\]{}`, c) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,148 @@
+package handlers
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestAcceptsGzip(t *testing.T) {
+	testCases := []struct {
+		name     string
+		header   string
+		expected bool
+	}{
+		{name: "Empty", header: "", expected: false},
+		{name: "Gzip", header: "gzip", expected: true},
+		{name: "Gzip Among Others", header: "deflate, gzip, br", expected: true},
+		{name: "Uppercase", header: "GZIP", expected: true},
+		{name: "Legacy Name", header: "x-gzip", expected: true},
+		{name: "Quality Value", header: "gzip;q=0.5", expected: true},
+		{name: "Disabled By Quality", header: "gzip;q=0", expected: false},
+		{name: "Wildcard", header: "*", expected: true},
+		{name: "Wildcard With Gzip Disabled", header: "*, gzip;q=0", expected: false},
+		{name: "Identity Only", header: "identity", expected: false},
+		{name: "Malformed Quality Is Ignored", header: "gzip;q=abc", expected: false},
+		{name: "Out Of Range Quality Is Ignored", header: "gzip;q=5", expected: false},
+		{name: "Malformed Entry Does Not Hide Valid One", header: "gz\"ip;q=1, gzip", expected: true},
+		{name: "Garbage", header: ";;;,,,===", expected: false},
+		{name: "Binary Garbage", header: "\x00\x01\x02gzip", expected: false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := acceptsGzip(tc.header); got != tc.expected {
+				t.Errorf("acceptsGzip(%q) = %v, want %v", tc.header, got, tc.expected)
+			}
+		})
+	}
+}
+
+func TestAcceptsGzipBoundsParsing(t *testing.T) {
+	// A huge header falls back to no compression even if gzip is present
+	huge := strings.Repeat("deflate;q=0.1, ", 10000) + "gzip"
+	if acceptsGzip(huge) {
+		t.Error("oversized Accept-Encoding header enabled compression")
+	}
+
+	// Only the first entries are examined
+	var entries []string
+	for i := 0; i < maxAcceptEncodingEntries; i++ {
+		entries = append(entries, "a")
+	}
+	entries = append(entries, "gzip")
+	if acceptsGzip(strings.Join(entries, ",")) {
+		t.Error("gzip beyond the entry limit enabled compression")
+	}
+}
+
+func TestCompressionMiddleware(t *testing.T) {
+	large := strings.Repeat(`{"name":"John Doe"}`, 100)
+	small := `{"name":"John Doe"}`
+
+	testCases := []struct {
+		name           string
+		acceptEncoding string
+		body           string
+		expectGzip     bool
+	}{
+		{name: "Gzip Client", acceptEncoding: "gzip", body: large, expectGzip: true},
+		{name: "Plain Client", acceptEncoding: "", body: large, expectGzip: false},
+		{name: "Gzip Refused", acceptEncoding: "gzip;q=0", body: large, expectGzip: false},
+		{name: "Below Threshold", acceptEncoding: "gzip", body: small, expectGzip: false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			handler := CompressionMiddleware(256)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				jsonResponse(w, http.StatusCreated, map[string]string{"data": tc.body})
+			}))
+
+			req := httptest.NewRequest("GET", "/api/users", nil)
+			if tc.acceptEncoding != "" {
+				req.Header.Set("Accept-Encoding", tc.acceptEncoding)
+			}
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			if status := rr.Code; status != http.StatusCreated {
+				t.Errorf("handler returned wrong status code: got %v want %v", status, http.StatusCreated)
+			}
+			if vary := rr.Header().Get("Vary"); vary != "Accept-Encoding" {
+				t.Errorf("wrong Vary header: got %q want %q", vary, "Accept-Encoding")
+			}
+
+			var body io.Reader = rr.Body
+			if tc.expectGzip {
+				if enc := rr.Header().Get("Content-Encoding"); enc != "gzip" {
+					t.Fatalf("wrong Content-Encoding: got %q want %q", enc, "gzip")
+				}
+				if cl := rr.Header().Get("Content-Length"); cl != "" {
+					t.Errorf("compressed response has stale Content-Length %v", cl)
+				}
+
+				gz, err := gzip.NewReader(rr.Body)
+				if err != nil {
+					t.Fatalf("could not read gzip body: %v", err)
+				}
+				defer gz.Close()
+				body = gz
+			} else if enc := rr.Header().Get("Content-Encoding"); enc != "" {
+				t.Errorf("uncompressed response has Content-Encoding %q", enc)
+			}
+
+			var got map[string]string
+			if err := json.NewDecoder(body).Decode(&got); err != nil {
+				t.Fatalf("could not parse response body: %v", err)
+			}
+			if got["data"] != tc.body {
+				t.Errorf("body did not round-trip: got %q want %q", got["data"], tc.body)
+			}
+		})
+	}
+}
+
+func TestCompressionMiddlewareKeepsStatusForLogging(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		errorResponse(w, r, http.StatusNotFound, strings.Repeat("missing ", 100))
+	})
+
+	// Wrap the compressed handler like LoggingMiddleware does
+	compressed := CompressionMiddleware(0)(handler)
+	req := httptest.NewRequest("GET", "/api/users/42", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	rr := httptest.NewRecorder()
+	rw := newResponseWriter(rr)
+	compressed.ServeHTTP(rw, req)
+
+	if rw.statusCode != http.StatusNotFound {
+		t.Errorf("wrapper captured wrong status code: got %v want %v", rw.statusCode, http.StatusNotFound)
+	}
+	if enc := rr.Header().Get("Content-Encoding"); enc != "gzip" {
+		t.Errorf("wrong Content-Encoding: got %q want %q", enc, "gzip")
+	}
+}
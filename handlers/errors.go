@@ -0,0 +1,103 @@
+package handlers
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/kakkoyun/demo-web-service/models"
+	"github.com/kakkoyun/demo-web-service/store"
+)
+
+// Machine-readable error codes for errors with a known cause.
+// Other errors get a code derived from their HTTP status, e.g. NOT_FOUND.
+const (
+	CodeUserNotFound    = "USER_NOT_FOUND"
+	CodeInvalidUserID   = "INVALID_USER_ID"
+	CodeValidation      = "VALIDATION_ERROR"
+	CodeEmptyBody       = "EMPTY_BODY"
+	CodeBodyTooLarge    = "BODY_TOO_LARGE"
+	CodeDuplicateID     = "DUPLICATE_ID"
+	CodePatchTestFailed = "PATCH_TEST_FAILED"
+	CodeInvalidCursor   = "INVALID_CURSOR"
+	CodeInvalidLimit    = "INVALID_LIMIT"
+)
+
+// errorCodes maps sentinel errors to their codes.
+// More specific errors come first, since an error may wrap several sentinels.
+var errorCodes = []struct {
+	err  error
+	code string
+}{
+	{err: ErrUserNotFound, code: CodeUserNotFound},
+	{err: ErrInvalidUserID, code: CodeInvalidUserID},
+	{err: ErrEmptyBody, code: CodeEmptyBody},
+	{err: ErrBodyTooLarge, code: CodeBodyTooLarge},
+	{err: store.ErrDuplicateID, code: CodeDuplicateID},
+	{err: ErrPatchTestFailed, code: CodePatchTestFailed},
+	{err: ErrInvalidCursor, code: CodeInvalidCursor},
+	{err: ErrInvalidLimit, code: CodeInvalidLimit},
+	{err: ErrValidation, code: CodeValidation},
+}
+
+// errorCode returns the code for err, falling back to one derived from status
+func errorCode(err error, status int) string {
+	for _, ec := range errorCodes {
+		if errors.Is(err, ec.err) {
+			return ec.code
+		}
+	}
+	return statusCode(status)
+}
+
+// statusCode derives an error code from an HTTP status, e.g. TOO_MANY_REQUESTS for 429
+func statusCode(status int) string {
+	text := http.StatusText(status)
+	if text == "" {
+		return "ERROR"
+	}
+	return strings.ToUpper(strings.NewReplacer(" ", "_", "-", "_", "'", "").Replace(text))
+}
+
+// errorResponse sends an error response in the format the client asked for,
+// with a code derived from the status
+func errorResponse(w http.ResponseWriter, r *http.Request, status int, message string) {
+	sendError(w, r, status, statusCode(status), message)
+}
+
+// errorResponseFor sends an error response whose code identifies the cause err,
+// detailing the invalid fields if err is an InvalidFieldsError
+func errorResponseFor(w http.ResponseWriter, r *http.Request, status int, err error, message string) {
+	sendError(w, r, status, errorCode(err, status), message, fieldErrors(err)...)
+}
+
+// fieldErrors returns the invalid fields listed by err, if it is an InvalidFieldsError
+func fieldErrors(err error) []models.FieldError {
+	var invalid *InvalidFieldsError
+	if errors.As(err, &invalid) {
+		return invalid.Fields
+	}
+	return nil
+}
+
+// sendError sends an error response with the given code and optional details
+func sendError(w http.ResponseWriter, r *http.Request, status int, code, message string, details ...models.FieldError) {
+	slog.Warn("Sending error response", "status", status, "code", code, "message", message)
+
+	// Server errors fail the request's trace span, if there is one
+	if status >= http.StatusInternalServerError {
+		trace.SpanFromContext(r.Context()).RecordError(errors.New(message))
+	}
+
+	response := models.ErrorResponse{
+		Status:  "error",
+		Code:    code,
+		Message: message,
+		Details: details,
+	}
+
+	respond(w, r, status, response)
+}
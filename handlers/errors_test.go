@@ -0,0 +1,94 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/kakkoyun/demo-web-service/models"
+)
+
+func TestErrorResponseCodes(t *testing.T) {
+	testCases := []struct {
+		name           string
+		method         string
+		path           string
+		body           string
+		expectedCode   string
+		expectedStatus int
+	}{
+		{name: "Invalid ID", method: "GET", path: "/api/users/abc", expectedStatus: http.StatusBadRequest, expectedCode: CodeInvalidUserID},
+		{name: "Non-Positive ID", method: "GET", path: "/api/users/0", expectedStatus: http.StatusBadRequest, expectedCode: CodeInvalidUserID},
+		{name: "Not Found", method: "GET", path: "/api/users/42", expectedStatus: http.StatusNotFound, expectedCode: CodeUserNotFound},
+		{name: "Validation", method: "POST", path: "/api/users", body: `{"name":""}`, expectedStatus: http.StatusUnprocessableEntity, expectedCode: CodeValidation},
+		{name: "Empty Body", method: "POST", path: "/api/users", body: "", expectedStatus: http.StatusBadRequest, expectedCode: CodeEmptyBody},
+		{name: "Duplicate ID", method: "POST", path: "/api/users/import", body: `[{"id":1,"name":"John Doe"}]`, expectedStatus: http.StatusConflict, expectedCode: CodeDuplicateID},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			api, _ := newTestAPI(t, nil)
+
+			req := httptest.NewRequest(tc.method, tc.path, strings.NewReader(tc.body))
+			rr := httptest.NewRecorder()
+			api.Routes().ServeHTTP(rr, req)
+
+			if status := rr.Code; status != tc.expectedStatus {
+				t.Fatalf("handler returned wrong status code: got %v want %v", status, tc.expectedStatus)
+			}
+
+			var response models.ErrorResponse
+			if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+				t.Fatalf("could not parse response body: %v", err)
+			}
+			if response.Status != "error" {
+				t.Errorf("handler returned wrong status: got %v want %v", response.Status, "error")
+			}
+			if response.Code != tc.expectedCode {
+				t.Errorf("handler returned wrong code: got %v want %v", response.Code, tc.expectedCode)
+			}
+		})
+	}
+}
+
+func TestStatusCode(t *testing.T) {
+	testCases := []struct {
+		status   int
+		expected string
+	}{
+		{status: http.StatusNotFound, expected: "NOT_FOUND"},
+		{status: http.StatusTooManyRequests, expected: "TOO_MANY_REQUESTS"},
+		{status: http.StatusServiceUnavailable, expected: "SERVICE_UNAVAILABLE"},
+		{status: http.StatusRequestEntityTooLarge, expected: "REQUEST_ENTITY_TOO_LARGE"},
+		{status: http.StatusTeapot, expected: "IM_A_TEAPOT"},
+		{status: 599, expected: "ERROR"},
+	}
+
+	for _, tc := range testCases {
+		if got := statusCode(tc.status); got != tc.expected {
+			t.Errorf("statusCode(%d) = %q, want %q", tc.status, got, tc.expected)
+		}
+	}
+}
+
+func TestErrorResponseKeyOrder(t *testing.T) {
+	rr := httptest.NewRecorder()
+	errorResponse(rr, httptest.NewRequest("GET", "/missing", nil), http.StatusNotFound, "Not found")
+
+	// Fields are serialized in struct order, so snapshots of error bodies are stable
+	expected := `{"status":"error","code":"NOT_FOUND","message":"Not found"}` + "\n"
+	if got := rr.Body.String(); got != expected {
+		t.Errorf("error response has wrong serialization: got %q want %q", got, expected)
+	}
+
+	rr = httptest.NewRecorder()
+	sendError(rr, httptest.NewRequest("GET", "/", nil), http.StatusUnprocessableEntity, CodeValidation, "Invalid user",
+		models.FieldError{Field: "name", Message: "is required"})
+
+	expected = `{"status":"error","code":"VALIDATION_ERROR","message":"Invalid user","details":[{"field":"name","message":"is required"}]}` + "\n"
+	if got := rr.Body.String(); got != expected {
+		t.Errorf("error response has wrong serialization: got %q want %q", got, expected)
+	}
+}
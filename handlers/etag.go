@@ -0,0 +1,63 @@
+package handlers
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"log/slog"
+	"net/http"
+	"strings"
+)
+
+// respondWithETag sends data like respond with a 200 status, tagged with a weak
+// ETag derived from the encoded body. When the request's If-None-Match already
+// names that ETag, it sends 304 Not Modified without a body instead.
+func respondWithETag(w http.ResponseWriter, r *http.Request, data interface{}) {
+	bw := newBufferedWriter(w)
+	respond(bw, r, http.StatusOK, data)
+
+	// Errors, such as a failure to encode the body, aren't cacheable
+	if bw.Status() != http.StatusOK {
+		if err := bw.commit(); err != nil {
+			slog.Debug("Failed to write response", "error", err)
+		}
+		return
+	}
+
+	etag := weakETag(bw.Body())
+	w.Header().Set("ETag", etag)
+
+	if etagMatches(r.Header.Get("If-None-Match"), etag) {
+		h := w.Header()
+		h.Del("Content-Type")
+		h.Del("Content-Length")
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	if err := bw.commit(); err != nil {
+		slog.Debug("Failed to write response", "error", err)
+	}
+}
+
+// weakETag returns a weak ETag for a response body.
+// It is weak because semantically equal bodies may differ in encoding details.
+func weakETag(body []byte) string {
+	sum := sha256.Sum256(body)
+	return `W/"` + hex.EncodeToString(sum[:16]) + `"`
+}
+
+// etagMatches reports whether an If-None-Match header value matches etag,
+// using the weak comparison required for If-None-Match
+func etagMatches(ifNoneMatch, etag string) bool {
+	if ifNoneMatch == "" {
+		return false
+	}
+
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == strings.TrimPrefix(etag, "W/") {
+			return true
+		}
+	}
+	return false
+}
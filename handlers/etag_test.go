@@ -0,0 +1,92 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/kakkoyun/demo-web-service/models"
+	"github.com/kakkoyun/demo-web-service/store"
+)
+
+func TestGetUserETag(t *testing.T) {
+	api, s := newTestAPI(t, nil)
+	handler := api.Routes()
+
+	get := func(ifNoneMatch string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/api/users/1", nil)
+		if ifNoneMatch != "" {
+			req.Header.Set("If-None-Match", ifNoneMatch)
+		}
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		return rr
+	}
+
+	// The first fetch returns the user with a weak ETag
+	rr := get("")
+	if status := rr.Code; status != http.StatusOK {
+		t.Fatalf("handler returned wrong status code: got %v want %v", status, http.StatusOK)
+	}
+	etag := rr.Header().Get("ETag")
+	if !strings.HasPrefix(etag, `W/"`) {
+		t.Fatalf("handler returned no weak ETag: got %q", etag)
+	}
+
+	// Replaying the ETag returns 304 without a body
+	rr = get(etag)
+	if status := rr.Code; status != http.StatusNotModified {
+		t.Fatalf("handler returned wrong status code: got %v want %v", status, http.StatusNotModified)
+	}
+	if rr.Body.Len() != 0 {
+		t.Errorf("304 response has a body: %q", rr.Body.String())
+	}
+	if got := rr.Header().Get("ETag"); got != etag {
+		t.Errorf("304 response has wrong ETag: got %q want %q", got, etag)
+	}
+
+	// A list of candidates matches too
+	if rr = get(`"other", ` + etag); rr.Code != http.StatusNotModified {
+		t.Errorf("handler returned wrong status code for ETag list: got %v want %v", rr.Code, http.StatusNotModified)
+	}
+
+	// Renaming the user changes the ETag
+	if _, err := s.Import(context.Background(), []models.User{{ID: 1, Name: "Johnny Doe"}}, store.DuplicateOverwrite); err != nil {
+		t.Fatal(err)
+	}
+
+	rr = get(etag)
+	if status := rr.Code; status != http.StatusOK {
+		t.Fatalf("handler returned wrong status code after update: got %v want %v", status, http.StatusOK)
+	}
+	if got := rr.Header().Get("ETag"); got == etag || got == "" {
+		t.Errorf("ETag did not change after update: got %q", got)
+	}
+	if !strings.Contains(rr.Body.String(), "Johnny Doe") {
+		t.Errorf("handler returned stale user: %q", rr.Body.String())
+	}
+}
+
+func TestETagMatches(t *testing.T) {
+	const etag = `W/"abc"`
+
+	testCases := []struct {
+		ifNoneMatch string
+		expected    bool
+	}{
+		{ifNoneMatch: "", expected: false},
+		{ifNoneMatch: `W/"abc"`, expected: true},
+		{ifNoneMatch: `"abc"`, expected: true},
+		{ifNoneMatch: `"xyz", W/"abc"`, expected: true},
+		{ifNoneMatch: "*", expected: true},
+		{ifNoneMatch: `"xyz"`, expected: false},
+	}
+
+	for _, tc := range testCases {
+		if got := etagMatches(tc.ifNoneMatch, etag); got != tc.expected {
+			t.Errorf("etagMatches(%q) = %v, want %v", tc.ifNoneMatch, got, tc.expected)
+		}
+	}
+}
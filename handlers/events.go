@@ -0,0 +1,154 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/kakkoyun/demo-web-service/models"
+)
+
+// subscriberBuffer is how many events a subscriber can fall behind before
+// further events are dropped for it
+const subscriberBuffer = 16
+
+// DefaultMaxEventSubscribers is the default limit on concurrent event stream subscribers
+const DefaultMaxEventSubscribers = 100
+
+// broadcast fans out user-creation events to its subscribers.
+// Publishing never blocks: events for subscribers that fall behind are dropped.
+type broadcast struct {
+	subscribers map[chan models.User]struct{}
+	// done is closed when the server shuts down, to end all streams
+	done      chan struct{}
+	closeOnce sync.Once
+	mu        sync.Mutex
+	// limit is the maximum number of subscribers; 0 means unlimited
+	limit int
+}
+
+// newBroadcast creates a broadcast without subscribers that accepts at most limit of them;
+// 0 means unlimited
+func newBroadcast(limit int) *broadcast {
+	return &broadcast{
+		subscribers: make(map[chan models.User]struct{}),
+		done:        make(chan struct{}),
+		limit:       limit,
+	}
+}
+
+// close tells all subscribers to stop; later calls have no effect
+func (b *broadcast) close() {
+	b.closeOnce.Do(func() { close(b.done) })
+}
+
+// subscribe returns a channel that receives every event published from now on.
+// It returns false when the broadcast already has the maximum number of subscribers.
+func (b *broadcast) subscribe() (chan models.User, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.limit > 0 && len(b.subscribers) >= b.limit {
+		return nil, false
+	}
+
+	ch := make(chan models.User, subscriberBuffer)
+	b.subscribers[ch] = struct{}{}
+	return ch, true
+}
+
+// unsubscribe stops sending events to ch
+func (b *broadcast) unsubscribe(ch chan models.User) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	delete(b.subscribers, ch)
+}
+
+// publish sends an event to all subscribers, reporting how many it was dropped for
+func (b *broadcast) publish(user models.User) (dropped int) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for ch := range b.subscribers {
+		select {
+		case ch <- user:
+		default:
+			dropped++
+		}
+	}
+	return dropped
+}
+
+// count returns the number of subscribers
+func (b *broadcast) count() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return len(b.subscribers)
+}
+
+// UserEvents streams an event for every created user as Server-Sent Events,
+// until the client disconnects
+func (a *API) UserEvents(w http.ResponseWriter, r *http.Request) {
+	rc := http.NewResponseController(w)
+
+	// The stream outlives the server's write timeout; writers that can't
+	// change their deadline don't have one
+	_ = rc.SetWriteDeadline(time.Time{})
+
+	events, ok := a.events.subscribe()
+	if !ok {
+		a.log(r.Context()).Warn("Event stream rejected, too many subscribers",
+			"remote_addr", r.RemoteAddr,
+			"limit", a.events.limit)
+		w.Header().Set("Retry-After", "5")
+		errorResponse(w, r, http.StatusServiceUnavailable, "Too many event stream subscribers, try again later")
+		return
+	}
+	defer a.events.unsubscribe(events)
+
+	a.log(r.Context()).Info("Event stream opened", "remote_addr", r.RemoteAddr, "subscribers", a.events.count())
+
+	h := w.Header()
+	h.Set("Content-Type", "text/event-stream")
+	h.Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	if err := rc.Flush(); err != nil {
+		a.log(r.Context()).Error("Event stream can't be flushed", "error", err)
+		return
+	}
+
+	for {
+		select {
+		case <-r.Context().Done():
+			a.log(r.Context()).Info("Event stream closed", "remote_addr", r.RemoteAddr)
+			return
+		case <-a.events.done:
+			a.log(r.Context()).Info("Event stream closed for shutdown", "remote_addr", r.RemoteAddr)
+			return
+		case user := <-events:
+			data, err := json.Marshal(user)
+			if err != nil {
+				a.log(r.Context()).Error("Failed to encode user event", "error", err)
+				continue
+			}
+			if _, err := fmt.Fprintf(w, "data: %s\n\n", data); err != nil {
+				a.log(r.Context()).Debug("Failed to write user event", "error", err)
+				return
+			}
+			if err := rc.Flush(); err != nil {
+				a.log(r.Context()).Debug("Failed to flush user event", "error", err)
+				return
+			}
+		}
+	}
+}
+
+// CloseEventStreams ends all open event streams, which would otherwise keep a
+// graceful shutdown waiting; register it with http.Server.RegisterOnShutdown
+func (a *API) CloseEventStreams() {
+	a.events.close()
+}
@@ -0,0 +1,187 @@
+package handlers
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kakkoyun/demo-web-service/models"
+)
+
+func TestUserEvents(t *testing.T) {
+	api, _ := newTestAPI(t, nil)
+
+	// The stream must survive the handler timeout and pass through compression
+	var handler http.Handler = api.Routes()
+	handler = Unless(api.Streaming, TimeoutMiddleware(50*time.Millisecond))(handler)
+	handler = CompressionMiddleware(1024)(handler)
+	handler = LoggingMiddleware(0)(handler)
+
+	srv := httptest.NewServer(handler)
+	defer srv.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, "GET", srv.URL+"/api/users/events", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Accept", "text/event-stream")
+	req.Header.Set("Accept-Encoding", "gzip")
+
+	// The response headers arrive once the subscription is in place
+	resp, err := srv.Client().Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Errorf("handler returned wrong content type: got %v want %v", ct, "text/event-stream")
+	}
+
+	// Outlast the handler timeout before the first event
+	time.Sleep(100 * time.Millisecond)
+
+	create, err := srv.Client().Post(srv.URL+"/api/users", "application/json", strings.NewReader(`{"name":"Streamed User"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	create.Body.Close()
+	if create.StatusCode != http.StatusCreated {
+		t.Fatalf("create returned wrong status code: got %v want %v", create.StatusCode, http.StatusCreated)
+	}
+
+	lines := make(chan string)
+	go func() {
+		scanner := bufio.NewScanner(resp.Body)
+		for scanner.Scan() {
+			lines <- scanner.Text()
+		}
+		close(lines)
+	}()
+
+	var line string
+	select {
+	case line = <-lines:
+	case <-time.After(time.Second):
+		t.Fatal("no event received")
+	}
+
+	data, ok := strings.CutPrefix(line, "data: ")
+	if !ok {
+		t.Fatalf("event is not a data line: %q", line)
+	}
+	var user models.User
+	if err := json.Unmarshal([]byte(data), &user); err != nil {
+		t.Fatalf("could not parse event data: %v", err)
+	}
+	if user.Name != "Streamed User" {
+		t.Errorf("event has wrong user name: got %v want %v", user.Name, "Streamed User")
+	}
+
+	// Disconnecting removes the subscription
+	cancel()
+	deadline := time.Now().Add(time.Second)
+	for api.events.count() > 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("subscription was not cleaned up after the client disconnected")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestBroadcastDropsEventsForSlowSubscribers(t *testing.T) {
+	b := newBroadcast(0)
+	ch, ok := b.subscribe()
+	if !ok {
+		t.Fatal("subscription rejected without a limit")
+	}
+
+	for range subscriberBuffer {
+		if dropped := b.publish(models.User{ID: 1}); dropped != 0 {
+			t.Fatalf("event dropped before the buffer was full: %v", dropped)
+		}
+	}
+	if dropped := b.publish(models.User{ID: 2}); dropped != 1 {
+		t.Errorf("wrong number of dropped events: got %v want %v", dropped, 1)
+	}
+
+	b.unsubscribe(ch)
+	if n := b.count(); n != 0 {
+		t.Errorf("wrong number of subscribers: got %v want %v", n, 0)
+	}
+}
+
+func TestCloseEventStreams(t *testing.T) {
+	api, _ := newTestAPI(t, nil)
+
+	req := httptest.NewRequest("GET", "/api/users/events", nil)
+	rr := httptest.NewRecorder()
+
+	done := make(chan struct{})
+	go func() {
+		api.Routes().ServeHTTP(rr, req)
+		close(done)
+	}()
+
+	api.CloseEventStreams()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("event stream did not end after closing")
+	}
+	if n := api.events.count(); n != 0 {
+		t.Errorf("wrong number of subscribers: got %v want %v", n, 0)
+	}
+}
+
+func TestUserEventsSubscriberLimit(t *testing.T) {
+	const limit = 3
+	api, _ := newTestAPI(t, []Option{WithMaxEventSubscribers(limit)})
+
+	srv := httptest.NewServer(api.Routes())
+	defer srv.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	subscribe := func() *http.Response {
+		t.Helper()
+		req, err := http.NewRequestWithContext(ctx, "GET", srv.URL+"/api/users/events", nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp, err := srv.Client().Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Cleanup(func() { resp.Body.Close() })
+		return resp
+	}
+
+	// The response headers arrive once each subscription is in place
+	for i := range limit {
+		if resp := subscribe(); resp.StatusCode != http.StatusOK {
+			t.Fatalf("subscriber %d got wrong status code: got %v want %v", i+1, resp.StatusCode, http.StatusOK)
+		}
+	}
+
+	resp := subscribe()
+	if resp.StatusCode != http.StatusServiceUnavailable {
+		t.Fatalf("subscriber over the limit got wrong status code: got %v want %v", resp.StatusCode, http.StatusServiceUnavailable)
+	}
+	if resp.Header.Get("Retry-After") == "" {
+		t.Error("rejection is missing the Retry-After header")
+	}
+	if n := api.events.count(); n != limit {
+		t.Errorf("wrong number of subscribers: got %v want %v", n, limit)
+	}
+}
@@ -0,0 +1,66 @@
+package handlers
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strings"
+)
+
+// NotFoundHandler responds to requests for unknown paths with a JSON error
+func NotFoundHandler(w http.ResponseWriter, r *http.Request) {
+	slog.Warn("No route matched", "method", r.Method, "path", r.URL.Path)
+
+	errorResponse(w, r, http.StatusNotFound, fmt.Sprintf("Path %s not found", r.URL.Path))
+}
+
+// methodNotAllowed responds to a request for a known path with a method the
+// path doesn't support, listing the allowed methods in the Allow header
+func methodNotAllowed(w http.ResponseWriter, r *http.Request, allowed []string) {
+	slog.Warn("Method not allowed", "method", r.Method, "path", r.URL.Path, "allowed", allowed)
+
+	w.Header().Set("Allow", strings.Join(allowed, ", "))
+	errorResponse(w, r, http.StatusMethodNotAllowed,
+		fmt.Sprintf("Method %s not allowed for %s", r.Method, r.URL.Path))
+}
+
+// withFallback serves requests that no route of mux matches with JSON errors
+// instead of the mux's plain-text ones: 405 for known paths requested with
+// another of the given methods, and 404 otherwise
+func withFallback(mux *http.ServeMux, methods []string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, pattern := mux.Handler(r); pattern != "" {
+			mux.ServeHTTP(w, r)
+			return
+		}
+
+		if allowed := allowedMethods(mux, r, methods); len(allowed) > 0 {
+			methodNotAllowed(w, r, allowed)
+			return
+		}
+		NotFoundHandler(w, r)
+	})
+}
+
+// allowedMethods returns the sorted methods among the given ones for which a
+// route of mux matches the request's path. GET routes also serve HEAD.
+func allowedMethods(mux *http.ServeMux, r *http.Request, methods []string) []string {
+	var allowed []string
+
+	probe := r.WithContext(r.Context())
+	for _, method := range methods {
+		probe.Method = method
+		if _, pattern := mux.Handler(probe); pattern == "" {
+			continue
+		}
+
+		allowed = append(allowed, method)
+		if method == http.MethodGet {
+			allowed = append(allowed, http.MethodHead)
+		}
+	}
+
+	slices.Sort(allowed)
+	return slices.Compact(allowed)
+}
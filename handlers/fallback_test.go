@@ -0,0 +1,103 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/kakkoyun/demo-web-service/models"
+)
+
+func TestNotFoundHandler(t *testing.T) {
+	testCases := []struct {
+		name           string
+		method         string
+		path           string
+		expectedStatus int
+	}{
+		{name: "Unknown Path", method: "GET", path: "/nonexistent", expectedStatus: http.StatusNotFound},
+		{name: "Unknown Nested Path", method: "POST", path: "/api/users/1/friends", expectedStatus: http.StatusNotFound},
+		{name: "Known Path", method: "GET", path: "/", expectedStatus: http.StatusOK},
+		{name: "Known Path Other Method", method: "DELETE", path: "/api/users", expectedStatus: http.StatusMethodNotAllowed},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			logs := captureLogs(t)
+			api, _ := newTestAPI(t, nil)
+
+			req := httptest.NewRequest(tc.method, tc.path, nil)
+			rr := httptest.NewRecorder()
+			api.Routes().ServeHTTP(rr, req)
+
+			if status := rr.Code; status != tc.expectedStatus {
+				t.Fatalf("handler returned wrong status code: got %v want %v", status, tc.expectedStatus)
+			}
+			if tc.expectedStatus != http.StatusNotFound {
+				return
+			}
+
+			if ct := rr.Header().Get("Content-Type"); !strings.HasPrefix(ct, "application/json") {
+				t.Errorf("handler returned wrong content type: got %v want %v", ct, "application/json")
+			}
+
+			var response models.ErrorResponse
+			if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+				t.Fatalf("could not parse response body: %v", err)
+			}
+			if response.Code != "NOT_FOUND" {
+				t.Errorf("handler returned wrong code: got %v want %v", response.Code, "NOT_FOUND")
+			}
+			if !strings.Contains(response.Message, tc.path) {
+				t.Errorf("message does not contain the path %s: %v", tc.path, response.Message)
+			}
+			if !strings.Contains(logs.String(), "No route matched") {
+				t.Errorf("miss was not logged: %s", logs.String())
+			}
+		})
+	}
+}
+
+func TestMethodNotAllowed(t *testing.T) {
+	testCases := []struct {
+		name          string
+		method        string
+		path          string
+		expectedAllow string
+	}{
+		{name: "Health", method: "PUT", path: "/api/health", expectedAllow: "GET, HEAD"},
+		{name: "Users", method: "DELETE", path: "/api/users", expectedAllow: "GET, HEAD, POST"},
+		{name: "User", method: "POST", path: "/api/users/1", expectedAllow: "GET, HEAD, PATCH"},
+		{name: "Added Endpoint", method: "PUT", path: "/api/version", expectedAllow: "GET, HEAD"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			api, _ := newTestAPI(t, nil)
+			api.Handle("GET", "/api/version", "VersionHandler", func(w http.ResponseWriter, _ *http.Request) {
+				w.WriteHeader(http.StatusOK)
+			})
+
+			req := httptest.NewRequest(tc.method, tc.path, nil)
+			rr := httptest.NewRecorder()
+			api.Routes().ServeHTTP(rr, req)
+
+			if status := rr.Code; status != http.StatusMethodNotAllowed {
+				t.Fatalf("handler returned wrong status code: got %v want %v", status, http.StatusMethodNotAllowed)
+			}
+			if allow := rr.Header().Get("Allow"); allow != tc.expectedAllow {
+				t.Errorf("handler returned wrong Allow header: got %q want %q", allow, tc.expectedAllow)
+			}
+
+			var response models.ErrorResponse
+			if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+				t.Fatalf("could not parse response body: %v", err)
+			}
+			if response.Code != "METHOD_NOT_ALLOWED" {
+				t.Errorf("handler returned wrong code: got %v want %v", response.Code, "METHOD_NOT_ALLOWED")
+			}
+		})
+	}
+}
@@ -0,0 +1,137 @@
+package handlers
+
+import (
+	"math/rand/v2"
+	"sync"
+	"time"
+)
+
+// Operations that can have simulated faults injected
+const (
+	// OpHome fails the home endpoint with 503
+	OpHome = "home"
+	// OpListUsers fails listing users with a database connection error
+	OpListUsers = "list_users"
+	// OpValidateUser fails user creation with a validation error
+	OpValidateUser = "validate_user"
+	// OpProcessUser fails user processing with a constraint violation, and delays it
+	OpProcessUser = "process_user"
+	// OpQueryTimeout fails a user lookup with a connection timeout
+	OpQueryTimeout = "query_timeout"
+	// OpQueryFailed fails a user lookup with a query execution error
+	OpQueryFailed = "query_failed"
+	// OpQueryConstraint fails a user lookup with a constraint violation
+	OpQueryConstraint = "query_constraint"
+)
+
+// FaultInjector decides when handlers simulate failures and latency
+type FaultInjector interface {
+	// ShouldFail reports whether the named operation should fail
+	ShouldFail(op string) bool
+	// Delay returns the simulated latency for the named operation
+	Delay(op string) time.Duration
+}
+
+// NoFaults is a FaultInjector that never injects failures or latency
+type NoFaults struct{}
+
+// ShouldFail always returns false
+func (NoFaults) ShouldFail(string) bool { return false }
+
+// Delay always returns zero
+func (NoFaults) Delay(string) time.Duration { return 0 }
+
+// WithoutFaults wraps a FaultInjector so that the given operations never fail
+// or get delayed, while the others are still decided by f
+func WithoutFaults(f FaultInjector, ops ...string) FaultInjector {
+	excluded := make(map[string]bool, len(ops))
+	for _, op := range ops {
+		excluded[op] = true
+	}
+	return excludedFaults{FaultInjector: f, excluded: excluded}
+}
+
+// excludedFaults is a FaultInjector that never injects faults into the excluded operations
+type excludedFaults struct {
+	FaultInjector
+	excluded map[string]bool
+}
+
+// ShouldFail reports whether the named operation should fail, never failing excluded ones
+func (f excludedFaults) ShouldFail(op string) bool {
+	return !f.excluded[op] && f.FaultInjector.ShouldFail(op)
+}
+
+// Delay returns the simulated latency for the named operation, zero for excluded ones
+func (f excludedFaults) Delay(op string) time.Duration {
+	if f.excluded[op] {
+		return 0
+	}
+	return f.FaultInjector.Delay(op)
+}
+
+// defaultFailureRates are the failure probabilities used for demos
+var defaultFailureRates = map[string]float64{
+	OpHome:            0.1,
+	OpListUsers:       0.2,
+	OpValidateUser:    1.0 / 3,
+	OpProcessUser:     0.25,
+	OpQueryTimeout:    0.3,
+	OpQueryFailed:     0.3,
+	OpQueryConstraint: 0.2,
+}
+
+// defaultMaxDelays are the upper bounds of the simulated latencies used for demos
+var defaultMaxDelays = map[string]time.Duration{
+	OpProcessUser: 100 * time.Millisecond,
+}
+
+// ProbabilisticFaults is a FaultInjector that fails operations at random
+// with fixed probabilities, for demonstrating error handling
+type ProbabilisticFaults struct {
+	rand      *rand.Rand
+	rates     map[string]float64
+	maxDelays map[string]time.Duration
+	mu        sync.Mutex
+}
+
+// NewProbabilisticFaults creates a ProbabilisticFaults with the demo failure rates.
+// A nil random source uses a randomly seeded one.
+func NewProbabilisticFaults(r *rand.Rand) *ProbabilisticFaults {
+	if r == nil {
+		r = rand.New(rand.NewPCG(rand.Uint64(), rand.Uint64())) //nolint:gosec
+	}
+
+	return &ProbabilisticFaults{
+		rand:      r,
+		rates:     defaultFailureRates,
+		maxDelays: defaultMaxDelays,
+	}
+}
+
+// ShouldFail reports whether the named operation should fail this time
+func (f *ProbabilisticFaults) ShouldFail(op string) bool {
+	rate, ok := f.rates[op]
+	if !ok {
+		return false
+	}
+
+	// rand.Rand is not safe for concurrent use
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return f.rand.Float64() < rate
+}
+
+// Delay returns a random latency up to the maximum for the named operation
+func (f *ProbabilisticFaults) Delay(op string) time.Duration {
+	maxDelay, ok := f.maxDelays[op]
+	if !ok || maxDelay <= 0 {
+		return 0
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return time.Duration(f.rand.Int64N(int64(maxDelay)))
+}
@@ -0,0 +1,140 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"expvar"
+	"math/rand/v2"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// stubFaults is a FaultInjector that fails exactly the configured operations
+type stubFaults map[string]bool
+
+func (s stubFaults) ShouldFail(op string) bool { return s[op] }
+
+func (stubFaults) Delay(string) time.Duration { return 0 }
+
+func TestInjectedFaults(t *testing.T) {
+	testCases := []struct {
+		name           string
+		faults         stubFaults
+		method         string
+		path           string
+		body           string
+		expectedStatus int
+	}{
+		{name: "Home", faults: stubFaults{OpHome: true}, method: "GET", path: "/", expectedStatus: http.StatusServiceUnavailable},
+		{name: "List Users", faults: stubFaults{OpListUsers: true}, method: "GET", path: "/api/users", expectedStatus: http.StatusInternalServerError},
+		{name: "Validate User", faults: stubFaults{OpValidateUser: true}, method: "POST", path: "/api/users", body: `{"name":"Test"}`, expectedStatus: http.StatusBadRequest},
+		{name: "Process User", faults: stubFaults{OpProcessUser: true}, method: "POST", path: "/api/users", body: `{"name":"Test"}`, expectedStatus: http.StatusBadRequest},
+		{name: "Query Timeout", faults: stubFaults{OpQueryTimeout: true}, method: "GET", path: "/api/users/5", expectedStatus: http.StatusInternalServerError},
+		{name: "Query Timeout Only Affects Some IDs", faults: stubFaults{OpQueryTimeout: true}, method: "GET", path: "/api/users/1", expectedStatus: http.StatusOK},
+		{name: "Query Failed", faults: stubFaults{OpQueryFailed: true}, method: "GET", path: "/api/users/3", expectedStatus: http.StatusInternalServerError},
+		{name: "Query Constraint", faults: stubFaults{OpQueryConstraint: true}, method: "GET", path: "/api/users/1001", expectedStatus: http.StatusInternalServerError},
+		{name: "Unrelated Fault", faults: stubFaults{OpHome: true}, method: "GET", path: "/api/users", expectedStatus: http.StatusOK},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			api, _ := newTestAPI(t, []Option{WithFaultInjector(tc.faults)})
+
+			req := httptest.NewRequest(tc.method, tc.path, bytes.NewBufferString(tc.body))
+			rr := httptest.NewRecorder()
+			api.Routes().ServeHTTP(rr, req)
+
+			if status := rr.Code; status != tc.expectedStatus {
+				t.Errorf("handler returned wrong status code: got %v want %v", status, tc.expectedStatus)
+			}
+		})
+	}
+}
+
+func TestProbabilisticFaults(t *testing.T) {
+	f := NewProbabilisticFaults(rand.New(rand.NewPCG(1, 2)))
+
+	if f.ShouldFail("unknown") {
+		t.Error("unknown operation should never fail")
+	}
+	if d := f.Delay(OpHome); d != 0 {
+		t.Errorf("wrong delay for operation without latency: got %v want %v", d, 0)
+	}
+
+	failures := 0
+	for range 1000 {
+		if f.ShouldFail(OpListUsers) {
+			failures++
+		}
+		if d := f.Delay(OpProcessUser); d < 0 || d >= defaultMaxDelays[OpProcessUser] {
+			t.Fatalf("delay out of range: got %v", d)
+		}
+	}
+
+	// The failure rate of list_users is 0.2
+	if failures < 100 || failures > 300 {
+		t.Errorf("unexpected number of failures: got %v of 1000", failures)
+	}
+}
+
+func TestWithoutFaultsKeepsHomeStable(t *testing.T) {
+	faults := WithoutFaults(NewProbabilisticFaults(rand.New(rand.NewPCG(1, 2))), OpHome)
+	api, _ := newTestAPI(t, []Option{WithFaultInjector(faults)})
+	routes := api.Routes()
+
+	for range 200 {
+		req := httptest.NewRequest("GET", "/", nil)
+		rr := httptest.NewRecorder()
+		routes.ServeHTTP(rr, req)
+
+		if status := rr.Code; status != http.StatusOK {
+			t.Fatalf("handler returned wrong status code: got %v want %v", status, http.StatusOK)
+		}
+	}
+
+	// Other operations are still failed at random
+	failures := 0
+	for range 200 {
+		if faults.ShouldFail(OpListUsers) {
+			failures++
+		}
+	}
+	if failures == 0 {
+		t.Error("excluding the home endpoint disabled other faults")
+	}
+}
+
+func TestDBErrorMetrics(t *testing.T) {
+	metrics := new(expvar.Map)
+	faults := NewProbabilisticFaults(rand.New(rand.NewPCG(1, 2)))
+	api, _ := newTestAPI(t, []Option{WithFaultInjector(faults), WithDBErrorMetrics(metrics)})
+
+	// Each ID is prone to exactly one type of simulated error
+	ids := map[string]int{
+		OpQueryTimeout:    5,
+		OpQueryFailed:     3,
+		OpQueryConstraint: 1001,
+	}
+
+	for errType, id := range ids {
+		failures := 0
+		for range 100 {
+			if err := api.queryDatabase(context.Background(), id); err != nil {
+				failures++
+			}
+		}
+
+		if failures == 0 {
+			t.Fatalf("no %s errors were simulated", errType)
+		}
+		counter, ok := metrics.Get(errType).(*expvar.Int)
+		if !ok {
+			t.Fatalf("no counter for %s errors", errType)
+		}
+		if got := counter.Value(); got != int64(failures) {
+			t.Errorf("wrong count of %s errors: got %v want %v", errType, got, failures)
+		}
+	}
+}
@@ -0,0 +1,202 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/gqlerrors"
+	"github.com/graphql-go/graphql/language/location"
+
+	"github.com/kakkoyun/demo-web-service/models"
+	"github.com/kakkoyun/demo-web-service/store"
+)
+
+// graphQLRequest is the body of a GraphQL request
+type graphQLRequest struct {
+	Variables     map[string]any `json:"variables"`
+	Query         string         `json:"query"`
+	OperationName string         `json:"operationName"`
+}
+
+// graphQLError is an error reported in the errors array of a GraphQL
+// response, with its machine-readable code in the extensions
+type graphQLError struct {
+	err  error
+	code string
+}
+
+func (e graphQLError) Error() string {
+	return e.err.Error()
+}
+
+// Extensions makes the library report the code next to the message
+func (e graphQLError) Extensions() map[string]any {
+	return map[string]any{"code": e.code}
+}
+
+// GraphQL executes a GraphQL query against the user store. Failures of the
+// query itself, such as an unknown user, are reported in the errors array of
+// a 200 response rather than through the HTTP status.
+func (a *API) GraphQL(w http.ResponseWriter, r *http.Request) {
+	a.log(r.Context()).Info("Executing GraphQL query", "path", r.URL.Path)
+
+	var req graphQLRequest
+	if err := decodeJSON(r, &req); err != nil {
+		status := http.StatusBadRequest
+		if errors.Is(err, ErrBodyTooLarge) {
+			status = http.StatusRequestEntityTooLarge
+		}
+		a.log(r.Context()).Error("Invalid GraphQL request", "error", err, "status", status)
+		jsonResponse(w, status, graphql.Result{Errors: []gqlerrors.FormattedError{{
+			Message:    err.Error(),
+			Locations:  []location.SourceLocation{},
+			Extensions: map[string]any{"code": errorCode(err, status)},
+		}}})
+		return
+	}
+
+	schema, err := a.graphQLSchema()
+	if err != nil {
+		a.log(r.Context()).Error("Failed to build GraphQL schema", "error", err)
+		errorResponse(w, r, http.StatusInternalServerError, "GraphQL is unavailable")
+		return
+	}
+
+	result := graphql.Do(graphql.Params{
+		Schema:         schema,
+		RequestString:  req.Query,
+		VariableValues: req.Variables,
+		OperationName:  req.OperationName,
+		Context:        r.Context(),
+	})
+	if result.HasErrors() {
+		a.log(r.Context()).Warn("GraphQL query returned errors", "errors", len(result.Errors), "first", result.Errors[0].Message)
+	}
+
+	jsonResponse(w, http.StatusOK, result)
+}
+
+// graphQLUserType is the GraphQL type of a user
+var graphQLUserType = graphql.NewObject(graphql.ObjectConfig{
+	Name: "User",
+	Fields: graphql.Fields{
+		"id":   &graphql.Field{Type: graphql.NewNonNull(graphql.Int)},
+		"name": &graphql.Field{Type: graphql.NewNonNull(graphql.String)},
+		"publicId": &graphql.Field{
+			Type: graphql.String,
+			Resolve: func(p graphql.ResolveParams) (any, error) {
+				return p.Source.(models.User).PublicID, nil
+			},
+		},
+		"createdAt": &graphql.Field{
+			Type: graphql.DateTime,
+			Resolve: func(p graphql.ResolveParams) (any, error) {
+				return p.Source.(models.User).CreatedAt, nil
+			},
+		},
+		"updatedAt": &graphql.Field{
+			Type: graphql.DateTime,
+			Resolve: func(p graphql.ResolveParams) (any, error) {
+				return p.Source.(models.User).UpdatedAt, nil
+			},
+		},
+	},
+})
+
+// newGraphQLSchema builds a schema with user(id) and users(limit, offset)
+// queries backed by the API's users. They go through the same fault
+// injection, retries and circuit breaker as the REST endpoints. When the API
+// looks users up by public ID, user takes one, a string, rather than an
+// internal ID.
+func (a *API) newGraphQLSchema() (graphql.Schema, error) {
+	users, publicIDs := a.users, a.publicIDPaths
+
+	idType := graphql.Int
+	if publicIDs {
+		idType = graphql.String
+	}
+
+	query := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Query",
+		Fields: graphql.Fields{
+			"user": &graphql.Field{
+				Type: graphQLUserType,
+				Args: graphql.FieldConfigArgument{
+					"id": &graphql.ArgumentConfig{Type: graphql.NewNonNull(idType)},
+				},
+				Resolve: func(p graphql.ResolveParams) (any, error) {
+					id, _ := p.Args["id"].(int)
+					if publicIDs {
+						publicID, _ := p.Args["id"].(string)
+						var err error
+						id, err = users.Resolve(p.Context, publicID)
+						if errors.Is(err, store.ErrNotFound) {
+							return nil, graphQLError{err: fmt.Errorf("User with ID %s not found", publicID), code: CodeUserNotFound}
+						}
+						if err != nil {
+							return nil, graphQLError{err: errors.New("Failed to retrieve user data"), code: statusCode(http.StatusInternalServerError)}
+						}
+					}
+					if id < 1 {
+						return nil, graphQLError{err: fmt.Errorf("%w: must be a positive integer, got %d", ErrInvalidUserID, id), code: CodeInvalidUserID}
+					}
+
+					err := a.guardedQuery(p.Context, id)
+					if errors.Is(err, ErrCircuitOpen) {
+						a.log(p.Context).Warn("Database query short-circuited", "id", id, "error", err)
+						return nil, graphQLError{err: errors.New("Database temporarily unavailable"), code: statusCode(http.StatusServiceUnavailable)}
+					}
+					if err != nil {
+						a.log(p.Context).Error("Database query failed", "id", id, "error", err)
+						return nil, graphQLError{err: errors.New("Failed to retrieve user data"), code: statusCode(http.StatusInternalServerError)}
+					}
+
+					user, err := users.Get(p.Context, id)
+					if errors.Is(err, store.ErrNotFound) {
+						return nil, graphQLError{err: fmt.Errorf("User with ID %d not found", id), code: CodeUserNotFound}
+					}
+					if err != nil {
+						return nil, graphQLError{err: errors.New("Failed to retrieve user data"), code: statusCode(http.StatusInternalServerError)}
+					}
+					return user, nil
+				},
+			},
+			"users": &graphql.Field{
+				Type: graphql.NewNonNull(graphql.NewList(graphql.NewNonNull(graphQLUserType))),
+				Args: graphql.FieldConfigArgument{
+					"limit":  &graphql.ArgumentConfig{Type: graphql.Int, DefaultValue: DefaultPageSize},
+					"offset": &graphql.ArgumentConfig{Type: graphql.Int, DefaultValue: 0},
+				},
+				Resolve: func(p graphql.ResolveParams) (any, error) {
+					limit, _ := p.Args["limit"].(int)
+					offset, _ := p.Args["offset"].(int)
+					if limit < 1 || limit > MaxPageSize {
+						return nil, graphQLError{err: fmt.Errorf("%w: must be between 1 and %d, got %d", ErrInvalidLimit, MaxPageSize, limit), code: CodeInvalidLimit}
+					}
+					if offset < 0 {
+						return nil, graphQLError{err: fmt.Errorf("%w: offset must not be negative, got %d", ErrValidation, offset), code: CodeValidation}
+					}
+
+					// Simulate a database outage when the fault injector says so
+					if a.faults.ShouldFail(OpListUsers) {
+						a.log(p.Context).Error("Failed to get users", "error", errors.New("database connection failed"))
+						return nil, graphQLError{err: errors.New("Failed to retrieve users"), code: statusCode(http.StatusInternalServerError)}
+					}
+
+					all, err := users.List(p.Context)
+					if err != nil {
+						return nil, graphQLError{err: errors.New("Failed to retrieve users"), code: statusCode(http.StatusInternalServerError)}
+					}
+					if offset > len(all) {
+						offset = len(all)
+					}
+					return all[offset:min(offset+limit, len(all))], nil
+				},
+			},
+		},
+	})
+
+	return graphql.NewSchema(graphql.SchemaConfig{Query: query})
+}
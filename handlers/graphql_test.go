@@ -0,0 +1,195 @@
+package handlers
+
+import (
+	"encoding/json"
+	"expvar"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kakkoyun/demo-web-service/models"
+)
+
+// graphQLResponse is the shape of a GraphQL response body
+type graphQLResponse struct {
+	Data   map[string]any `json:"data"`
+	Errors []struct {
+		Extensions map[string]any `json:"extensions"`
+		Message    string         `json:"message"`
+	} `json:"errors"`
+}
+
+func TestGraphQLHandler(t *testing.T) {
+	testCases := []struct {
+		name          string
+		body          string
+		expectedData  map[string]any
+		expectedCode  string
+		expectedError bool
+	}{
+		{
+			name: "User",
+			body: `{"query":"{ user(id: 1) { id name } }"}`,
+			expectedData: map[string]any{
+				"user": map[string]any{"id": float64(1), "name": "John Doe"},
+			},
+		},
+		{
+			name: "User With Variables",
+			body: `{"query":"query Get($id: Int!) { user(id: $id) { name } }","variables":{"id":2}}`,
+			expectedData: map[string]any{
+				"user": map[string]any{"name": "Jane Smith"},
+			},
+		},
+		{
+			name: "Users",
+			body: `{"query":"{ users { id name } }"}`,
+			expectedData: map[string]any{
+				"users": []any{
+					map[string]any{"id": float64(1), "name": "John Doe"},
+					map[string]any{"id": float64(2), "name": "Jane Smith"},
+					map[string]any{"id": float64(3), "name": "Jane Doe"},
+				},
+			},
+		},
+		{
+			name: "Users With Limit And Offset",
+			body: `{"query":"{ users(limit: 1, offset: 1) { id } }"}`,
+			expectedData: map[string]any{
+				"users": []any{map[string]any{"id": float64(2)}},
+			},
+		},
+		{
+			name: "Users Offset Past The End",
+			body: `{"query":"{ users(offset: 10) { id } }"}`,
+			expectedData: map[string]any{
+				"users": []any{},
+			},
+		},
+		{
+			name:          "Unknown User",
+			body:          `{"query":"{ user(id: 42) { name } }"}`,
+			expectedData:  map[string]any{"user": nil},
+			expectedError: true,
+			expectedCode:  CodeUserNotFound,
+		},
+		{
+			name:          "Invalid Limit",
+			body:          `{"query":"{ users(limit: 0) { id } }"}`,
+			expectedError: true,
+			expectedCode:  CodeInvalidLimit,
+		},
+		{
+			name:          "Syntax Error",
+			body:          `{"query":"{ user(id: 1) { name }"}`,
+			expectedError: true,
+		},
+		{
+			name:          "Unknown Field",
+			body:          `{"query":"{ user(id: 1) { email } }"}`,
+			expectedError: true,
+		},
+	}
+
+	api, _ := newTestAPI(t, nil,
+		models.User{ID: 1, Name: "John Doe"},
+		models.User{ID: 2, Name: "Jane Smith"},
+		models.User{ID: 3, Name: "Jane Doe"},
+	)
+	handler := api.Routes()
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest("POST", "/api/graphql", strings.NewReader(tc.body))
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			// Query errors are reported in the body, not the status
+			if status := rr.Code; status != http.StatusOK {
+				t.Fatalf("handler returned wrong status code: got %v want %v", status, http.StatusOK)
+			}
+
+			var response graphQLResponse
+			if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+				t.Fatalf("could not parse response body: %v", err)
+			}
+
+			if hasErrors := len(response.Errors) > 0; hasErrors != tc.expectedError {
+				t.Fatalf("handler returned wrong errors: %s", rr.Body.String())
+			}
+			if tc.expectedCode != "" && response.Errors[0].Extensions["code"] != tc.expectedCode {
+				t.Errorf("handler returned wrong error code: got %v want %v", response.Errors[0].Extensions["code"], tc.expectedCode)
+			}
+			if tc.expectedData != nil && !reflect.DeepEqual(response.Data, tc.expectedData) {
+				t.Errorf("handler returned wrong data: got %v want %v", response.Data, tc.expectedData)
+			}
+		})
+	}
+}
+
+func TestGraphQLHandlerFaults(t *testing.T) {
+	metrics := new(expvar.Map)
+	cb, _ := newTestBreaker(1, 30*time.Second)
+	api, _ := newTestAPI(t, []Option{
+		WithFaultInjector(stubFaults{OpQueryTimeout: true, OpListUsers: true}),
+		WithDBErrorMetrics(metrics),
+		WithCircuitBreaker(cb),
+	}, models.User{ID: 5, Name: "John Doe"})
+	handler := api.Routes()
+
+	testCases := []struct {
+		name         string
+		body         string
+		expectedCode string
+	}{
+		{name: "User", body: `{"query":"{ user(id: 5) { name } }"}`, expectedCode: "INTERNAL_SERVER_ERROR"},
+		{name: "User With Open Circuit", body: `{"query":"{ user(id: 5) { name } }"}`, expectedCode: "SERVICE_UNAVAILABLE"},
+		{name: "Users", body: `{"query":"{ users { name } }"}`, expectedCode: "INTERNAL_SERVER_ERROR"},
+	}
+
+	for _, tc := range testCases {
+		req := httptest.NewRequest("POST", "/api/graphql", strings.NewReader(tc.body))
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+
+		var response graphQLResponse
+		if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+			t.Fatalf("%s: could not parse response body: %v", tc.name, err)
+		}
+		if len(response.Errors) == 0 {
+			t.Fatalf("%s: handler returned no errors: %s", tc.name, rr.Body.String())
+		}
+		if got := response.Errors[0].Extensions["code"]; got != tc.expectedCode {
+			t.Errorf("%s: handler returned wrong error code: got %v want %v", tc.name, got, tc.expectedCode)
+		}
+	}
+
+	// Only the query that reached the simulated database counts as an error
+	counter, ok := metrics.Get(OpQueryTimeout).(*expvar.Int)
+	if !ok || counter.Value() != 1 {
+		t.Errorf("wrong count of %s errors: got %v want 1", OpQueryTimeout, metrics.Get(OpQueryTimeout))
+	}
+}
+
+func TestGraphQLHandlerInvalidBody(t *testing.T) {
+	api, _ := newTestAPI(t, nil)
+
+	req := httptest.NewRequest("POST", "/api/graphql", strings.NewReader(`{"query":`))
+	rr := httptest.NewRecorder()
+	api.Routes().ServeHTTP(rr, req)
+
+	if status := rr.Code; status != http.StatusBadRequest {
+		t.Fatalf("handler returned wrong status code: got %v want %v", status, http.StatusBadRequest)
+	}
+
+	var response graphQLResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+		t.Fatalf("could not parse response body: %v", err)
+	}
+	if len(response.Errors) != 1 || response.Errors[0].Extensions["code"] != CodeValidation {
+		t.Errorf("handler returned wrong errors: %s", rr.Body.String())
+	}
+}
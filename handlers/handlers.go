@@ -1,111 +1,163 @@
 package handlers
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"log/slog"
-	"math/rand/v2"
+	"math"
 	"net/http"
 	"runtime/debug"
 	"strconv"
+	"strings"
 	"time"
 
 	"braces.dev/errtrace"
 
 	"github.com/kakkoyun/demo-web-service/models"
+	"github.com/kakkoyun/demo-web-service/store"
 )
 
-// TestMode controls whether random errors are generated
-// Set this to true in tests to disable random failures
-var TestMode bool
+// Home handles the root endpoint
+func (a *API) Home(w http.ResponseWriter, r *http.Request) {
+	a.log(r.Context()).Info("Handling home request", "path", r.URL.Path, "method", r.Method)
 
-// HomeHandler handles the root endpoint
-func HomeHandler(w http.ResponseWriter, r *http.Request) {
-	slog.Info("Handling home request", "path", r.URL.Path, "method", r.Method)
-
-	// Randomly generate an error 10% of the time (but not in test mode)
-	// #nosec G404 -- This is a false positive
-	if !TestMode && rand.IntN(10) == 0 { //nolint:gosec
-		slog.Error("Random error in home handler", "error", "random service unavailable")
-		errorResponse(w, http.StatusServiceUnavailable, "Service temporarily unavailable")
+	// Simulate an outage when the fault injector says so
+	if a.faults.ShouldFail(OpHome) {
+		a.log(r.Context()).Error("Random error in home handler", "error", "random service unavailable")
+		errorResponse(w, r, http.StatusServiceUnavailable, "Service temporarily unavailable")
 		return
 	}
 
-	response := map[string]string{
-		"message": "Welcome to the API",
+	response := models.MessageResponse{
+		Message: "Welcome to the API",
 	}
 
-	jsonResponse(w, http.StatusOK, response)
+	respond(w, r, http.StatusOK, response)
 }
 
-// HealthCheckHandler returns the API health status
-func HealthCheckHandler(w http.ResponseWriter, r *http.Request) {
-	slog.Debug("Health check requested", "remote_addr", r.RemoteAddr)
+// startTime is when the process started, from which the health check reports uptime
+var startTime = time.Now()
+
+// HealthCheck returns the API health status
+func (a *API) HealthCheck(w http.ResponseWriter, r *http.Request) {
+	a.log(r.Context()).Debug("Health check requested", "remote_addr", r.RemoteAddr)
 
-	response := map[string]string{
-		"status": "healthy",
+	now := time.Now()
+	response := models.HealthResponse{
+		Status:         "healthy",
+		Version:        a.version,
+		Uptime:         now.Sub(startTime).Seconds(),
+		Timestamp:      now.UTC().Truncate(time.Second),
+		RequestsServed: RequestsServed(),
 	}
 
-	jsonResponse(w, http.StatusOK, response)
+	respond(w, r, http.StatusOK, response)
 }
 
-// GetUsersHandler returns a list of users
-func GetUsersHandler(w http.ResponseWriter, r *http.Request) {
-	slog.Info("Getting all users", "path", r.URL.Path)
+// GetUsers returns a list of users
+func (a *API) GetUsers(w http.ResponseWriter, r *http.Request) {
+	a.log(r.Context()).Info("Getting all users", "path", r.URL.Path)
 
-	// Randomly generate an error 20% of the time (but not in test mode)
-	// #nosec G404 -- This is a false positive
-	if !TestMode && rand.IntN(5) == 0 { //nolint:gosec
+	p, paginated, err := parsePage(r)
+	if err != nil {
+		a.log(r.Context()).Error("Invalid pagination parameters", "error", err)
+		errorResponseFor(w, r, http.StatusBadRequest, err, err.Error())
+		return
+	}
+
+	// Simulate a database outage when the fault injector says so
+	if a.faults.ShouldFail(OpListUsers) {
 		// Simple error handling - just log and return an error
 		err := errors.New("database connection failed")
-		slog.Error("Failed to get users", "error", err)
-		errorResponse(w, http.StatusInternalServerError, "Failed to retrieve users")
+		a.log(r.Context()).Error("Failed to get users", "error", err)
+		errorResponse(w, r, http.StatusInternalServerError, "Failed to retrieve users")
 		return
 	}
 
-	// In a real application, we would get these from a database
-	users := []models.User{
-		{ID: 1, Name: "John Doe"},
-		{ID: 2, Name: "Jane Smith"},
+	users, err := a.users.List(r.Context())
+	if err != nil {
+		a.log(r.Context()).Error("Failed to list users", "error", err)
+		errorResponse(w, r, http.StatusInternalServerError, "Failed to retrieve users")
+		return
 	}
 
-	response := models.UserResponse{
-		Status: "success",
-		Users:  users,
+	// Send an empty array rather than null when there are no users
+	if users == nil {
+		users = []models.User{}
+	}
+
+	response := models.UserListResponse{Status: "success"}
+	if paginated {
+		var prev, next *page
+		users, prev, next = p.slice(users)
+		if links := pageLinks(r, prev, next); links != "" {
+			w.Header().Set("Link", links)
+		}
+		response.Pagination = &models.Pagination{NextCursor: next.cursor(), Limit: p.limit}
 	}
+	response.Users = users
+	response.Count = len(users)
 
-	jsonResponse(w, http.StatusOK, response)
+	respond(w, r, http.StatusOK, response)
 }
 
-// CreateUserHandler creates a new user
-func CreateUserHandler(w http.ResponseWriter, r *http.Request) {
-	slog.Info("Creating new user", "path", r.URL.Path)
+// CountUsers returns the number of users, only counting those whose name
+// contains the name query parameter, ignoring case, when it is given
+func (a *API) CountUsers(w http.ResponseWriter, r *http.Request) {
+	a.log(r.Context()).Info("Counting users", "path", r.URL.Path)
+
+	if a.faults.ShouldFail(OpListUsers) {
+		err := errors.New("database connection failed")
+		a.log(r.Context()).Error("Failed to count users", "error", err)
+		errorResponse(w, r, http.StatusInternalServerError, "Failed to count users")
+		return
+	}
 
-	// Example of basic error checking
-	if r.ContentLength == 0 {
-		err := errors.New("empty request body")
-		slog.Error("Failed to create user", "error", err)
-		errorResponse(w, http.StatusBadRequest, "Empty request body")
+	count, err := a.users.Count(r.Context(), r.URL.Query().Get("name"))
+	if err != nil {
+		a.log(r.Context()).Error("Failed to count users", "error", err)
+		errorResponse(w, r, http.StatusInternalServerError, "Failed to count users")
 		return
 	}
 
+	respond(w, r, http.StatusOK, models.UserCountResponse{Status: "success", Count: count})
+}
+
+// CreateUser creates a new user
+func (a *API) CreateUser(w http.ResponseWriter, r *http.Request) {
+	a.log(r.Context()).Info("Creating new user", "path", r.URL.Path)
+
 	// Process the user data and handle any errors
-	if err := validateAndCreateUser(r); err != nil {
+	user, err := a.validateAndCreateUser(r)
+	if err != nil {
 		// Here we handle errors from our nested function
+		var invalid *InvalidFieldsError
 		statusCode := http.StatusBadRequest
+		switch {
+		case errors.Is(err, ErrBodyTooLarge):
+			statusCode = http.StatusRequestEntityTooLarge
+		case errors.As(err, &invalid):
+			statusCode = http.StatusUnprocessableEntity
+		}
 		errMsg := err.Error()
 
-		slog.Error("User creation failed",
+		a.log(r.Context()).Error("User creation failed",
 			"error", err,
 			"status", statusCode)
-		errorResponse(w, statusCode, errMsg)
+		errorResponseFor(w, r, statusCode, err, errMsg)
 		return
 	}
 
-	var user models.User = models.User{
-		ID:   3,
-		Name: "New User",
+	if dropped := a.events.publish(user); dropped > 0 {
+		a.log(r.Context()).Warn("User event dropped for slow subscribers", "user_id", user.ID, "dropped", dropped)
+	}
+	// Webhooks are delivered in the background; failures never affect the response
+	if a.webhooks != nil && !a.webhooks.enqueue(r.Context(), user) {
+		a.log(r.Context()).Warn("User webhook dropped", "user_id", user.ID)
 	}
 
 	response := models.UserResponse{
@@ -114,117 +166,288 @@ func CreateUserHandler(w http.ResponseWriter, r *http.Request) {
 		User:    &user,
 	}
 
-	jsonResponse(w, http.StatusCreated, response)
+	respond(w, r, http.StatusCreated, response)
 }
 
 // Common validation errors
 var (
 	ErrValidation = errors.New("validation error")
+	ErrEmptyBody  = errors.New("empty request body")
+	// ErrBodyTooLarge is not a validation error, as the body was never fully read
+	ErrBodyTooLarge = errors.New("request body too large")
 )
 
+// InvalidFieldsError is a validation error listing every invalid field of a
+// request, which is answered with a 422 and the fields in its details
+type InvalidFieldsError struct {
+	Fields []models.FieldError
+}
+
+// Error lists the invalid fields, e.g. "validation error: name is required"
+func (e *InvalidFieldsError) Error() string {
+	problems := make([]string, len(e.Fields))
+	for i, field := range e.Fields {
+		problems[i] = field.String()
+	}
+	return ErrValidation.Error() + ": " + strings.Join(problems, "; ")
+}
+
+// Unwrap makes an InvalidFieldsError match ErrValidation
+func (e *InvalidFieldsError) Unwrap() error {
+	return ErrValidation
+}
+
+// validateUser returns an InvalidFieldsError if user has invalid fields
+func validateUser(user models.User) error {
+	if problems := user.Validate(); len(problems) > 0 {
+		return &InvalidFieldsError{Fields: problems}
+	}
+	return nil
+}
+
+// decodeJSON decodes a JSON request body into v.
+// Emptiness is detected by reading the body rather than trusting ContentLength,
+// which is -1 for chunked requests.
+func decodeJSON(r *http.Request, v any) error {
+	return errtrace.Wrap(decodeWith(json.NewDecoder(r.Body), v))
+}
+
+// decodeJSONStrict decodes a JSON request body like decodeJSON, but rejects
+// fields that v doesn't have
+func decodeJSONStrict(r *http.Request, v any) error {
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+	return errtrace.Wrap(decodeWith(dec, v))
+}
+
+// decodeWith decodes a request body with dec, classifying its errors
+func decodeWith(dec *json.Decoder, v any) error {
+	err := dec.Decode(v)
+
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		return errtrace.Wrap(fmt.Errorf("%w: limit is %d bytes", ErrBodyTooLarge, maxBytesErr.Limit))
+	}
+	if errors.Is(err, io.EOF) {
+		return errtrace.Wrap(fmt.Errorf("%w: %w", ErrValidation, ErrEmptyBody))
+	}
+	if err != nil {
+		return errtrace.Wrap(fmt.Errorf("%w: invalid JSON body: %w", ErrValidation, err))
+	}
+	return nil
+}
+
 // validateAndCreateUser demonstrates nested function calls with error wrapping
-func validateAndCreateUser(_ *http.Request) error {
-	// Randomly generate validation errors
-	// #nosec G404 -- This is a false positive
-	if !TestMode && rand.IntN(3) == 0 { //nolint:gosec
-		return errtrace.Wrap(fmt.Errorf("%w: required fields missing", ErrValidation))
+func (a *API) validateAndCreateUser(r *http.Request) (_ models.User, err error) {
+	defer a.breadcrumb(r.Context(), "validateAndCreateUser")(&err)
+
+	// Simulate validation errors
+	if a.faults.ShouldFail(OpValidateUser) {
+		return models.User{}, errtrace.Wrap(fmt.Errorf("%w: required fields missing", ErrValidation))
+	}
+
+	var input models.User
+	if err := a.decodeUserJSON(r, &input); err != nil {
+		return models.User{}, errtrace.Wrap(err)
+	}
+
+	if err := validateUser(input); err != nil {
+		return models.User{}, errtrace.Wrap(err)
 	}
 
 	// Try to process the user data
-	if err := processUserData(); err != nil {
+	if err := a.processUserData(r.Context()); err != nil {
 		// Wrap the lower-level error
-		return errtrace.Wrap(fmt.Errorf("user processing failed: %w", err))
+		return models.User{}, errtrace.Wrap(fmt.Errorf("user processing failed: %w", err))
 	}
 
-	return nil
+	user, err := a.users.Create(r.Context(), models.User{Name: input.Name})
+	if err != nil {
+		return models.User{}, errtrace.Wrap(fmt.Errorf("storing user failed: %w", err))
+	}
+
+	return user, nil
 }
 
-// processUserData is a nested function that might return errors
-func processUserData() error {
-	// Randomly fail this operation (but not in test mode)
-	// #nosec G404 -- This is a false positive
-	if !TestMode && rand.IntN(4) == 0 { //nolint:gosec
+// processUserData is a nested function that might return errors.
+// It gives up with the context's error once the request is canceled or times out.
+func (a *API) processUserData(ctx context.Context) (err error) {
+	defer a.breadcrumb(ctx, "processUserData")(&err)
+
+	// Simulate a failure of this operation
+	if a.faults.ShouldFail(OpProcessUser) {
 		return errtrace.Wrap(errors.New("database constraint violation"))
 	}
 
-	// Simulate slow processing (minimal in test mode)
-	if TestMode {
-		time.Sleep(time.Millisecond)
-	} else {
-		// #nosec G404 -- This is a false positive
-		time.Sleep(time.Millisecond * time.Duration(rand.IntN(100))) //nolint:gosec
+	// Simulate slow processing
+	if err := sleepContext(ctx, a.faults.Delay(OpProcessUser)); err != nil {
+		return errtrace.Wrap(fmt.Errorf("processing aborted: %w", err))
 	}
 
 	return nil
 }
 
-// GetUserHandler returns a specific user by ID
-func GetUserHandler(w http.ResponseWriter, r *http.Request) {
+// breadcrumb logs entering the nested operation op at debug level, and returns
+// a function that logs leaving it with the error it returned, if any, so a
+// failed request leaves a trail of the steps it went through.
+// Use it as: defer a.breadcrumb(ctx, "op")(&err)
+func (a *API) breadcrumb(ctx context.Context, op string) func(*error) {
+	logger := a.log(ctx)
+	logger.Debug("Entering operation", "operation", op)
+
+	start := time.Now()
+	return func(err *error) {
+		if *err != nil {
+			logger.Debug("Operation failed", "operation", op, "duration", time.Since(start), "error", *err)
+			return
+		}
+		logger.Debug("Leaving operation", "operation", op, "duration", time.Since(start))
+	}
+}
+
+// GetUser returns a specific user by ID
+func (a *API) GetUser(w http.ResponseWriter, r *http.Request) {
+	a.log(r.Context()).Info("Getting user by ID", "id", r.PathValue("id"), "path", r.URL.Path)
+
+	id, ok := a.pathUserID(w, r)
+	if !ok {
+		return
+	}
+
+	// Simulate database query that might fail, retrying transient failures
+	err := a.guardedQuery(r.Context(), id)
+	if errors.Is(err, ErrCircuitOpen) {
+		a.log(r.Context()).Warn("Database query short-circuited", "id", id, "error", err)
+
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(a.breaker.retryAfter().Seconds()))))
+		errorResponse(w, r, http.StatusServiceUnavailable, "Database temporarily unavailable")
+		return
+	}
+	if err != nil {
+		a.log(r.Context()).Error("Database query failed",
+			"id", id,
+			"error", err)
+		errorResponse(w, r, http.StatusInternalServerError, "Failed to retrieve user data")
+		return
+	}
+
+	user, err := a.users.Get(r.Context(), id)
+	if errors.Is(err, store.ErrNotFound) {
+		notFoundErr := fmt.Errorf("%w: ID %d", ErrUserNotFound, id)
+		a.log(r.Context()).Error("User not found",
+			"id", id,
+			"error", notFoundErr)
+
+		errorResponseFor(w, r, http.StatusNotFound, notFoundErr, fmt.Sprintf("User with ID %d not found", id))
+		return
+	}
+	if err != nil {
+		a.log(r.Context()).Error("Failed to get user",
+			"id", id,
+			"error", err)
+		errorResponse(w, r, http.StatusInternalServerError, "Failed to retrieve user data")
+		return
+	}
+
+	response := models.UserResponse{
+		Status: "success",
+		User:   &user,
+	}
+
+	// Let clients revalidate their cached copy with If-None-Match
+	respondWithETag(w, r, response)
+}
+
+// pathUserID parses the user ID in the request path, or resolves it as a
+// public ID when the API looks users up by their public IDs.
+// It sends a 400 response and returns false when the ID is invalid, and a 404
+// when no user has the public ID.
+func (a *API) pathUserID(w http.ResponseWriter, r *http.Request) (int, bool) {
 	// Get the ID from path parameter using Go 1.22's PathValue method
 	idStr := r.PathValue("id")
 
-	slog.Info("Getting user by ID", "id", idStr, "path", r.URL.Path)
+	if a.publicIDPaths {
+		return a.resolvePublicID(w, r, strings.TrimSpace(idStr))
+	}
 
-	// Convert string ID to integer
-	id, err := strconv.Atoi(idStr)
+	// Convert string ID to integer, tolerating surrounding whitespace
+	// that proxies or clients sometimes add to path segments
+	id, err := strconv.Atoi(strings.TrimSpace(idStr))
 	if err != nil {
 		// Log the error with its stack trace for debugging
 		stack := debug.Stack()
 		wrappedErr := fmt.Errorf("%w: %s is not a valid integer", ErrInvalidUserID, idStr)
 
-		slog.Error("Invalid user ID",
+		a.log(r.Context()).Error("Invalid user ID",
 			"id", idStr,
 			"error", wrappedErr,
 			"stack", string(stack))
 
-		errorResponse(w, http.StatusBadRequest, fmt.Sprintf("Invalid user ID: %s", idStr))
-		return
+		errorResponseFor(w, r, http.StatusBadRequest, wrappedErr, fmt.Sprintf("Invalid user ID: %s", idStr))
+		return 0, false
 	}
 
 	// Validate the ID
 	if id <= 0 {
 		wrappedErr := fmt.Errorf("%w: ID must be positive", ErrInvalidUserID)
-		slog.Error("Invalid user ID value",
+		a.log(r.Context()).Error("Invalid user ID value",
 			"id", id,
 			"error", wrappedErr)
 
-		errorResponse(w, http.StatusBadRequest, fmt.Sprintf("Invalid user ID: %d", id))
-		return
+		errorResponseFor(w, r, http.StatusBadRequest, wrappedErr, fmt.Sprintf("Invalid user ID: %d", id))
+		return 0, false
 	}
 
-	// Simulate database query that might fail
-	if err := queryDatabase(id); err != nil {
-		slog.Error("Database query failed",
-			"id", id,
+	return id, true
+}
+
+// resolvePublicID returns the ID of the user with the given public ID.
+// It sends an error response and returns false when there is no such user or
+// the lookup fails.
+func (a *API) resolvePublicID(w http.ResponseWriter, r *http.Request, publicID string) (int, bool) {
+	id, err := a.users.Resolve(r.Context(), publicID)
+	if errors.Is(err, store.ErrNotFound) {
+		notFoundErr := fmt.Errorf("%w: public ID %s", ErrUserNotFound, publicID)
+		a.log(r.Context()).Error("User not found",
+			"public_id", publicID,
+			"error", notFoundErr)
+
+		errorResponseFor(w, r, http.StatusNotFound, notFoundErr, fmt.Sprintf("User with ID %s not found", publicID))
+		return 0, false
+	}
+	if err != nil {
+		a.log(r.Context()).Error("Failed to resolve public user ID",
+			"public_id", publicID,
 			"error", err)
-		errorResponse(w, http.StatusInternalServerError, "Failed to retrieve user data")
-		return
+		errorResponse(w, r, http.StatusInternalServerError, "Failed to retrieve user data")
+		return 0, false
 	}
 
-	// Randomly generate "not found" errors for valid IDs over 10 (but not in test mode)
-	// #nosec G404 -- This is a false positive
-	if !TestMode && id > 10 && rand.IntN(2) == 0 { //nolint:gosec
-		notFoundErr := fmt.Errorf("%w: ID %d", ErrUserNotFound, id)
-		slog.Error("User not found",
-			"id", id,
-			"error", notFoundErr)
+	return id, true
+}
 
-		errorResponse(w, http.StatusNotFound, fmt.Sprintf("User with ID %d not found", id))
+// Reset clears the user store and resets its ID counter.
+// It is meant for demos and integration tests, so unless debug endpoints are
+// enabled it responds with 404 as if the route did not exist.
+func (a *API) Reset(w http.ResponseWriter, r *http.Request) {
+	if !a.debugEndpoints {
+		errorResponse(w, r, http.StatusNotFound, "Not found")
 		return
 	}
 
-	// In a real application, we would get this from a database
-	user := models.User{
-		ID:   id,
-		Name: fmt.Sprintf("User %d", id),
+	if err := a.users.Reset(r.Context()); err != nil {
+		a.log(r.Context()).Error("Failed to reset user store", "error", err)
+		errorResponse(w, r, http.StatusInternalServerError, "Failed to reset user store")
+		return
 	}
+	a.log(r.Context()).Warn("User store reset", "remote_addr", r.RemoteAddr)
 
 	response := models.UserResponse{
-		Status: "success",
-		User:   &user,
+		Status:  "success",
+		Message: "User store reset",
 	}
 
-	jsonResponse(w, http.StatusOK, response)
+	respond(w, r, http.StatusOK, response)
 }
 
 // Common user errors
@@ -233,59 +456,116 @@ var (
 	ErrInvalidUserID = errors.New("invalid user ID")
 )
 
-// queryDatabase simulates a database query that might fail
-func queryDatabase(id int) error {
-	// Simulate different database errors (but not in test mode)
-	if TestMode {
-		return nil
+// queryDatabase simulates a database query that might fail.
+// Timeouts and execution failures are transient and worth retrying, while
+// constraint violations are not. It gives up with the context's error once the
+// request is canceled or times out.
+func (a *API) queryDatabase(ctx context.Context, id int) error {
+	if err := ctx.Err(); err != nil {
+		return errtrace.Wrap(fmt.Errorf("query aborted: %w", err))
 	}
 
-	// Use id in random error generation
-	// #nosec G404 -- This is a false positive
-	errorChance := rand.IntN(10) //nolint:gosec
-
-	// IDs divisible by 5 have a higher chance of connection timeout
-	if id%5 == 0 && errorChance < 3 {
-		return errtrace.Wrap(errors.New("connection timeout"))
+	// IDs divisible by 5 are prone to connection timeouts
+	if id%5 == 0 && a.faults.ShouldFail(OpQueryTimeout) {
+		a.countDBError(OpQueryTimeout)
+		return errtrace.Wrap(transient(errors.New("connection timeout")))
 	}
 
-	// IDs divisible by 3 have a higher chance of query execution failure
-	if id%3 == 0 && errorChance < 3 {
-		return errtrace.Wrap(errors.New("query execution failed"))
+	// IDs divisible by 3 are prone to query execution failures
+	if id%3 == 0 && a.faults.ShouldFail(OpQueryFailed) {
+		a.countDBError(OpQueryFailed)
+		return errtrace.Wrap(transient(errors.New("query execution failed")))
 	}
 
 	// Very high IDs might cause a constraint error
-	if id > 1000 && errorChance < 2 {
+	if id > 1000 && a.faults.ShouldFail(OpQueryConstraint) {
+		a.countDBError(OpQueryConstraint)
 		return errtrace.Wrap(errors.New("primary key constraint violation"))
 	}
 
 	return nil
 }
 
-// jsonResponse sends a JSON response
+// guardedQuery runs queryDatabase with retries, through the circuit breaker if
+// there is one. The breaker records the outcome once the retries are over, so a
+// single request counts as at most one failure however many attempts it made.
+func (a *API) guardedQuery(ctx context.Context, id int) error {
+	query := func(ctx context.Context) error {
+		return a.withRetries(ctx, "query_database", func(ctx context.Context) error {
+			return a.queryDatabase(ctx, id)
+		})
+	}
+	if a.breaker == nil {
+		return query(ctx)
+	}
+	return a.breaker.Do(ctx, query)
+}
+
+// countDBError counts a simulated database error of the given type,
+// if database error metrics are enabled
+func (a *API) countDBError(errType string) {
+	if a.dbErrors != nil {
+		a.dbErrors.Add(errType, 1)
+	}
+}
+
+// jsonResponse sends a JSON response.
+// The body is encoded up front so that Content-Length is accurate, which also
+// keeps HEAD responses (whose body is discarded) consistent with GET.
 func jsonResponse(w http.ResponseWriter, status int, data interface{}) {
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(status)
+	encodeJSON(w, status, data, false)
+}
 
-	if err := json.NewEncoder(w).Encode(data); err != nil {
+// encodeJSON sends a JSON response, indented for humans when pretty is set
+func encodeJSON(w http.ResponseWriter, status int, data interface{}, pretty bool) {
+	buf := getBuffer()
+	defer putBuffer(buf)
+
+	enc := json.NewEncoder(buf)
+	if pretty {
+		enc.SetIndent("", "  ")
+	}
+	if err := encodeSafely(func() error { return enc.Encode(data) }); err != nil {
 		slog.Error("Failed to encode JSON response", "error", err)
-		http.Error(w, "Failed to generate response", http.StatusInternalServerError)
+		encodeJSON(w, http.StatusInternalServerError, encodeFailure, false)
+		return
 	}
+
+	writeBody(w, status, "application/json", buf)
 }
 
-// JSONResponse is an exported version of jsonResponse that can be used by other packages
-func JSONResponse(w http.ResponseWriter, status int, data interface{}) {
-	jsonResponse(w, status, data)
+// encodeFailure is sent in place of a response that couldn't be encoded.
+// Responses are encoded into a buffer before anything is written, so the
+// client still gets a well-formed error rather than a truncated body.
+var encodeFailure = models.ErrorResponse{
+	Status:  "error",
+	Code:    statusCode(http.StatusInternalServerError),
+	Message: "Failed to generate response",
+}
+
+// encodeSafely runs encode, turning a panic, such as one raised by a faulty
+// MarshalJSON method, into an error
+func encodeSafely(encode func() error) (err error) {
+	defer func() {
+		if rec := recover(); rec != nil {
+			err = fmt.Errorf("encoder panicked: %v", rec)
+		}
+	}()
+	return encode()
 }
 
-// errorResponse sends an error response
-func errorResponse(w http.ResponseWriter, status int, message string) {
-	slog.Warn("Sending error response", "status", status, "message", message)
+// writeBody sends an encoded response body with its Content-Type and Content-Length
+func writeBody(w http.ResponseWriter, status int, contentType string, buf *bytes.Buffer) {
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Length", strconv.Itoa(buf.Len()))
+	w.WriteHeader(status)
 
-	response := map[string]string{
-		"status":  "error",
-		"message": message,
+	if _, err := w.Write(buf.Bytes()); err != nil {
+		slog.Debug("Failed to write response", "error", err)
 	}
+}
 
-	jsonResponse(w, status, response)
+// JSONResponse is an exported version of jsonResponse that can be used by other packages
+func JSONResponse(w http.ResponseWriter, status int, data interface{}) {
+	jsonResponse(w, status, data)
 }
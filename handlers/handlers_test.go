@@ -1,28 +1,138 @@
 package handlers
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"log/slog"
 	"net/http"
 	"net/http/httptest"
-	"os"
+	"slices"
 	"strconv"
 	"strings"
 	"testing"
 
 	"github.com/kakkoyun/demo-web-service/models"
+	"github.com/kakkoyun/demo-web-service/store"
 )
 
-// TestMain sets up the testing environment
-func TestMain(m *testing.M) {
-	// Enable test mode to disable random failures
-	TestMode = true
+// newTestAPI creates an API without simulated failures, backed by a memory store
+// seeded with the given users, or with the demo users when none are given
+func newTestAPI(t *testing.T, opts []Option, seed ...models.User) (*API, *store.MemoryStore) {
+	t.Helper()
 
-	// Run all tests
-	exitCode := m.Run()
+	users := store.NewDemoStore()
+	if len(seed) > 0 {
+		users = store.NewMemoryStore(seed...)
+	}
+
+	opts = append([]Option{WithFaultInjector(NoFaults{})}, opts...)
+	return NewAPI(users, nil, opts...), users
+}
+
+// fakeStore is a UserStore whose results are set by the test
+type fakeStore struct {
+	err   error
+	users []models.User
+	calls int
+}
+
+func (f *fakeStore) List(_ context.Context) ([]models.User, error) {
+	f.calls++
+	return f.users, f.err
+}
+
+func (f *fakeStore) ListAfter(_ context.Context, after, limit int) ([]models.User, error) {
+	f.calls++
+	var users []models.User
+	for _, user := range f.users {
+		if user.ID > after && len(users) < limit {
+			users = append(users, user)
+		}
+	}
+	return users, f.err
+}
+
+func (f *fakeStore) Count(_ context.Context, name string) (int, error) {
+	f.calls++
+	count := 0
+	for _, user := range f.users {
+		if strings.Contains(strings.ToLower(user.Name), strings.ToLower(name)) {
+			count++
+		}
+	}
+	return count, f.err
+}
+
+func (f *fakeStore) Get(_ context.Context, id int) (models.User, error) {
+	f.calls++
+	if f.err != nil {
+		return models.User{}, f.err
+	}
+	for _, user := range f.users {
+		if user.ID == id {
+			return user, nil
+		}
+	}
+	return models.User{}, store.ErrNotFound
+}
+
+func (f *fakeStore) Resolve(_ context.Context, publicID string) (int, error) {
+	f.calls++
+	if f.err != nil {
+		return 0, f.err
+	}
+	for _, user := range f.users {
+		if user.PublicID == publicID {
+			return user.ID, nil
+		}
+	}
+	return 0, store.ErrNotFound
+}
+
+func (f *fakeStore) Create(_ context.Context, user models.User) (models.User, error) {
+	f.calls++
+	if f.err != nil {
+		return models.User{}, f.err
+	}
+	user.ID = len(f.users) + 1
+	f.users = append(f.users, user)
+	return user, nil
+}
+
+func (f *fakeStore) Update(_ context.Context, user models.User) (models.User, error) {
+	f.calls++
+	if f.err != nil {
+		return models.User{}, f.err
+	}
+	for i := range f.users {
+		if f.users[i].ID == user.ID {
+			f.users[i] = user
+			return user, nil
+		}
+	}
+	return models.User{}, store.ErrNotFound
+}
 
-	// Exit with the same code
-	os.Exit(exitCode)
+func (f *fakeStore) Import(_ context.Context, users []models.User, policy store.DuplicatePolicy) (models.ImportSummary, error) {
+	f.calls++
+	if f.err != nil {
+		return models.ImportSummary{}, f.err
+	}
+	f.users = append(f.users, users...)
+	return models.ImportSummary{Policy: string(policy), Imported: len(users)}, nil
+}
+
+func (f *fakeStore) Ping(_ context.Context) error {
+	return f.err
+}
+
+func (f *fakeStore) Reset(_ context.Context) error {
+	f.calls++
+	f.users = nil
+	return f.err
 }
 
 func TestGetUsersHandler(t *testing.T) {
@@ -34,7 +144,8 @@ func TestGetUsersHandler(t *testing.T) {
 
 	// Create a ResponseRecorder to record the response
 	rr := httptest.NewRecorder()
-	handler := http.HandlerFunc(GetUsersHandler)
+	api, _ := newTestAPI(t, nil)
+	handler := http.HandlerFunc(api.GetUsers)
 
 	// Serve the request
 	handler.ServeHTTP(rr, req)
@@ -51,7 +162,7 @@ func TestGetUsersHandler(t *testing.T) {
 	}
 
 	// Parse the response body
-	var response models.UserResponse
+	var response models.UserListResponse
 	if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
 		t.Errorf("could not parse response body: %v", err)
 	}
@@ -65,6 +176,9 @@ func TestGetUsersHandler(t *testing.T) {
 	if len(response.Users) != 2 {
 		t.Errorf("handler returned wrong number of users: got %v want %v", len(response.Users), 2)
 	}
+	if response.Count != 2 {
+		t.Errorf("handler returned wrong count: got %v want %v", response.Count, 2)
+	}
 
 	// Check the first user's fields
 	if response.Users[0].ID != 1 || response.Users[0].Name != "John Doe" {
@@ -72,6 +186,76 @@ func TestGetUsersHandler(t *testing.T) {
 	}
 }
 
+func TestCountUsersHandler(t *testing.T) {
+	testCases := []struct {
+		name          string
+		query         string
+		expectedCount int
+	}{
+		{name: "All Users", query: "", expectedCount: 3},
+		{name: "Filtered By Name", query: "?name=doe", expectedCount: 2},
+		{name: "No Matches", query: "?name=nobody", expectedCount: 0},
+	}
+
+	api, _ := newTestAPI(t, nil,
+		models.User{ID: 1, Name: "John Doe"},
+		models.User{ID: 2, Name: "Jane Doe"},
+		models.User{ID: 3, Name: "Jane Smith"},
+	)
+	handler := api.Routes()
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/api/users/count"+tc.query, nil)
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			if status := rr.Code; status != http.StatusOK {
+				t.Fatalf("handler returned wrong status code: got %v want %v", status, http.StatusOK)
+			}
+
+			var response models.UserCountResponse
+			if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+				t.Fatalf("could not parse response body: %v", err)
+			}
+			if response.Status != "success" {
+				t.Errorf("handler returned wrong status: got %v want %v", response.Status, "success")
+			}
+			if response.Count != tc.expectedCount {
+				t.Errorf("handler returned wrong count: got %v want %v", response.Count, tc.expectedCount)
+			}
+		})
+	}
+}
+
+func TestGetUsersEmptyStore(t *testing.T) {
+	api := NewAPI(store.NewMemoryStore(), nil, WithFaultInjector(NoFaults{}))
+
+	req := httptest.NewRequest("GET", "/api/users", nil)
+	rr := httptest.NewRecorder()
+	api.GetUsers(rr, req)
+
+	if status := rr.Code; status != http.StatusOK {
+		t.Errorf("handler returned wrong status code: got %v want %v", status, http.StatusOK)
+	}
+
+	// Decode into a map to see exactly which fields were sent
+	var response map[string]any
+	if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+		t.Fatalf("could not parse response body: %v", err)
+	}
+
+	if response["status"] != "success" {
+		t.Errorf("handler returned wrong status: got %v want %v", response["status"], "success")
+	}
+	if count, ok := response["count"].(float64); !ok || count != 0 {
+		t.Errorf("handler returned wrong count: got %v want %v", response["count"], 0)
+	}
+	if users, ok := response["users"].([]any); !ok || len(users) != 0 {
+		t.Errorf("handler returned wrong users: got %v want an empty array", response["users"])
+	}
+}
+
 // TestGetUserHandlerDirect tests the GetUserHandler handler
 // Note: Because of Go 1.22's PathValue method, we're using a custom test
 // that extracts the ID from the URL path and passes it to a simplified version
@@ -108,12 +292,12 @@ func TestGetUserHandlerDirect(t *testing.T) {
 
 			// Create a test handler that simulates the functionality of GetUserHandler
 			// but accepts the ID directly instead of using PathValue
-			testHandler := func(w http.ResponseWriter, _ *http.Request) {
+			testHandler := func(w http.ResponseWriter, r *http.Request) {
 				idStr := tc.userID // Directly use the test case ID
 
 				id, err := strconv.Atoi(idStr)
 				if err != nil {
-					errorResponse(w, http.StatusBadRequest, "Invalid user ID")
+					errorResponse(w, r, http.StatusBadRequest, "Invalid user ID")
 					return
 				}
 
@@ -175,7 +359,8 @@ func TestCreateUserHandler(t *testing.T) {
 
 	// Create a ResponseRecorder to record the response
 	rr := httptest.NewRecorder()
-	handler := http.HandlerFunc(CreateUserHandler)
+	api, _ := newTestAPI(t, nil)
+	handler := http.HandlerFunc(api.CreateUser)
 
 	// Serve the request
 	handler.ServeHTTP(rr, req)
@@ -206,3 +391,488 @@ func TestCreateUserHandler(t *testing.T) {
 		t.Errorf("handler returned wrong user ID: got %v want %v", response.User.ID, 3)
 	}
 }
+
+func TestCreateUserLogsBreadcrumbs(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	api := NewAPI(store.NewDemoStore(), logger, WithFaultInjector(stubFaults{OpProcessUser: true}))
+
+	req := httptest.NewRequest("POST", "/api/users", strings.NewReader(`{"name":"New User"}`))
+	rr := httptest.NewRecorder()
+	api.Routes().ServeHTTP(rr, req)
+
+	if status := rr.Code; status != http.StatusBadRequest {
+		t.Fatalf("handler returned wrong status code: got %v want %v", status, http.StatusBadRequest)
+	}
+
+	// Each nested step logs entering and failing, innermost failure first
+	var trail []string
+	for line := range bytes.Lines(buf.Bytes()) {
+		var entry map[string]any
+		if err := json.Unmarshal(line, &entry); err != nil {
+			t.Fatalf("could not parse log line %q: %v", line, err)
+		}
+		if op, ok := entry["operation"].(string); ok {
+			if entry["level"] != "DEBUG" {
+				t.Errorf("breadcrumb for %s logged at %v, want DEBUG", op, entry["level"])
+			}
+			trail = append(trail, fmt.Sprintf("%s %s", entry["msg"], op))
+		}
+	}
+
+	expected := []string{
+		"Entering operation validateAndCreateUser",
+		"Entering operation processUserData",
+		"Operation failed processUserData",
+		"Operation failed validateAndCreateUser",
+	}
+	if !slices.Equal(trail, expected) {
+		t.Errorf("wrong breadcrumb trail:\ngot  %q\nwant %q", trail, expected)
+	}
+}
+
+func TestCreateUserHandlerEmptyBody(t *testing.T) {
+	testCases := []struct {
+		name           string
+		body           string
+		chunked        bool
+		expectedStatus int
+	}{
+		{name: "Empty", body: "", expectedStatus: http.StatusBadRequest},
+		{name: "Chunked Empty", body: "", chunked: true, expectedStatus: http.StatusBadRequest},
+		{name: "Chunked Whitespace", body: " \n", chunked: true, expectedStatus: http.StatusBadRequest},
+		{name: "Chunked User", body: `{"name":"Chunked User"}`, chunked: true, expectedStatus: http.StatusCreated},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest("POST", "/api/users", strings.NewReader(tc.body))
+			if tc.chunked {
+				// Chunked requests don't announce their length
+				req.ContentLength = -1
+				req.TransferEncoding = []string{"chunked"}
+			}
+
+			rr := httptest.NewRecorder()
+			api, _ := newTestAPI(t, nil)
+			api.CreateUser(rr, req)
+
+			if status := rr.Code; status != tc.expectedStatus {
+				t.Fatalf("handler returned wrong status code: got %v want %v", status, tc.expectedStatus)
+			}
+
+			if tc.expectedStatus == http.StatusBadRequest {
+				var response map[string]string
+				if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+					t.Fatalf("could not parse response body: %v", err)
+				}
+				if !strings.Contains(response["message"], ErrEmptyBody.Error()) {
+					t.Errorf("handler returned wrong message: got %q want it to mention %q", response["message"], ErrEmptyBody)
+				}
+			}
+		})
+	}
+}
+
+func TestCreateUserHandlerBodyTooLarge(t *testing.T) {
+	testCases := []struct {
+		name           string
+		body           string
+		expectedStatus int
+	}{
+		{name: "Oversized", body: `{"name":"` + strings.Repeat("a", 100) + `"}`, expectedStatus: http.StatusRequestEntityTooLarge},
+		{name: "Within Limit", body: `{"name":"Small User"}`, expectedStatus: http.StatusCreated},
+		{name: "Invalid JSON Within Limit", body: `{"name":`, expectedStatus: http.StatusBadRequest},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			api, _ := newTestAPI(t, []Option{WithMaxBodyBytes(64)})
+
+			req := httptest.NewRequest("POST", "/api/users", strings.NewReader(tc.body))
+			rr := httptest.NewRecorder()
+			api.Routes().ServeHTTP(rr, req)
+
+			if status := rr.Code; status != tc.expectedStatus {
+				t.Errorf("handler returned wrong status code: got %v want %v", status, tc.expectedStatus)
+			}
+		})
+	}
+}
+
+func TestGetUserHandlerIDParsing(t *testing.T) {
+	testCases := []struct {
+		name           string
+		userID         string
+		expectedStatus int
+		expectedID     int
+	}{
+		{name: "Plain ID", userID: "5", expectedStatus: http.StatusOK, expectedID: 5},
+		{name: "Leading and Trailing Spaces", userID: " 5 ", expectedStatus: http.StatusOK, expectedID: 5},
+		{name: "Tabs and Newlines", userID: "\t7\n", expectedStatus: http.StatusOK, expectedID: 7},
+		{name: "Letters", userID: "abc", expectedStatus: http.StatusBadRequest},
+		{name: "Inner Space", userID: "1 2", expectedStatus: http.StatusBadRequest},
+		{name: "Only Whitespace", userID: "   ", expectedStatus: http.StatusBadRequest},
+		{name: "Decimal", userID: "1.5", expectedStatus: http.StatusBadRequest},
+		{name: "Zero", userID: " 0 ", expectedStatus: http.StatusBadRequest},
+		{name: "Negative", userID: "-3", expectedStatus: http.StatusBadRequest},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req, err := http.NewRequest("GET", "/api/users/id", nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			req.SetPathValue("id", tc.userID)
+
+			api, _ := newTestAPI(t, nil, models.User{ID: 5, Name: "User 5"}, models.User{ID: 7, Name: "User 7"})
+			rr := httptest.NewRecorder()
+			api.GetUser(rr, req)
+
+			if status := rr.Code; status != tc.expectedStatus {
+				t.Fatalf("handler returned wrong status code: got %v want %v", status, tc.expectedStatus)
+			}
+
+			if tc.expectedStatus != http.StatusOK {
+				return
+			}
+
+			var response models.UserResponse
+			if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+				t.Fatalf("could not parse response body: %v", err)
+			}
+
+			if response.User == nil || response.User.ID != tc.expectedID {
+				t.Errorf("handler returned wrong user: got %+v want ID %v", response.User, tc.expectedID)
+			}
+		})
+	}
+}
+
+func TestGetUserByPublicID(t *testing.T) {
+	users := store.NewMemoryStoreWithIDs(store.UUIDs{}, models.User{ID: 5, Name: "User 5"})
+	api := NewAPI(users, nil, WithFaultInjector(NoFaults{}), WithPublicIDPaths(true))
+
+	user, err := users.Get(context.Background(), 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	testCases := []struct {
+		name           string
+		userID         string
+		expectedStatus int
+	}{
+		{name: "Public ID", userID: user.PublicID, expectedStatus: http.StatusOK},
+		{name: "Internal ID", userID: "5", expectedStatus: http.StatusNotFound},
+		{name: "Unknown Public ID", userID: "00000000-0000-4000-8000-000000000000", expectedStatus: http.StatusNotFound},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			api.Routes().ServeHTTP(rr, httptest.NewRequest("GET", "/api/users/"+tc.userID, nil))
+
+			if status := rr.Code; status != tc.expectedStatus {
+				t.Fatalf("handler returned wrong status code: got %v want %v", status, tc.expectedStatus)
+			}
+			if tc.expectedStatus != http.StatusOK {
+				return
+			}
+
+			var response models.UserResponse
+			if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+				t.Fatalf("could not parse response body: %v", err)
+			}
+			if response.User == nil || response.User.PublicID != user.PublicID {
+				t.Errorf("handler returned wrong user: got %+v want public ID %v", response.User, user.PublicID)
+			}
+		})
+	}
+}
+
+func TestResetHandler(t *testing.T) {
+	api, _ := newTestAPI(t, []Option{WithDebugEndpoints(true)})
+
+	// Reset the store
+	req := httptest.NewRequest("POST", "/api/admin/reset", nil)
+	rr := httptest.NewRecorder()
+	api.Reset(rr, req)
+
+	if status := rr.Code; status != http.StatusOK {
+		t.Fatalf("handler returned wrong status code: got %v want %v", status, http.StatusOK)
+	}
+
+	// The users list is now empty
+	rr = httptest.NewRecorder()
+	api.GetUsers(rr, httptest.NewRequest("GET", "/api/users", nil))
+
+	var response models.UserResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+		t.Fatalf("could not parse response body: %v", err)
+	}
+	if len(response.Users) != 0 {
+		t.Errorf("store was not cleared: got %v users", len(response.Users))
+	}
+
+	// The ID counter starts over
+	req = httptest.NewRequest("POST", "/api/users", strings.NewReader(`{"name":"First User"}`))
+	rr = httptest.NewRecorder()
+	api.CreateUser(rr, req)
+
+	if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+		t.Fatalf("could not parse response body: %v", err)
+	}
+	if response.User == nil || response.User.ID != 1 {
+		t.Errorf("ID counter was not reset: got %+v", response.User)
+	}
+}
+
+func TestResetHandlerDisabled(t *testing.T) {
+	api, s := newTestAPI(t, nil, models.User{ID: 1, Name: "John Doe"})
+
+	req := httptest.NewRequest("POST", "/api/admin/reset", nil)
+	rr := httptest.NewRecorder()
+	api.Reset(rr, req)
+
+	if status := rr.Code; status != http.StatusNotFound {
+		t.Errorf("handler returned wrong status code: got %v want %v", status, http.StatusNotFound)
+	}
+
+	users, err := s.List(req.Context())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(users) != 1 {
+		t.Errorf("store was modified while reset was disabled: got %v users", len(users))
+	}
+}
+
+func TestAPIWithFakeStore(t *testing.T) {
+	t.Run("Lists Users From Store", func(t *testing.T) {
+		fake := &fakeStore{users: []models.User{{ID: 9, Name: "Fake User"}}}
+		api := NewAPI(fake, nil, WithFaultInjector(NoFaults{}))
+
+		rr := httptest.NewRecorder()
+		api.GetUsers(rr, httptest.NewRequest("GET", "/api/users", nil))
+
+		var response models.UserResponse
+		if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+			t.Fatalf("could not parse response body: %v", err)
+		}
+		if len(response.Users) != 1 || response.Users[0].Name != "Fake User" {
+			t.Errorf("handler returned wrong users: got %+v", response.Users)
+		}
+	})
+
+	t.Run("Store Error Returns 500", func(t *testing.T) {
+		fake := &fakeStore{err: errors.New("store unavailable")}
+		api := NewAPI(fake, nil, WithFaultInjector(NoFaults{}))
+
+		rr := httptest.NewRecorder()
+		api.GetUsers(rr, httptest.NewRequest("GET", "/api/users", nil))
+
+		if status := rr.Code; status != http.StatusInternalServerError {
+			t.Errorf("handler returned wrong status code: got %v want %v", status, http.StatusInternalServerError)
+		}
+	})
+
+	t.Run("Missing User Returns 404", func(t *testing.T) {
+		fake := &fakeStore{}
+		api := NewAPI(fake, nil, WithFaultInjector(NoFaults{}))
+
+		req := httptest.NewRequest("GET", "/api/users/4", nil)
+		req.SetPathValue("id", "4")
+		rr := httptest.NewRecorder()
+		api.GetUser(rr, req)
+
+		if status := rr.Code; status != http.StatusNotFound {
+			t.Errorf("handler returned wrong status code: got %v want %v", status, http.StatusNotFound)
+		}
+		if fake.calls != 1 {
+			t.Errorf("store was called %v times, want 1", fake.calls)
+		}
+	})
+
+	t.Run("Created User Is Stored", func(t *testing.T) {
+		fake := &fakeStore{}
+		api := NewAPI(fake, nil, WithFaultInjector(NoFaults{}))
+
+		req := httptest.NewRequest("POST", "/api/users", strings.NewReader(`{"name":"Stored User"}`))
+		rr := httptest.NewRecorder()
+		api.CreateUser(rr, req)
+
+		if status := rr.Code; status != http.StatusCreated {
+			t.Fatalf("handler returned wrong status code: got %v want %v", status, http.StatusCreated)
+		}
+		if len(fake.users) != 1 || fake.users[0].Name != "Stored User" {
+			t.Errorf("user was not stored: got %+v", fake.users)
+		}
+	})
+}
+
+func TestGetUserDistinguishesNotFoundFromStoreErrors(t *testing.T) {
+	testCases := []struct {
+		err            error
+		name           string
+		expectedCode   string
+		expectedStatus int
+	}{
+		{name: "Not Found", err: store.ErrNotFound, expectedStatus: http.StatusNotFound, expectedCode: CodeUserNotFound},
+		{name: "Wrapped Not Found", err: fmt.Errorf("query user 4: %w", store.ErrNotFound), expectedStatus: http.StatusNotFound, expectedCode: CodeUserNotFound},
+		{name: "Query Error", err: errors.New("connection reset by peer"), expectedStatus: http.StatusInternalServerError, expectedCode: "INTERNAL_SERVER_ERROR"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			fake := &fakeStore{err: tc.err}
+			api := NewAPI(fake, nil, WithFaultInjector(NoFaults{}))
+
+			req := httptest.NewRequest("GET", "/api/users/4", nil)
+			rr := httptest.NewRecorder()
+			api.Routes().ServeHTTP(rr, req)
+
+			if status := rr.Code; status != tc.expectedStatus {
+				t.Fatalf("handler returned wrong status code: got %v want %v", status, tc.expectedStatus)
+			}
+
+			var response models.ErrorResponse
+			if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+				t.Fatalf("could not parse response body: %v", err)
+			}
+			if response.Code != tc.expectedCode {
+				t.Errorf("handler returned wrong code: got %v want %v", response.Code, tc.expectedCode)
+			}
+			// Store errors are logged, not shown to clients
+			if strings.Contains(response.Message, "connection reset") {
+				t.Errorf("handler leaked the store error: %v", response.Message)
+			}
+		})
+	}
+}
+
+func TestRoutes(t *testing.T) {
+	api, _ := newTestAPI(t, nil)
+	server := httptest.NewServer(api.Routes())
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "/api/users/2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	var response models.UserResponse
+	if err := json.NewDecoder(resp.Body).Decode(&response); err != nil {
+		t.Fatalf("could not parse response body: %v", err)
+	}
+
+	if response.User == nil || response.User.Name != "Jane Smith" {
+		t.Errorf("routes returned wrong user: got %+v", response.User)
+	}
+}
+
+func TestStreaming(t *testing.T) {
+	api, _ := newTestAPI(t, nil)
+
+	testCases := []struct {
+		method    string
+		path      string
+		accept    string
+		streaming bool
+	}{
+		{method: "GET", path: "/api/users/events", streaming: true},
+		{method: "GET", path: "/api/users/events", accept: "text/event-stream", streaming: true},
+		{method: "GET", path: "/api/users/stream", accept: "text/event-stream"},
+		{method: "POST", path: "/api/users/import", accept: "text/event-stream"},
+		{method: "POST", path: "/api/users/events"},
+	}
+
+	for _, tc := range testCases {
+		req := httptest.NewRequest(tc.method, tc.path, nil)
+		if tc.accept != "" {
+			req.Header.Set("Accept", tc.accept)
+		}
+
+		if got := api.Streaming(req); got != tc.streaming {
+			t.Errorf("%s %s with Accept %q: got streaming %v want %v", tc.method, tc.path, tc.accept, got, tc.streaming)
+		}
+	}
+}
+
+func TestJSONResponseUnencodableValue(t *testing.T) {
+	rr := httptest.NewRecorder()
+	JSONResponse(rr, http.StatusOK, map[string]any{"status": "success", "updates": make(chan int)})
+
+	if status := rr.Code; status != http.StatusInternalServerError {
+		t.Errorf("handler returned wrong status code: got %v want %v", status, http.StatusInternalServerError)
+	}
+	if length := rr.Header().Get("Content-Length"); length != strconv.Itoa(rr.Body.Len()) {
+		t.Errorf("handler returned wrong content length: got %v want %v", length, rr.Body.Len())
+	}
+
+	// The body must be the error alone, with nothing of the failed response before it
+	var response models.ErrorResponse
+	dec := json.NewDecoder(rr.Body)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&response); err != nil {
+		t.Fatalf("could not parse response body: %v", err)
+	}
+	if dec.More() {
+		t.Error("handler returned trailing data after the error")
+	}
+	if response.Status != "error" || response.Code != "INTERNAL_SERVER_ERROR" {
+		t.Errorf("handler returned wrong error: got %+v", response)
+	}
+}
+
+func TestCreateUserHandlerFieldErrors(t *testing.T) {
+	testCases := []struct {
+		name     string
+		body     string
+		expected []models.FieldError
+	}{
+		{
+			name: "Empty Name And Negative ID",
+			body: `{"id":-1,"name":" "}`,
+			expected: []models.FieldError{
+				{Field: "id", Message: "must not be negative"},
+				{Field: "name", Message: "is required"},
+			},
+		},
+		{
+			name: "Long Name With Control Characters",
+			body: `{"name":"` + strings.Repeat("a", models.MaxNameLength) + `\u0007"}`,
+			expected: []models.FieldError{
+				{Field: "name", Message: fmt.Sprintf("must be at most %d characters", models.MaxNameLength)},
+				{Field: "name", Message: "must not contain control characters"},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			api, _ := newTestAPI(t, nil)
+
+			req := httptest.NewRequest("POST", "/api/users", strings.NewReader(tc.body))
+			rr := httptest.NewRecorder()
+			api.Routes().ServeHTTP(rr, req)
+
+			if status := rr.Code; status != http.StatusUnprocessableEntity {
+				t.Fatalf("handler returned wrong status code: got %v want %v", status, http.StatusUnprocessableEntity)
+			}
+
+			var response models.ErrorResponse
+			if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+				t.Fatalf("could not parse response body: %v", err)
+			}
+			if response.Code != CodeValidation {
+				t.Errorf("handler returned wrong error code: got %v want %v", response.Code, CodeValidation)
+			}
+			if !slices.Equal(response.Details, tc.expected) {
+				t.Errorf("handler returned wrong field errors: got %v want %v", response.Details, tc.expected)
+			}
+		})
+	}
+}
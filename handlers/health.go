@@ -0,0 +1,212 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kakkoyun/demo-web-service/models"
+)
+
+// readinessCheckTimeout bounds how long all readiness checks may take together
+const readinessCheckTimeout = 2 * time.Second
+
+// ReadinessChecker is implemented by dependencies that can report whether
+// they are ready to serve traffic
+type ReadinessChecker interface {
+	Check(ctx context.Context) error
+}
+
+// ReadinessCheckerFunc adapts an ordinary function to a ReadinessChecker
+type ReadinessCheckerFunc func(ctx context.Context) error
+
+// Check calls f(ctx)
+func (f ReadinessCheckerFunc) Check(ctx context.Context) error {
+	return f(ctx)
+}
+
+// ReadinessPolicy decides how failing critical checks are aggregated
+type ReadinessPolicy string
+
+// Supported readiness policies
+const (
+	// FailIfAny reports not ready when any critical check fails
+	FailIfAny ReadinessPolicy = "fail-if-any"
+	// FailIfAll reports not ready only when every critical check fails,
+	// and degraded while some of them fail
+	FailIfAll ReadinessPolicy = "fail-if-all"
+)
+
+// ParseReadinessPolicy parses a readiness policy name, ignoring case
+func ParseReadinessPolicy(s string) (ReadinessPolicy, error) {
+	switch p := ReadinessPolicy(strings.ToLower(strings.TrimSpace(s))); p {
+	case FailIfAny, FailIfAll:
+		return p, nil
+	default:
+		return "", fmt.Errorf("unknown readiness policy %q: must be one of fail-if-any or fail-if-all", s)
+	}
+}
+
+// Readiness statuses reported by the readiness endpoint
+const (
+	statusReady    = "ready"
+	statusDegraded = "degraded"
+	statusNotReady = "not ready"
+)
+
+// registeredCheck is a readiness check and whether its failure affects
+// readiness according to the policy, or regardless of it
+type registeredCheck struct {
+	checker  ReadinessChecker
+	critical bool
+	fatal    bool
+}
+
+// Readiness is a registry of named readiness checks.
+// It serves the readiness endpoint, which fails according to its policy when
+// critical checks fail, and under every policy when a fatal check fails.
+// Failing non-critical checks only report the service as degraded.
+type Readiness struct {
+	checks map[string]registeredCheck
+	policy ReadinessPolicy
+	mu     sync.RWMutex
+}
+
+// NewReadiness creates an empty readiness registry with the FailIfAny policy
+func NewReadiness() *Readiness {
+	return &Readiness{
+		checks: make(map[string]registeredCheck),
+		policy: FailIfAny,
+	}
+}
+
+// SetPolicy sets how failing critical checks are aggregated
+func (rd *Readiness) SetPolicy(policy ReadinessPolicy) {
+	rd.mu.Lock()
+	defer rd.mu.Unlock()
+
+	rd.policy = policy
+}
+
+// Register adds a named critical readiness check, replacing any check with the same name
+func (rd *Readiness) Register(name string, checker ReadinessChecker) {
+	rd.register(name, registeredCheck{checker: checker, critical: true})
+}
+
+// RegisterNonCritical adds a named readiness check whose failure only degrades
+// the service, replacing any check with the same name
+func (rd *Readiness) RegisterNonCritical(name string, checker ReadinessChecker) {
+	rd.register(name, registeredCheck{checker: checker})
+}
+
+// RegisterFatal adds a named readiness check whose failure makes the service
+// not ready whatever the policy, e.g. one failing once a shutdown has begun,
+// replacing any check with the same name
+func (rd *Readiness) RegisterFatal(name string, checker ReadinessChecker) {
+	rd.register(name, registeredCheck{checker: checker, critical: true, fatal: true})
+}
+
+// register adds a named readiness check, replacing any check with the same name
+func (rd *Readiness) register(name string, check registeredCheck) {
+	rd.mu.Lock()
+	defer rd.mu.Unlock()
+
+	rd.checks[name] = check
+}
+
+// ServeHTTP runs all registered checks and reports whether the service is ready
+func (rd *Readiness) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), readinessCheckTimeout)
+	defer cancel()
+
+	results, policy := rd.run(ctx)
+
+	response := models.ReadinessResponse{
+		Status: aggregate(results, policy),
+		Checks: results,
+	}
+
+	for _, result := range results {
+		if result.Error != "" {
+			slog.Warn("Readiness check failed", "check", result.Name, "critical", result.Critical, "error", result.Error)
+		}
+	}
+
+	status := http.StatusOK
+	if response.Status == statusNotReady {
+		status = http.StatusServiceUnavailable
+	}
+
+	jsonResponse(w, status, response)
+}
+
+// aggregate combines check results into a readiness status according to policy.
+// Fatal checks are left out of the policy, as any of them failing is enough.
+func aggregate(results []models.CheckResult, policy ReadinessPolicy) string {
+	critical, criticalFailed, failed := 0, 0, 0
+	fatalFailed := false
+	for _, result := range results {
+		if result.Critical && !result.Fatal {
+			critical++
+		}
+		if result.Error == "" {
+			continue
+		}
+		failed++
+		switch {
+		case result.Fatal:
+			fatalFailed = true
+		case result.Critical:
+			criticalFailed++
+		}
+	}
+
+	switch {
+	case fatalFailed:
+		return statusNotReady
+	case criticalFailed > 0 && (policy != FailIfAll || criticalFailed == critical):
+		return statusNotReady
+	case failed > 0:
+		return statusDegraded
+	default:
+		return statusReady
+	}
+}
+
+// run executes every registered check and returns the results ordered by name,
+// along with the policy to aggregate them by
+func (rd *Readiness) run(ctx context.Context) ([]models.CheckResult, ReadinessPolicy) {
+	rd.mu.RLock()
+	defer rd.mu.RUnlock()
+
+	results := make([]models.CheckResult, 0, len(rd.checks))
+	for name, check := range rd.checks {
+		result := models.CheckResult{Name: name, Status: "ok", Critical: check.critical, Fatal: check.fatal}
+		if err := check.checker.Check(ctx); err != nil {
+			result.Status = "failed"
+			result.Error = err.Error()
+		}
+		results = append(results, result)
+	}
+
+	sort.Slice(results, func(i, j int) bool {
+		return results[i].Name < results[j].Name
+	})
+
+	return results, rd.policy
+}
+
+// LivenessHandler reports that the process is up.
+// It deliberately checks no dependencies, so it only fails if the process can't serve at all.
+func LivenessHandler(w http.ResponseWriter, _ *http.Request) {
+	response := models.StatusResponse{
+		Status: "alive",
+	}
+
+	jsonResponse(w, http.StatusOK, response)
+}
@@ -0,0 +1,344 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/kakkoyun/demo-web-service/models"
+	"github.com/kakkoyun/demo-web-service/store"
+)
+
+func TestHealthCheckReportsUptimeAndVersion(t *testing.T) {
+	api, _ := newTestAPI(t, []Option{WithVersion("v1.2.3")})
+
+	before := time.Now().UTC().Truncate(time.Second)
+	req := httptest.NewRequest("GET", "/api/health", nil)
+	rr := httptest.NewRecorder()
+	api.Routes().ServeHTTP(rr, req)
+
+	if status := rr.Code; status != http.StatusOK {
+		t.Fatalf("handler returned wrong status code: got %v want %v", status, http.StatusOK)
+	}
+
+	// Decode loosely to check that every field is present
+	var fields map[string]any
+	if err := json.Unmarshal(rr.Body.Bytes(), &fields); err != nil {
+		t.Fatalf("could not parse response body: %v", err)
+	}
+	for _, field := range []string{"status", "version", "uptime", "timestamp", "requests_served"} {
+		if _, ok := fields[field]; !ok {
+			t.Errorf("response is missing the %s field: %s", field, rr.Body.String())
+		}
+	}
+
+	var response models.HealthResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+		t.Fatalf("could not parse response body: %v", err)
+	}
+	if response.Status != "healthy" {
+		t.Errorf("handler returned wrong status: got %v want %v", response.Status, "healthy")
+	}
+	if response.Version != "v1.2.3" {
+		t.Errorf("handler returned wrong version: got %v want %v", response.Version, "v1.2.3")
+	}
+	if response.Uptime < 0 {
+		t.Errorf("handler returned negative uptime: %v", response.Uptime)
+	}
+	if response.Timestamp.Before(before) || response.Timestamp.Nanosecond() != 0 {
+		t.Errorf("handler returned wrong timestamp: got %v, want whole seconds from %v", response.Timestamp, before)
+	}
+}
+
+func TestLivenessHandler(t *testing.T) {
+	rr := httptest.NewRecorder()
+	LivenessHandler(rr, httptest.NewRequest("GET", "/api/health/live", nil))
+
+	if status := rr.Code; status != http.StatusOK {
+		t.Errorf("handler returned wrong status code: got %v want %v", status, http.StatusOK)
+	}
+}
+
+func TestReadiness(t *testing.T) {
+	passing := ReadinessCheckerFunc(func(_ context.Context) error { return nil })
+	failing := ReadinessCheckerFunc(func(_ context.Context) error { return errors.New("connection refused") })
+
+	testCases := []struct {
+		name           string
+		checks         map[string]ReadinessChecker
+		expectedStatus int
+		expectedBody   string
+		expectedFailed []string
+	}{
+		{
+			name:           "No Checks",
+			checks:         map[string]ReadinessChecker{},
+			expectedStatus: http.StatusOK,
+			expectedBody:   "ready",
+		},
+		{
+			name:           "Passing Check",
+			checks:         map[string]ReadinessChecker{"store": passing},
+			expectedStatus: http.StatusOK,
+			expectedBody:   "ready",
+		},
+		{
+			name:           "Failing Check",
+			checks:         map[string]ReadinessChecker{"store": passing, "database": failing},
+			expectedStatus: http.StatusServiceUnavailable,
+			expectedBody:   "not ready",
+			expectedFailed: []string{"database"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			readiness := NewReadiness()
+			for name, checker := range tc.checks {
+				readiness.Register(name, checker)
+			}
+
+			rr := httptest.NewRecorder()
+			readiness.ServeHTTP(rr, httptest.NewRequest("GET", "/api/health/ready", nil))
+
+			if status := rr.Code; status != tc.expectedStatus {
+				t.Errorf("handler returned wrong status code: got %v want %v", status, tc.expectedStatus)
+			}
+
+			var response models.ReadinessResponse
+			if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+				t.Fatalf("could not parse response body: %v", err)
+			}
+
+			if response.Status != tc.expectedBody {
+				t.Errorf("handler returned wrong status: got %v want %v", response.Status, tc.expectedBody)
+			}
+
+			if len(response.Checks) != len(tc.checks) {
+				t.Errorf("handler returned wrong number of checks: got %v want %v", len(response.Checks), len(tc.checks))
+			}
+
+			var failed []string
+			for _, check := range response.Checks {
+				if check.Status == "failed" {
+					failed = append(failed, check.Name)
+					if check.Error == "" {
+						t.Errorf("failed check %q has no error message", check.Name)
+					}
+				}
+			}
+
+			if len(failed) != len(tc.expectedFailed) {
+				t.Fatalf("handler returned wrong failed checks: got %v want %v", failed, tc.expectedFailed)
+			}
+			for i := range failed {
+				if failed[i] != tc.expectedFailed[i] {
+					t.Errorf("handler returned wrong failed checks: got %v want %v", failed, tc.expectedFailed)
+				}
+			}
+		})
+	}
+}
+
+func TestStoreReadiness(t *testing.T) {
+	closed := store.NewDemoStore()
+	closed.Close()
+
+	testCases := []struct {
+		users          store.UserStore
+		name           string
+		expectedStatus int
+	}{
+		{name: "Ping Succeeds", users: store.NewDemoStore(), expectedStatus: http.StatusOK},
+		{name: "Store Closed", users: closed, expectedStatus: http.StatusServiceUnavailable},
+		{name: "Ping Fails", users: &fakeStore{err: errors.New("connection refused")}, expectedStatus: http.StatusServiceUnavailable},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			api := NewAPI(tc.users, nil, WithFaultInjector(NoFaults{}))
+
+			rr := httptest.NewRecorder()
+			api.Routes().ServeHTTP(rr, httptest.NewRequest("GET", "/api/health/ready", nil))
+
+			if status := rr.Code; status != tc.expectedStatus {
+				t.Errorf("handler returned wrong status code: got %v want %v", status, tc.expectedStatus)
+			}
+
+			var response models.ReadinessResponse
+			if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+				t.Fatalf("could not parse response body: %v", err)
+			}
+			if len(response.Checks) != 1 || response.Checks[0].Name != "store" {
+				t.Fatalf("handler returned wrong checks: got %+v", response.Checks)
+			}
+			if failed := response.Checks[0].Status == "failed"; failed != (tc.expectedStatus != http.StatusOK) {
+				t.Errorf("handler returned wrong store check status: got %+v", response.Checks[0])
+			}
+		})
+	}
+}
+
+func TestReadinessPolicies(t *testing.T) {
+	passing := ReadinessCheckerFunc(func(_ context.Context) error { return nil })
+	failing := ReadinessCheckerFunc(func(_ context.Context) error { return errors.New("connection refused") })
+
+	type check struct {
+		checker  ReadinessChecker
+		critical bool
+		fatal    bool
+	}
+
+	testCases := []struct {
+		name           string
+		policy         ReadinessPolicy
+		checks         map[string]check
+		expectedBody   string
+		expectedStatus int
+	}{
+		{
+			name:   "Any: One Critical Failing",
+			policy: FailIfAny,
+			checks: map[string]check{
+				"database": {checker: failing, critical: true},
+				"store":    {checker: passing, critical: true},
+			},
+			expectedStatus: http.StatusServiceUnavailable,
+			expectedBody:   "not ready",
+		},
+		{
+			name:   "Any: Only Non-Critical Failing",
+			policy: FailIfAny,
+			checks: map[string]check{
+				"cache": {checker: failing},
+				"store": {checker: passing, critical: true},
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   "degraded",
+		},
+		{
+			name:   "All: One Critical Failing",
+			policy: FailIfAll,
+			checks: map[string]check{
+				"database": {checker: failing, critical: true},
+				"store":    {checker: passing, critical: true},
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   "degraded",
+		},
+		{
+			name:   "All: Every Critical Failing",
+			policy: FailIfAll,
+			checks: map[string]check{
+				"database": {checker: failing, critical: true},
+				"store":    {checker: failing, critical: true},
+				"cache":    {checker: passing},
+			},
+			expectedStatus: http.StatusServiceUnavailable,
+			expectedBody:   "not ready",
+		},
+		{
+			name:   "All: Only Non-Critical Failing",
+			policy: FailIfAll,
+			checks: map[string]check{
+				"cache":   {checker: failing},
+				"metrics": {checker: failing},
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   "degraded",
+		},
+		{
+			name:   "All: Draining",
+			policy: FailIfAll,
+			checks: map[string]check{
+				"shutdown": {checker: failing, critical: true, fatal: true},
+				"store":    {checker: passing, critical: true},
+			},
+			expectedStatus: http.StatusServiceUnavailable,
+			expectedBody:   "not ready",
+		},
+		{
+			name:   "All: Critical Failing While Fatal Passes",
+			policy: FailIfAll,
+			checks: map[string]check{
+				"shutdown": {checker: passing, critical: true, fatal: true},
+				"store":    {checker: failing, critical: true},
+			},
+			expectedStatus: http.StatusServiceUnavailable,
+			expectedBody:   "not ready",
+		},
+		{
+			name:   "Any: Draining",
+			policy: FailIfAny,
+			checks: map[string]check{
+				"shutdown": {checker: failing, critical: true, fatal: true},
+				"cache":    {checker: passing},
+			},
+			expectedStatus: http.StatusServiceUnavailable,
+			expectedBody:   "not ready",
+		},
+		{
+			name:   "All: Everything Passing",
+			policy: FailIfAll,
+			checks: map[string]check{
+				"cache": {checker: passing},
+				"store": {checker: passing, critical: true},
+			},
+			expectedStatus: http.StatusOK,
+			expectedBody:   "ready",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			readiness := NewReadiness()
+			readiness.SetPolicy(tc.policy)
+			for name, c := range tc.checks {
+				switch {
+				case c.fatal:
+					readiness.RegisterFatal(name, c.checker)
+				case c.critical:
+					readiness.Register(name, c.checker)
+				default:
+					readiness.RegisterNonCritical(name, c.checker)
+				}
+			}
+
+			rr := httptest.NewRecorder()
+			readiness.ServeHTTP(rr, httptest.NewRequest("GET", "/api/health/ready", nil))
+
+			if status := rr.Code; status != tc.expectedStatus {
+				t.Errorf("handler returned wrong status code: got %v want %v", status, tc.expectedStatus)
+			}
+
+			var response models.ReadinessResponse
+			if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+				t.Fatalf("could not parse response body: %v", err)
+			}
+			if response.Status != tc.expectedBody {
+				t.Errorf("handler returned wrong status: got %v want %v", response.Status, tc.expectedBody)
+			}
+			for _, result := range response.Checks {
+				if result.Critical != tc.checks[result.Name].critical || result.Fatal != tc.checks[result.Name].fatal {
+					t.Errorf("check %q reported wrong criticality: got critical %v, fatal %v", result.Name, result.Critical, result.Fatal)
+				}
+			}
+		})
+	}
+}
+
+func TestParseReadinessPolicy(t *testing.T) {
+	for _, s := range []string{"fail-if-any", "FAIL-IF-ALL"} {
+		if _, err := ParseReadinessPolicy(s); err != nil {
+			t.Errorf("unexpected error for %q: %v", s, err)
+		}
+	}
+
+	if _, err := ParseReadinessPolicy("majority"); err == nil {
+		t.Error("expected an error for an unknown policy")
+	}
+}
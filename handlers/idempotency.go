@@ -0,0 +1,263 @@
+package handlers
+
+import (
+	"bytes"
+	"container/list"
+	"context"
+	"crypto/sha256"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// IdempotencyKeyHeader is the header clients set to make retried requests safe
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+// DefaultIdempotencyTTL is how long responses are kept for replay by default
+const DefaultIdempotencyTTL = 24 * time.Hour
+
+// maxIdempotencyKeyLength bounds client-supplied keys so they can't bloat memory
+const maxIdempotencyKeyLength = 255
+
+// maxIdempotencyKeys bounds the number of keys kept at once, however many
+// clients send within the TTL
+const maxIdempotencyKeys = 10000
+
+// WithIdempotencyTTL sets how long responses to requests with an
+// Idempotency-Key are kept for replay
+func WithIdempotencyTTL(ttl time.Duration) Option {
+	return func(a *API) {
+		a.idempotency.ttl = ttl
+	}
+}
+
+// idempotentResponse is a stored response to a request with an Idempotency-Key
+type idempotentResponse struct {
+	expires time.Time
+	// data is the value the response encoded, if the handler sent it with
+	// respond, so that replays can encode it as their own request prefers
+	data        interface{}
+	contentType string
+	body        []byte
+	// element is the key's place in the cache's eviction order
+	element *list.Element
+	// fingerprint is a hash of the request body the key was first used with
+	fingerprint [sha256.Size]byte
+	status      int
+	// done is false while the first request with the key is still being served
+	done bool
+}
+
+// idempotencyCache holds responses by Idempotency-Key until they expire.
+// Expired entries are swept lazily while serving requests, like the rate limiter's.
+// Once it holds maxEntries keys, the oldest ones are evicted to make room.
+type idempotencyCache struct {
+	lastSweep time.Time
+	responses map[string]*idempotentResponse
+	// order lists the keys from the least to the most recently stored
+	order      *list.List
+	now        func() time.Time
+	ttl        time.Duration
+	maxEntries int
+	mu         sync.Mutex
+}
+
+// newIdempotencyCache creates an idempotencyCache keeping responses for ttl
+func newIdempotencyCache(ttl time.Duration) *idempotencyCache {
+	return &idempotencyCache{
+		responses:  make(map[string]*idempotentResponse),
+		order:      list.New(),
+		now:        time.Now,
+		ttl:        ttl,
+		maxEntries: maxIdempotencyKeys,
+	}
+}
+
+// begin looks up key for a request with the given body fingerprint.
+// It returns the stored response to replay, or nil if the request should be
+// served, in which case the key is reserved until finish or abandon is called.
+// ok is false when the key was used with a different body or its first request
+// is still in progress.
+func (c *idempotencyCache) begin(key string, fingerprint [sha256.Size]byte) (stored *idempotentResponse, ok bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := c.now()
+	c.sweep(now)
+
+	if resp, found := c.responses[key]; found && now.Before(resp.expires) {
+		if resp.fingerprint != fingerprint || !resp.done {
+			return nil, false
+		}
+		return resp, true
+	}
+
+	c.remove(key)
+	for c.order.Len() >= c.maxEntries {
+		c.remove(c.order.Front().Value.(string))
+	}
+	c.responses[key] = &idempotentResponse{
+		fingerprint: fingerprint,
+		expires:     now.Add(c.ttl),
+		element:     c.order.PushBack(key),
+	}
+	return nil, true
+}
+
+// finish stores the response to the request that reserved key, unless the key
+// was evicted in the meantime
+func (c *idempotencyCache) finish(key string, status int, contentType string, body []byte, data interface{}) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if resp, found := c.responses[key]; found {
+		resp.status = status
+		resp.contentType = contentType
+		resp.body = bytes.Clone(body)
+		resp.data = data
+		resp.done = true
+		resp.expires = c.now().Add(c.ttl)
+		c.order.MoveToBack(resp.element)
+	}
+}
+
+// abandon releases key without storing a response, so the request can be retried
+func (c *idempotencyCache) abandon(key string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.remove(key)
+}
+
+// remove drops key, if it is stored. c.mu must be held.
+func (c *idempotencyCache) remove(key string) {
+	if resp, found := c.responses[key]; found {
+		c.order.Remove(resp.element)
+		delete(c.responses, key)
+	}
+}
+
+// sweep removes expired responses, at most once a minute. c.mu must be held.
+func (c *idempotencyCache) sweep(now time.Time) {
+	if now.Sub(c.lastSweep) < time.Minute {
+		return
+	}
+	c.lastSweep = now
+
+	for key, resp := range c.responses {
+		if !now.Before(resp.expires) {
+			c.remove(key)
+		}
+	}
+}
+
+// idempotent makes a handler safe to retry with an Idempotency-Key header.
+// The first successful response for a key is stored and replayed, without
+// calling the handler again, for later requests with the same key and body.
+// Replays are encoded in the format and indentation their own request asks for.
+// Reusing a key with a different body, or while its first request is still in
+// progress, gets a 409. Failed requests aren't stored, since they changed
+// nothing and may succeed when retried. Requests without the header are served
+// as usual.
+// Keys are scoped to the caller, so one client can't replay, or block, the
+// responses to another's requests by guessing its keys.
+func (a *API) idempotent(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get(IdempotencyKeyHeader)
+		if key == "" {
+			next(w, r)
+			return
+		}
+		if len(key) > maxIdempotencyKeyLength {
+			errorResponse(w, r, http.StatusBadRequest, "Idempotency-Key is too long")
+			return
+		}
+
+		// Read the body up front to fingerprint it, then hand it on along with
+		// any read error, such as the body being too large, for the handler to report
+		body, readErr := io.ReadAll(r.Body)
+		r.Body = io.NopCloser(io.MultiReader(bytes.NewReader(body), errReader{err: readErr}))
+
+		// Keys are scoped to the caller and the route they were used with
+		scopedKey := caller(r) + " " + r.Method + " " + r.URL.Path + " " + key
+
+		stored, ok := a.idempotency.begin(scopedKey, sha256.Sum256(body))
+		if !ok {
+			a.log(r.Context()).Warn("Idempotency key conflict", "idempotency_key", key)
+			errorResponse(w, r, http.StatusConflict, "Idempotency-Key was already used with a different request, or that request is still in progress")
+			return
+		}
+		if stored != nil {
+			a.log(r.Context()).Info("Replaying response for idempotency key", "idempotency_key", key)
+			w.Header().Set("Idempotent-Replayed", "true")
+			if stored.data != nil {
+				respond(w, r, stored.status, stored.data)
+				return
+			}
+			writeBody(w, stored.status, stored.contentType, bytes.NewBuffer(stored.body))
+			return
+		}
+
+		// Release the key unless a response was stored, even if next panics
+		finished := false
+		defer func() {
+			if !finished {
+				a.idempotency.abandon(scopedKey)
+			}
+		}()
+
+		sent := new(sentResponse)
+		bw := newBufferedWriter(w)
+		next(bw, r.WithContext(context.WithValue(r.Context(), sentResponseKey, sent)))
+
+		if status := bw.Status(); status >= 200 && status < 300 {
+			a.idempotency.finish(scopedKey, status, w.Header().Get("Content-Type"), bw.Body(), sent.data)
+			finished = true
+		}
+
+		if err := bw.commit(); err != nil {
+			a.log(r.Context()).Debug("Failed to write response", "error", err)
+		}
+	}
+}
+
+// sentResponse records the value a handler sent with respond
+type sentResponse struct {
+	data interface{}
+}
+
+// recordSent records data as the response to r, if r is served by idempotent
+func recordSent(r *http.Request, data interface{}) {
+	if sent, ok := r.Context().Value(sentResponseKey).(*sentResponse); ok {
+		sent.data = data
+	}
+}
+
+// caller identifies who made r for scoping idempotency keys: the subject of its
+// bearer token, the owner of its API key, or its basic auth username. Requests
+// with no credentials share a single anonymous scope.
+func caller(r *http.Request) string {
+	if subject := SubjectFromContext(r.Context()); subject != "" {
+		return "subject:" + subject
+	}
+	if owner := OwnerFromContext(r.Context()); owner != "" {
+		return "owner:" + owner
+	}
+	if username, _, ok := r.BasicAuth(); ok {
+		return "user:" + username
+	}
+	return "anonymous:"
+}
+
+// errReader is an io.Reader that always fails with err, or reports EOF if err is nil
+type errReader struct {
+	err error
+}
+
+func (er errReader) Read([]byte) (int, error) {
+	if er.err != nil {
+		return 0, er.err
+	}
+	return 0, io.EOF
+}
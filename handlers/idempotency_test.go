@@ -0,0 +1,179 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kakkoyun/demo-web-service/models"
+)
+
+// createUser sends a create request with the given Idempotency-Key and body
+func createUser(handler http.Handler, key, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest("POST", "/api/users", strings.NewReader(body))
+	if key != "" {
+		req.Header.Set(IdempotencyKeyHeader, key)
+	}
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	return rr
+}
+
+// createdUserID returns the ID of the user in a create response
+func createdUserID(t *testing.T, rr *httptest.ResponseRecorder) int {
+	t.Helper()
+
+	var response models.UserResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+		t.Fatalf("could not parse response body: %v", err)
+	}
+	if response.User == nil {
+		t.Fatalf("response has no user: %s", rr.Body.String())
+	}
+	return response.User.ID
+}
+
+func TestIdempotentCreateUser(t *testing.T) {
+	api, users := newTestAPI(t, nil)
+	handler := api.Routes()
+
+	first := createUser(handler, "key-1", `{"name":"Alice"}`)
+	if first.Code != http.StatusCreated {
+		t.Fatalf("handler returned wrong status code: got %v want %v", first.Code, http.StatusCreated)
+	}
+
+	// Retrying with the same key and body replays the original response
+	retry := createUser(handler, "key-1", `{"name":"Alice"}`)
+	if retry.Code != http.StatusCreated {
+		t.Errorf("handler returned wrong status code on retry: got %v want %v", retry.Code, http.StatusCreated)
+	}
+	if got, want := createdUserID(t, retry), createdUserID(t, first); got != want {
+		t.Errorf("retry returned a different user: got ID %v want %v", got, want)
+	}
+	if retry.Header().Get("Idempotent-Replayed") != "true" {
+		t.Error("replayed response is not marked with Idempotent-Replayed")
+	}
+
+	// Reusing the key with a different body is a conflict
+	conflict := createUser(handler, "key-1", `{"name":"Bob"}`)
+	if conflict.Code != http.StatusConflict {
+		t.Errorf("handler returned wrong status code for a reused key: got %v want %v", conflict.Code, http.StatusConflict)
+	}
+
+	list, err := users.List(t.Context())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 3 {
+		t.Errorf("wrong number of users: got %v want %v", len(list), 3)
+	}
+}
+
+func TestIdempotencyKeyIsOptional(t *testing.T) {
+	api, _ := newTestAPI(t, nil)
+	handler := api.Routes()
+
+	first := createUser(handler, "", `{"name":"Alice"}`)
+	second := createUser(handler, "", `{"name":"Alice"}`)
+
+	if createdUserID(t, first) == createdUserID(t, second) {
+		t.Error("requests without an Idempotency-Key were deduplicated")
+	}
+}
+
+func TestIdempotencyDoesNotStoreFailures(t *testing.T) {
+	// The first attempt fails validation, the retry succeeds
+	api, _ := newTestAPI(t, []Option{WithFaultInjector(&failOnce{op: OpValidateUser})})
+	handler := api.Routes()
+
+	if rr := createUser(handler, "key-1", `{"name":"Alice"}`); rr.Code != http.StatusBadRequest {
+		t.Fatalf("handler returned wrong status code: got %v want %v", rr.Code, http.StatusBadRequest)
+	}
+	if rr := createUser(handler, "key-1", `{"name":"Alice"}`); rr.Code != http.StatusCreated {
+		t.Errorf("handler returned wrong status code on retry: got %v want %v", rr.Code, http.StatusCreated)
+	}
+}
+
+func TestIdempotencyKeysExpire(t *testing.T) {
+	api, _ := newTestAPI(t, []Option{WithIdempotencyTTL(time.Hour)})
+	now := time.Now()
+	api.idempotency.now = func() time.Time { return now }
+	handler := api.Routes()
+
+	first := createUser(handler, "key-1", `{"name":"Alice"}`)
+	now = now.Add(2 * time.Hour)
+	second := createUser(handler, "key-1", `{"name":"Alice"}`)
+
+	if createdUserID(t, first) == createdUserID(t, second) {
+		t.Error("expired Idempotency-Key was replayed")
+	}
+}
+
+func TestIdempotencyKeysAreEvictedOldestFirst(t *testing.T) {
+	api, _ := newTestAPI(t, nil)
+	api.idempotency.maxEntries = 2
+	handler := api.Routes()
+
+	first := createUser(handler, "key-1", `{"name":"Alice"}`)
+	createUser(handler, "key-2", `{"name":"Bob"}`)
+	createUser(handler, "key-3", `{"name":"Carol"}`)
+
+	if got := len(api.idempotency.responses); got != 2 {
+		t.Errorf("wrong number of stored keys: got %v want %v", got, 2)
+	}
+	if retry := createUser(handler, "key-2", `{"name":"Bob"}`); retry.Header().Get("Idempotent-Replayed") != "true" {
+		t.Error("a recent key was evicted")
+	}
+	if retry := createUser(handler, "key-1", `{"name":"Alice"}`); createdUserID(t, retry) == createdUserID(t, first) {
+		t.Error("the oldest key was replayed after it should have been evicted")
+	}
+}
+
+func TestIdempotencyKeysAreScopedToCaller(t *testing.T) {
+	api, _ := newTestAPI(t, nil)
+	handler := api.Routes()
+
+	createAs := func(username string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/api/users", strings.NewReader(`{"name":"Alice"}`))
+		req.Header.Set(IdempotencyKeyHeader, "key-1")
+		req.SetBasicAuth(username, "secret")
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		return rr
+	}
+
+	alice := createAs("alice")
+	bob := createAs("bob")
+	if bob.Header().Get("Idempotent-Replayed") != "" {
+		t.Error("another caller's response was replayed")
+	}
+	if createdUserID(t, alice) == createdUserID(t, bob) {
+		t.Error("requests from different callers with the same key were deduplicated")
+	}
+}
+
+func TestIdempotencyReplayNegotiatesFormat(t *testing.T) {
+	api, _ := newTestAPI(t, nil)
+	handler := api.Routes()
+
+	createUser(handler, "key-1", `{"name":"Alice"}`)
+
+	req := httptest.NewRequest("POST", "/api/users", strings.NewReader(`{"name":"Alice"}`))
+	req.Header.Set(IdempotencyKeyHeader, "key-1")
+	req.Header.Set("Accept", "application/xml")
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	if rr.Header().Get("Idempotent-Replayed") != "true" {
+		t.Fatal("response was not replayed")
+	}
+	if ct := rr.Header().Get("Content-Type"); ct != "application/xml" {
+		t.Errorf("replay has wrong content type: got %v want %v", ct, "application/xml")
+	}
+	if !strings.Contains(rr.Body.String(), "<name>Alice</name>") {
+		t.Errorf("replay is not the stored user encoded as XML: %s", rr.Body.String())
+	}
+}
@@ -0,0 +1,117 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"braces.dev/errtrace"
+
+	"github.com/kakkoyun/demo-web-service/models"
+	"github.com/kakkoyun/demo-web-service/store"
+)
+
+// ImportUsers stores a batch of users with explicit IDs.
+// ID collisions are resolved by the API's duplicate policy, which a request
+// can override with the on_duplicate query parameter.
+func (a *API) ImportUsers(w http.ResponseWriter, r *http.Request) {
+	a.log(r.Context()).Info("Importing users", "path", r.URL.Path)
+
+	policy := a.duplicatePolicy
+	if p := r.URL.Query().Get("on_duplicate"); p != "" {
+		parsed, err := store.ParseDuplicatePolicy(p)
+		if err != nil {
+			a.log(r.Context()).Error("Invalid duplicate policy", "policy", p, "error", err)
+			errorResponse(w, r, http.StatusBadRequest, err.Error())
+			return
+		}
+		policy = parsed
+	}
+
+	users, err := a.decodeImport(r)
+	if err != nil {
+		a.log(r.Context()).Error("User import failed", "error", err)
+		var invalid *InvalidFieldsError
+		statusCode := http.StatusBadRequest
+		switch {
+		case errors.Is(err, ErrBodyTooLarge):
+			statusCode = http.StatusRequestEntityTooLarge
+		case errors.As(err, &invalid):
+			statusCode = http.StatusUnprocessableEntity
+		}
+		errorResponseFor(w, r, statusCode, err, err.Error())
+		return
+	}
+
+	summary, err := a.users.Import(r.Context(), users, policy)
+	if errors.Is(err, store.ErrDuplicateID) {
+		a.log(r.Context()).Error("User import rejected", "policy", policy, "error", err)
+		errorResponseFor(w, r, http.StatusConflict, err, err.Error())
+		return
+	}
+	if err != nil {
+		a.log(r.Context()).Error("Failed to import users", "error", err)
+		errorResponse(w, r, http.StatusInternalServerError, "Failed to import users")
+		return
+	}
+
+	a.log(r.Context()).Info("Users imported",
+		"policy", policy,
+		"imported", summary.Imported,
+		"overwritten", summary.Overwritten,
+		"skipped", summary.Skipped)
+
+	response := models.ImportResponse{
+		Status:  "success",
+		Message: "Users imported successfully",
+		Summary: summary,
+	}
+
+	respond(w, r, http.StatusOK, response)
+}
+
+// decodeImport decodes and validates the users of an import request.
+// Every user is checked like a created one, and the invalid fields of all of
+// them are reported together, named after their index, e.g. "users[3].name".
+func (a *API) decodeImport(r *http.Request) ([]models.User, error) {
+	var users []models.User
+	if err := decodeJSON(r, &users); err != nil {
+		return nil, errtrace.Wrap(err)
+	}
+
+	if len(users) == 0 {
+		return nil, errtrace.Wrap(fmt.Errorf("%w: no users to import", ErrValidation))
+	}
+
+	var problems []models.FieldError
+	for i, user := range users {
+		var fields []models.FieldError
+		if user.ID <= 0 {
+			fields = append(fields, models.FieldError{Field: "id", Message: "must be positive"})
+		}
+		for _, err := range []error{validateUser(user), a.validateUserSchema(user)} {
+			var invalid *InvalidFieldsError
+			switch {
+			case errors.As(err, &invalid):
+				fields = append(fields, invalid.Fields...)
+			case err != nil:
+				return nil, errtrace.Wrap(fmt.Errorf("user %d: %w", i, err))
+			}
+		}
+
+		for _, field := range fields {
+			// Schema violations of the whole user name the "body"
+			if field.Field == "body" {
+				field.Field = fmt.Sprintf("users[%d]", i)
+			} else {
+				field.Field = fmt.Sprintf("users[%d].%s", i, field.Field)
+			}
+			problems = append(problems, field)
+		}
+	}
+	if len(problems) > 0 {
+		return nil, errtrace.Wrap(&InvalidFieldsError{Fields: problems})
+	}
+
+	return users, nil
+}
@@ -0,0 +1,238 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/kakkoyun/demo-web-service/models"
+	"github.com/kakkoyun/demo-web-service/store"
+)
+
+func TestImportUsersHandler(t *testing.T) {
+	// ID 1 collides with John Doe, ID 5 is new
+	const body = `[{"id":5,"name":"Imported User"},{"id":1,"name":"Johnny Doe"}]`
+
+	testCases := []struct {
+		name           string
+		policy         store.DuplicatePolicy
+		query          string
+		body           string
+		expectedName   string
+		expected       models.ImportSummary
+		expectedStatus int
+	}{
+		{
+			name:           "Skip",
+			policy:         store.DuplicateSkip,
+			body:           body,
+			expectedStatus: http.StatusOK,
+			expected:       models.ImportSummary{Policy: "skip", Imported: 1, Skipped: 1},
+			expectedName:   "John Doe",
+		},
+		{
+			name:           "Overwrite",
+			policy:         store.DuplicateOverwrite,
+			body:           body,
+			expectedStatus: http.StatusOK,
+			expected:       models.ImportSummary{Policy: "overwrite", Imported: 1, Overwritten: 1},
+			expectedName:   "Johnny Doe",
+		},
+		{
+			name:           "Error",
+			policy:         store.DuplicateError,
+			body:           body,
+			expectedStatus: http.StatusConflict,
+			expectedName:   "John Doe",
+		},
+		{
+			name:           "Query Overrides Policy",
+			policy:         store.DuplicateError,
+			query:          "?on_duplicate=overwrite",
+			body:           body,
+			expectedStatus: http.StatusOK,
+			expected:       models.ImportSummary{Policy: "overwrite", Imported: 1, Overwritten: 1},
+			expectedName:   "Johnny Doe",
+		},
+		{
+			name:           "Invalid Policy",
+			policy:         store.DuplicateSkip,
+			query:          "?on_duplicate=merge",
+			body:           body,
+			expectedStatus: http.StatusBadRequest,
+			expectedName:   "John Doe",
+		},
+		{
+			name:           "Missing ID",
+			policy:         store.DuplicateOverwrite,
+			body:           `[{"name":"Johnny Doe"}]`,
+			expectedStatus: http.StatusUnprocessableEntity,
+			expectedName:   "John Doe",
+		},
+		{
+			name:           "Empty Batch",
+			policy:         store.DuplicateSkip,
+			body:           `[]`,
+			expectedStatus: http.StatusBadRequest,
+			expectedName:   "John Doe",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			api, s := newTestAPI(t, []Option{WithDuplicatePolicy(tc.policy)})
+
+			req := httptest.NewRequest("POST", "/api/users/import"+tc.query, strings.NewReader(tc.body))
+			rr := httptest.NewRecorder()
+			api.Routes().ServeHTTP(rr, req)
+
+			if status := rr.Code; status != tc.expectedStatus {
+				t.Fatalf("handler returned wrong status code: got %v want %v", status, tc.expectedStatus)
+			}
+
+			if tc.expectedStatus == http.StatusOK {
+				var response models.ImportResponse
+				if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+					t.Fatalf("could not parse response body: %v", err)
+				}
+				got := response.Summary
+				if got.Policy != tc.expected.Policy ||
+					got.Imported != tc.expected.Imported ||
+					got.Overwritten != tc.expected.Overwritten ||
+					got.Skipped != tc.expected.Skipped {
+					t.Errorf("handler returned wrong summary: got %+v want %+v", got, tc.expected)
+				}
+			}
+
+			user, err := s.Get(req.Context(), 1)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if user.Name != tc.expectedName {
+				t.Errorf("colliding user has wrong name: got %v want %v", user.Name, tc.expectedName)
+			}
+		})
+	}
+}
+
+func TestImportUsersInvalidFields(t *testing.T) {
+	// Stricter than the built-in validation, so only the schema rejects long names
+	schema := newTestSchemaValidator(t, `{
+		"type": "object",
+		"properties": {"name": {"type": "string", "maxLength": 8}}
+	}`)
+	api, s := newTestAPI(t, []Option{WithUserSchema(schema)})
+
+	body := `[{"id":5,"name":"Imported"},{"id":6,"name":" "},{"id":0,"name":"Imported User"}]`
+	req := httptest.NewRequest("POST", "/api/users/import", strings.NewReader(body))
+	rr := httptest.NewRecorder()
+	api.Routes().ServeHTTP(rr, req)
+
+	if status := rr.Code; status != http.StatusUnprocessableEntity {
+		t.Fatalf("handler returned wrong status code: got %v want %v: %s", status, http.StatusUnprocessableEntity, rr.Body)
+	}
+
+	var response models.ErrorResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+		t.Fatalf("could not parse response body: %v", err)
+	}
+	expected := []models.FieldError{
+		{Field: "users[1].name", Message: "is required"},
+		{Field: "users[2].id", Message: "must be positive"},
+		{Field: "users[2].name", Message: "is invalid: maxLength: got 13, want 8"},
+	}
+	if !slices.Equal(response.Details, expected) {
+		t.Errorf("handler returned wrong field errors: got %v want %v", response.Details, expected)
+	}
+
+	// A batch with an invalid user is rejected as a whole
+	if _, err := s.Get(req.Context(), 5); err == nil {
+		t.Error("valid user of a rejected batch was imported")
+	}
+}
+
+func TestBodyLimitsPerRoute(t *testing.T) {
+	// A batch of 100 users is about 3KB, well over the single-create limit
+	users := make([]string, 0, 100)
+	for i := range 100 {
+		users = append(users, fmt.Sprintf(`{"id":%d,"name":"Imported User %d"}`, 100+i, i))
+	}
+	largeBody := "[" + strings.Join(users, ",") + "]"
+	largeUser := `{"name":"` + strings.Repeat("a", len(largeBody)) + `"}`
+
+	testCases := []struct {
+		name           string
+		path           string
+		body           string
+		importLimit    int64
+		expectedStatus int
+	}{
+		{name: "Large Import", path: "/api/users/import", body: largeBody, importLimit: 1 << 20, expectedStatus: http.StatusOK},
+		{name: "Large Create", path: "/api/users", body: largeUser, importLimit: 1 << 20, expectedStatus: http.StatusRequestEntityTooLarge},
+		{name: "Import Over Its Own Limit", path: "/api/users/import", body: largeBody, importLimit: 1024, expectedStatus: http.StatusRequestEntityTooLarge},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			api, _ := newTestAPI(t, []Option{WithMaxBodyBytes(256), WithImportMaxBodyBytes(tc.importLimit)})
+
+			req := httptest.NewRequest("POST", tc.path, strings.NewReader(tc.body))
+			rr := httptest.NewRecorder()
+			api.Routes().ServeHTTP(rr, req)
+
+			if status := rr.Code; status != tc.expectedStatus {
+				t.Errorf("handler returned wrong status code: got %v want %v", status, tc.expectedStatus)
+			}
+		})
+	}
+}
+
+func TestImportUsersStringIDs(t *testing.T) {
+	testCases := []struct {
+		name           string
+		body           string
+		expectedStatus int
+	}{
+		{name: "Numeric String", body: `[{"id":"5","name":"Imported User"}]`, expectedStatus: http.StatusOK},
+		{name: "Number", body: `[{"id":5,"name":"Imported User"}]`, expectedStatus: http.StatusOK},
+		{name: "Non-Numeric String", body: `[{"id":"five","name":"Imported User"}]`, expectedStatus: http.StatusBadRequest},
+		{name: "Fraction", body: `[{"id":5.5,"name":"Imported User"}]`, expectedStatus: http.StatusBadRequest},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			api, s := newTestAPI(t, nil)
+
+			req := httptest.NewRequest("POST", "/api/users/import", strings.NewReader(tc.body))
+			rr := httptest.NewRecorder()
+			api.Routes().ServeHTTP(rr, req)
+
+			if status := rr.Code; status != tc.expectedStatus {
+				t.Fatalf("handler returned wrong status code: got %v want %v", status, tc.expectedStatus)
+			}
+
+			if tc.expectedStatus != http.StatusOK {
+				var response models.ErrorResponse
+				if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+					t.Fatalf("could not parse response body: %v", err)
+				}
+				if !strings.Contains(response.Message, "id must be an integer") {
+					t.Errorf("error does not explain the expected ID type: %v", response.Message)
+				}
+				return
+			}
+
+			user, err := s.Get(req.Context(), 5)
+			if err != nil {
+				t.Fatalf("imported user not found: %v", err)
+			}
+			if user.Name != "Imported User" {
+				t.Errorf("imported user has wrong name: got %v want %v", user.Name, "Imported User")
+			}
+		})
+	}
+}
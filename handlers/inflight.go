@@ -0,0 +1,38 @@
+package handlers
+
+import (
+	"log/slog"
+	"net/http"
+)
+
+// MaxInFlightMiddleware creates a middleware that serves at most n requests at
+// once. Further requests are rejected straight away with a 503 and a
+// Retry-After header rather than queued, so an overloaded server sheds load
+// instead of piling up work. A slot is released when its request finishes,
+// even if the handler panics, so the middleware may sit inside the recover
+// middleware. Streaming routes, which have their own subscriber limit, should
+// skip it, see API.Streaming. A non-positive n disables the limit.
+func MaxInFlightMiddleware(n int) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if n <= 0 {
+			return next
+		}
+
+		slots := make(chan struct{}, n)
+
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			select {
+			case slots <- struct{}{}:
+			default:
+				slog.Warn("Too many requests in flight", "limit", n)
+
+				w.Header().Set("Retry-After", "1")
+				errorResponse(w, r, http.StatusServiceUnavailable, "Server is busy, try again later")
+				return
+			}
+			defer func() { <-slots }()
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
@@ -0,0 +1,131 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+)
+
+func TestMaxInFlightMiddleware(t *testing.T) {
+	const limit, requests = 2, 5
+
+	entered := make(chan struct{}, requests)
+	release := make(chan struct{})
+	slow := http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		entered <- struct{}{}
+		<-release
+		w.WriteHeader(http.StatusOK)
+	})
+	handler := MaxInFlightMiddleware(limit)(slow)
+
+	var wg sync.WaitGroup
+	codes := make(chan *httptest.ResponseRecorder, requests)
+	for range requests {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, httptest.NewRequest("GET", "/api/users", nil))
+			codes <- rr
+		}()
+	}
+
+	// Let the admitted requests finish only once every other one was rejected
+	for range limit {
+		<-entered
+	}
+	for range requests - limit {
+		rr := <-codes
+		if status := rr.Code; status != http.StatusServiceUnavailable {
+			t.Errorf("handler returned wrong status code: got %v want %v", status, http.StatusServiceUnavailable)
+		}
+		if rr.Header().Get("Retry-After") == "" {
+			t.Error("rejected request has no Retry-After header")
+		}
+	}
+	close(release)
+	wg.Wait()
+	close(codes)
+
+	for rr := range codes {
+		if status := rr.Code; status != http.StatusOK {
+			t.Errorf("handler returned wrong status code: got %v want %v", status, http.StatusOK)
+		}
+	}
+}
+
+func TestMaxInFlightMiddlewareReleasesSlotOnPanic(t *testing.T) {
+	panicking := true
+	handler := MaxInFlightMiddleware(1)(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		if panicking {
+			panic("handler failed")
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	func() {
+		defer func() {
+			if recover() == nil {
+				t.Fatal("panic was not passed on to the recover middleware")
+			}
+		}()
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+	}()
+
+	panicking = false
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest("GET", "/", nil))
+	if status := rr.Code; status != http.StatusOK {
+		t.Errorf("handler returned wrong status code after a panic: got %v want %v", status, http.StatusOK)
+	}
+}
+
+func TestMaxInFlightMiddlewareExemptsStreamingRoutes(t *testing.T) {
+	api, _ := newTestAPI(t, nil)
+
+	entered := make(chan struct{})
+	release := make(chan struct{})
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/api/health" {
+			close(entered)
+			<-release
+		}
+		w.WriteHeader(http.StatusOK)
+	})
+	handler := Unless(api.Streaming, MaxInFlightMiddleware(1))(next)
+
+	// Take the only slot
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/api/health", nil))
+	}()
+	<-entered
+	defer func() {
+		close(release)
+		<-done
+	}()
+
+	testCases := []struct {
+		name           string
+		path           string
+		expectedStatus int
+	}{
+		{name: "Event Stream", path: "/api/users/events", expectedStatus: http.StatusOK},
+		{name: "Other Route Accepting Event Stream", path: "/api/users", expectedStatus: http.StatusServiceUnavailable},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", tc.path, nil)
+			req.Header.Set("Accept", "text/event-stream")
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			if status := rr.Code; status != tc.expectedStatus {
+				t.Errorf("handler returned wrong status code: got %v want %v", status, tc.expectedStatus)
+			}
+		})
+	}
+}
@@ -0,0 +1,82 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"braces.dev/errtrace"
+
+	"github.com/kakkoyun/demo-web-service/models"
+)
+
+// JSONPatchContentType is the media type of RFC 6902 JSON Patch documents
+const JSONPatchContentType = "application/json-patch+json"
+
+// ErrPatchTestFailed is returned when a JSON Patch test operation doesn't
+// match the user it is applied to
+var ErrPatchTestFailed = errors.New("patch test failed")
+
+// patchOperation is a single operation of a JSON Patch document
+type patchOperation struct {
+	Op   string `json:"op"`
+	Path string `json:"path"`
+	// name is the decoded Value of operations on /name
+	name  string
+	Value json.RawMessage `json:"value"`
+}
+
+// jsonPatch is a JSON Patch document: operations applied in order, all or nothing
+type jsonPatch []patchOperation
+
+// isJSONPatch reports whether a request's body is a JSON Patch document
+func isJSONPatch(r *http.Request) bool {
+	mediaType, _, ok := parseMediaType(r.Header.Get("Content-Type"))
+	return ok && mediaType == JSONPatchContentType
+}
+
+// decodeJSONPatch decodes and validates a JSON Patch document.
+// Only the replace and test operations on /name are supported.
+func decodeJSONPatch(r *http.Request) (jsonPatch, error) {
+	var patch jsonPatch
+	if err := decodeJSONStrict(r, &patch); err != nil {
+		return nil, errtrace.Wrap(err)
+	}
+
+	for i := range patch {
+		op := &patch[i]
+		switch op.Op {
+		case "replace", "test":
+		case "":
+			return nil, errtrace.Wrap(fmt.Errorf("%w: operation %d has no op", ErrValidation, i))
+		default:
+			return nil, errtrace.Wrap(fmt.Errorf("%w: operation %d: unsupported op %q", ErrValidation, i, op.Op))
+		}
+
+		if op.Path != "/name" {
+			return nil, errtrace.Wrap(fmt.Errorf("%w: operation %d: unsupported path %q", ErrValidation, i, op.Path))
+		}
+		if err := json.Unmarshal(op.Value, &op.name); err != nil {
+			return nil, errtrace.Wrap(fmt.Errorf("%w: operation %d: value must be a string", ErrValidation, i))
+		}
+	}
+
+	return patch, nil
+}
+
+// Apply returns the user with the patch's operations applied, or an error
+// wrapping ErrPatchTestFailed if one of its tests fails
+func (p jsonPatch) Apply(user models.User) (models.User, error) {
+	for i, op := range p {
+		switch op.Op {
+		case "replace":
+			user.Name = op.name
+		case "test":
+			if user.Name != op.name {
+				return models.User{}, errtrace.Wrap(fmt.Errorf("%w: operation %d: %s is %q, not %q", ErrPatchTestFailed, i, op.Path, user.Name, op.name))
+			}
+		}
+	}
+	return user, nil
+}
@@ -0,0 +1,103 @@
+package handlers
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newTestJWT returns an HS256 token holding claims, signed with secret
+func newTestJWT(t *testing.T, secret string, claims map[string]any) string {
+	t.Helper()
+
+	header := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"HS256","typ":"JWT"}`))
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		t.Fatalf("Failed to encode claims: %v", err)
+	}
+	signingInput := header + "." + base64.RawURLEncoding.EncodeToString(payload)
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(signJWT(signingInput, []byte(secret)))
+}
+
+func TestJWTAuthMiddleware(t *testing.T) {
+	const secret = "a-secret-that-is-at-least-32-bytes"
+	future := time.Now().Add(time.Hour).Unix()
+	past := time.Now().Add(-time.Hour).Unix()
+
+	valid := newTestJWT(t, secret, map[string]any{"sub": "alice", "exp": future})
+	tampered := newTestJWT(t, secret, map[string]any{"sub": "alice", "exp": future})
+	forged := newTestJWT(t, secret, map[string]any{"sub": "mallory", "exp": future})
+	// Keep alice's signature but swap in mallory's claims
+	tampered = strings.Join(append(strings.Split(forged, ".")[:2], strings.Split(tampered, ".")[2]), ".")
+	unsigned := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"none"}`)) + "." +
+		strings.Split(valid, ".")[1] + "."
+
+	testCases := []struct {
+		name            string
+		authorization   string
+		expectedSubject string
+		expectedStatus  int
+	}{
+		{name: "Valid Token", authorization: "Bearer " + valid, expectedStatus: http.StatusOK, expectedSubject: "alice"},
+		{name: "Lowercase Scheme", authorization: "bearer " + valid, expectedStatus: http.StatusOK, expectedSubject: "alice"},
+		{name: "Expired Token", authorization: "Bearer " + newTestJWT(t, secret, map[string]any{"sub": "alice", "exp": past}), expectedStatus: http.StatusUnauthorized},
+		{name: "Missing Expiry", authorization: "Bearer " + newTestJWT(t, secret, map[string]any{"sub": "alice"}), expectedStatus: http.StatusUnauthorized},
+		{name: "Not Valid Yet", authorization: "Bearer " + newTestJWT(t, secret, map[string]any{"sub": "alice", "exp": future, "nbf": future}), expectedStatus: http.StatusUnauthorized},
+		{name: "Missing Subject", authorization: "Bearer " + newTestJWT(t, secret, map[string]any{"exp": future}), expectedStatus: http.StatusUnauthorized},
+		{name: "Tampered Signature", authorization: "Bearer " + tampered, expectedStatus: http.StatusUnauthorized},
+		{name: "Wrong Secret", authorization: "Bearer " + newTestJWT(t, "another-secret", map[string]any{"sub": "alice", "exp": future}), expectedStatus: http.StatusUnauthorized},
+		{name: "Unsigned Token", authorization: "Bearer " + unsigned, expectedStatus: http.StatusUnauthorized},
+		{name: "Malformed Token", authorization: "Bearer not-a-token", expectedStatus: http.StatusUnauthorized},
+		{name: "Basic Scheme", authorization: "Basic YWxpY2U6c2VjcmV0", expectedStatus: http.StatusUnauthorized},
+		{name: "Missing Header", expectedStatus: http.StatusUnauthorized},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var subject string
+			handler := JWTAuthMiddleware([]byte(secret))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				subject = SubjectFromContext(r.Context())
+			}))
+
+			req := httptest.NewRequest("GET", "/api/users", nil)
+			if tc.authorization != "" {
+				req.Header.Set("Authorization", tc.authorization)
+			}
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			if status := rr.Code; status != tc.expectedStatus {
+				t.Errorf("handler returned wrong status code: got %v want %v", status, tc.expectedStatus)
+			}
+			if subject != tc.expectedSubject {
+				t.Errorf("handler saw wrong subject: got %q want %q", subject, tc.expectedSubject)
+			}
+
+			challenge := rr.Header().Get("WWW-Authenticate")
+			if tc.expectedStatus == http.StatusUnauthorized && !strings.HasPrefix(challenge, "Bearer ") {
+				t.Errorf("handler returned wrong WWW-Authenticate header: got %q", challenge)
+			}
+		})
+	}
+}
+
+func TestJWTAuthMiddlewareLogsSubject(t *testing.T) {
+	const secret = "a-secret-that-is-at-least-32-bytes"
+	logs := captureLogs(t)
+
+	handler := ContextLoggerMiddleware(nil)(JWTAuthMiddleware([]byte(secret))(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		LoggerFromContext(r.Context()).Info("Handled")
+	})))
+
+	req := httptest.NewRequest("GET", "/api/users", nil)
+	req.Header.Set("Authorization", "Bearer "+newTestJWT(t, secret, map[string]any{"sub": "alice", "exp": time.Now().Add(time.Hour).Unix()}))
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	if !strings.Contains(logs.String(), `"subject":"alice"`) {
+		t.Errorf("log line lacks the token subject: %s", logs.String())
+	}
+}
@@ -0,0 +1,73 @@
+package handlers
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/kakkoyun/demo-web-service/internal/ctxkeys"
+)
+
+// ContextLoggerMiddleware creates a middleware that stores a request-scoped
+// logger in the request context: a child of logger carrying the request ID,
+// method and path, so every line a handler logs can be tied to its request.
+// It must sit inside RequestIDMiddleware to see the request ID.
+// A nil logger uses the default slog logger at the time of the request.
+func ContextLoggerMiddleware(logger *slog.Logger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			base := logger
+			if base == nil {
+				base = slog.Default()
+			}
+
+			requestLogger := base.With(
+				"request_id", RequestIDFromContext(r.Context()),
+				"method", r.Method,
+				"path", r.URL.Path,
+			)
+
+			next.ServeHTTP(w, r.WithContext(ctxkeys.WithLogger(r.Context(), requestLogger)))
+		})
+	}
+}
+
+// LoggerFromContext returns the request-scoped logger stored in ctx by
+// ContextLoggerMiddleware, or the default slog logger if there is none.
+// If ctx holds a trace span, the logger also carries its trace and span IDs.
+func LoggerFromContext(ctx context.Context) *slog.Logger {
+	logger, ok := contextLogger(ctx)
+	if !ok {
+		logger = slog.Default()
+	}
+	return withTraceIDs(ctx, logger)
+}
+
+// contextLogger returns the request-scoped logger stored in ctx, if any
+func contextLogger(ctx context.Context) (*slog.Logger, bool) {
+	logger := ctxkeys.Logger(ctx)
+	return logger, logger != nil
+}
+
+// traceIDs returns the trace_id and span_id log attributes of the span in ctx,
+// or nil if ctx holds no span
+func traceIDs(ctx context.Context) []any {
+	spanContext := trace.SpanContextFromContext(ctx)
+	if !spanContext.IsValid() {
+		return nil
+	}
+	return []any{
+		"trace_id", spanContext.TraceID().String(),
+		"span_id", spanContext.SpanID().String(),
+	}
+}
+
+// withTraceIDs returns logger with the IDs of the span in ctx, if there is one
+func withTraceIDs(ctx context.Context, logger *slog.Logger) *slog.Logger {
+	if ids := traceIDs(ctx); ids != nil {
+		return logger.With(ids...)
+	}
+	return logger
+}
@@ -0,0 +1,122 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http/httptest"
+	"testing"
+
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestContextLoggerMiddleware(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, nil))
+
+	api, _ := newTestAPI(t, nil)
+	handler := RequestIDMiddleware(ContextLoggerMiddleware(logger)(api.Routes()))
+
+	req := httptest.NewRequest("GET", "/api/users", nil)
+	req.Header.Set(RequestIDHeader, "test-request-id")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	var entry map[string]any
+	for line := range bytes.Lines(buf.Bytes()) {
+		if err := json.Unmarshal(line, &entry); err != nil {
+			t.Fatalf("could not parse log line %q: %v", line, err)
+		}
+		if entry["msg"] == "Getting all users" {
+			break
+		}
+		entry = nil
+	}
+	if entry == nil {
+		t.Fatalf("handler log line not found in %q", buf.String())
+	}
+
+	expected := map[string]string{
+		"request_id": "test-request-id",
+		"method":     "GET",
+		"path":       "/api/users",
+	}
+	for key, want := range expected {
+		if got := entry[key]; got != want {
+			t.Errorf("handler log has wrong %s: got %v want %v", key, got, want)
+		}
+	}
+}
+
+func TestLoggerFromContextFallsBackToDefault(t *testing.T) {
+	buf := captureLogs(t)
+
+	LoggerFromContext(context.Background()).Info("No request logger")
+
+	if !bytes.Contains(buf.Bytes(), []byte("No request logger")) {
+		t.Errorf("default logger was not used: got %q", buf.String())
+	}
+}
+
+func TestLogsCarryTraceIDs(t *testing.T) {
+	tp := sdktrace.NewTracerProvider()
+	t.Cleanup(func() { _ = tp.Shutdown(t.Context()) })
+
+	testCases := []struct {
+		name   string
+		traced bool
+	}{
+		{name: "With Span", traced: true},
+		{name: "Without Span", traced: false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			logs := captureLogs(t)
+
+			api, _ := newTestAPI(t, nil)
+			handler := LoggingMiddleware(0)(ContextLoggerMiddleware(nil)(api.Routes()))
+
+			req := httptest.NewRequest("GET", "/api/users", nil)
+			var spanContext trace.SpanContext
+			if tc.traced {
+				ctx, span := tp.Tracer("test").Start(req.Context(), "request")
+				defer span.End()
+				spanContext = span.SpanContext()
+				req = req.WithContext(ctx)
+			}
+			handler.ServeHTTP(httptest.NewRecorder(), req)
+
+			found := 0
+			for line := range bytes.Lines(logs.Bytes()) {
+				var entry map[string]any
+				if err := json.Unmarshal(line, &entry); err != nil {
+					t.Fatalf("could not parse log line %q: %v", line, err)
+				}
+				if entry["msg"] != "Getting all users" && entry["msg"] != "Request completed" {
+					continue
+				}
+				found++
+
+				if !tc.traced {
+					for _, key := range []string{"trace_id", "span_id"} {
+						if _, ok := entry[key]; ok {
+							t.Errorf("%q log has %s without a span", entry["msg"], key)
+						}
+					}
+					continue
+				}
+				if got, want := entry["trace_id"], spanContext.TraceID().String(); got != want {
+					t.Errorf("%q log has wrong trace_id: got %v want %v", entry["msg"], got, want)
+				}
+				if got, want := entry["span_id"], spanContext.SpanID().String(); got != want {
+					t.Errorf("%q log has wrong span_id: got %v want %v", entry["msg"], got, want)
+				}
+			}
+			if found != 2 {
+				t.Fatalf("expected the handler and request log lines, found %d in %q", found, logs.String())
+			}
+		})
+	}
+}
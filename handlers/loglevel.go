@@ -0,0 +1,57 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+
+	"github.com/kakkoyun/demo-web-service/config"
+)
+
+// logLevelRequest is the request body for changing the log level
+type logLevelRequest struct {
+	Level string `json:"level"`
+}
+
+// logLevelResponse is the response body after changing the log level
+type logLevelResponse struct {
+	Status string `json:"status"`
+	Level  string `json:"level"`
+}
+
+// LogLevelHandler returns a handler that changes the log level at runtime.
+// It accepts a body such as {"level":"debug"} and, unless enabled, responds
+// with 404 as if the route did not exist.
+func LogLevelHandler(level *slog.LevelVar, enabled bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !enabled {
+			errorResponse(w, r, http.StatusNotFound, "Not found")
+			return
+		}
+
+		var req logLevelRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			slog.Error("Failed to decode log level request", "error", err)
+			errorResponse(w, r, http.StatusBadRequest, "Invalid request body")
+			return
+		}
+
+		newLevel, err := config.ParseLogLevel(req.Level)
+		if err != nil {
+			slog.Error("Invalid log level requested", "level", req.Level, "error", err)
+			errorResponse(w, r, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		previous := level.Level()
+		level.Set(newLevel)
+		slog.Warn("Log level changed", "from", previous, "to", newLevel, "remote_addr", r.RemoteAddr)
+
+		response := logLevelResponse{
+			Status: "success",
+			Level:  newLevel.String(),
+		}
+
+		jsonResponse(w, http.StatusOK, response)
+	}
+}
@@ -0,0 +1,69 @@
+package handlers
+
+import (
+	"bytes"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestLogLevelHandler(t *testing.T) {
+	level := new(slog.LevelVar)
+	level.Set(slog.LevelInfo)
+
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: level}))
+
+	logger.Debug("before change")
+	if strings.Contains(buf.String(), "before change") {
+		t.Fatal("debug log emitted at info level")
+	}
+
+	req := httptest.NewRequest("PUT", "/debug/loglevel", strings.NewReader(`{"level":"debug"}`))
+	rr := httptest.NewRecorder()
+	LogLevelHandler(level, true).ServeHTTP(rr, req)
+
+	if status := rr.Code; status != http.StatusOK {
+		t.Fatalf("handler returned wrong status code: got %v want %v", status, http.StatusOK)
+	}
+
+	logger.Debug("after change")
+	if !strings.Contains(buf.String(), "after change") {
+		t.Error("debug log not emitted after switching to debug level")
+	}
+}
+
+func TestLogLevelHandlerErrors(t *testing.T) {
+	testCases := []struct {
+		name           string
+		body           string
+		enabled        bool
+		expectedStatus int
+	}{
+		{name: "Invalid Level", body: `{"level":"verbose"}`, enabled: true, expectedStatus: http.StatusBadRequest},
+		{name: "Missing Level", body: `{}`, enabled: true, expectedStatus: http.StatusBadRequest},
+		{name: "Malformed Body", body: `{"level":`, enabled: true, expectedStatus: http.StatusBadRequest},
+		{name: "Disabled", body: `{"level":"debug"}`, enabled: false, expectedStatus: http.StatusNotFound},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			level := new(slog.LevelVar)
+			level.Set(slog.LevelWarn)
+
+			req := httptest.NewRequest("PUT", "/debug/loglevel", strings.NewReader(tc.body))
+			rr := httptest.NewRecorder()
+			LogLevelHandler(level, tc.enabled).ServeHTTP(rr, req)
+
+			if status := rr.Code; status != tc.expectedStatus {
+				t.Errorf("handler returned wrong status code: got %v want %v", status, tc.expectedStatus)
+			}
+
+			if level.Level() != slog.LevelWarn {
+				t.Errorf("level changed on failed request: got %v", level.Level())
+			}
+		})
+	}
+}
@@ -0,0 +1,57 @@
+package handlers
+
+import (
+	"log/slog"
+	"math"
+	"net/http"
+	"slices"
+	"strconv"
+	"sync/atomic"
+	"time"
+)
+
+// maintenanceExemptPaths are served even in maintenance mode, so that
+// orchestrators don't restart an instance that is down on purpose. Readiness
+// isn't among them, so that load balancers stop routing traffic to it.
+var maintenanceExemptPaths = []string{"/api/health", "/api/health/live"}
+
+// Maintenance switches the service in and out of maintenance mode at runtime.
+// While it is enabled, its middleware answers every request except the health
+// and liveness checks with a 503 and a Retry-After header.
+type Maintenance struct {
+	// retryAfter is the Retry-After value in whole seconds
+	retryAfter atomic.Int64
+	enabled    atomic.Bool
+}
+
+// NewMaintenance creates a Maintenance that starts out disabled
+func NewMaintenance() *Maintenance {
+	return &Maintenance{}
+}
+
+// Set enables or disables maintenance mode, telling clients to retry after
+// retryAfter, rounded up to whole seconds
+func (m *Maintenance) Set(enabled bool, retryAfter time.Duration) {
+	m.retryAfter.Store(max(1, int64(math.Ceil(retryAfter.Seconds()))))
+	m.enabled.Store(enabled)
+}
+
+// Enabled reports whether maintenance mode is on
+func (m *Maintenance) Enabled() bool {
+	return m.enabled.Load()
+}
+
+// Middleware short-circuits requests through next while maintenance mode is on
+func (m *Maintenance) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !m.Enabled() || slices.Contains(maintenanceExemptPaths, r.URL.Path) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		slog.Debug("Request refused for maintenance", "path", r.URL.Path)
+
+		w.Header().Set("Retry-After", strconv.FormatInt(m.retryAfter.Load(), 10))
+		errorResponse(w, r, http.StatusServiceUnavailable, "Service is down for maintenance, try again later")
+	})
+}
@@ -0,0 +1,90 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kakkoyun/demo-web-service/models"
+)
+
+func TestMaintenanceMiddleware(t *testing.T) {
+	api, _ := newTestAPI(t, nil)
+	maintenance := NewMaintenance()
+	handler := maintenance.Middleware(api.Routes())
+
+	testCases := []struct {
+		name               string
+		method             string
+		path               string
+		enabled            bool
+		expectedStatus     int
+		expectedRetryAfter string
+	}{
+		{name: "Disabled", method: "GET", path: "/api/users", expectedStatus: http.StatusOK},
+		{name: "User List", method: "GET", path: "/api/users", enabled: true, expectedStatus: http.StatusServiceUnavailable, expectedRetryAfter: "90"},
+		{name: "User Creation", method: "POST", path: "/api/users", enabled: true, expectedStatus: http.StatusServiceUnavailable, expectedRetryAfter: "90"},
+		{name: "Home", method: "GET", path: "/", enabled: true, expectedStatus: http.StatusServiceUnavailable, expectedRetryAfter: "90"},
+		{name: "Unknown Path", method: "GET", path: "/nope", enabled: true, expectedStatus: http.StatusServiceUnavailable, expectedRetryAfter: "90"},
+		{name: "Liveness", method: "GET", path: "/api/health/live", enabled: true, expectedStatus: http.StatusOK},
+		{name: "Readiness", method: "GET", path: "/api/health/ready", enabled: true, expectedStatus: http.StatusServiceUnavailable, expectedRetryAfter: "90"},
+		{name: "Health", method: "GET", path: "/api/health", enabled: true, expectedStatus: http.StatusOK},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			maintenance.Set(tc.enabled, 90*time.Second)
+
+			req := httptest.NewRequest(tc.method, tc.path, strings.NewReader(`{"name":"Alice"}`))
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			if status := rr.Code; status != tc.expectedStatus {
+				t.Fatalf("handler returned wrong status code: got %v want %v", status, tc.expectedStatus)
+			}
+			if got := rr.Header().Get("Retry-After"); got != tc.expectedRetryAfter {
+				t.Errorf("handler returned wrong Retry-After header: got %q want %q", got, tc.expectedRetryAfter)
+			}
+			if tc.expectedStatus != http.StatusServiceUnavailable {
+				return
+			}
+
+			var response models.ErrorResponse
+			if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+				t.Fatalf("could not parse response body: %v", err)
+			}
+			if response.Code != "SERVICE_UNAVAILABLE" {
+				t.Errorf("handler returned wrong error code: got %v want %v", response.Code, "SERVICE_UNAVAILABLE")
+			}
+		})
+	}
+}
+
+func TestMaintenanceRetryAfter(t *testing.T) {
+	testCases := []struct {
+		retryAfter time.Duration
+		expected   string
+	}{
+		{retryAfter: 5 * time.Minute, expected: "300"},
+		{retryAfter: 1500 * time.Millisecond, expected: "2"},
+		{retryAfter: time.Millisecond, expected: "1"},
+		{retryAfter: 0, expected: "1"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.retryAfter.String(), func(t *testing.T) {
+			maintenance := NewMaintenance()
+			maintenance.Set(true, tc.retryAfter)
+
+			rr := httptest.NewRecorder()
+			maintenance.Middleware(http.NotFoundHandler()).ServeHTTP(rr, httptest.NewRequest("GET", "/api/users", nil))
+
+			if got := rr.Header().Get("Retry-After"); got != tc.expected {
+				t.Errorf("handler returned wrong Retry-After header: got %q want %q", got, tc.expected)
+			}
+		})
+	}
+}
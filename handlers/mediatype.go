@@ -0,0 +1,66 @@
+package handlers
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"mime"
+	"net/http"
+	"slices"
+	"strings"
+)
+
+// parseMediaType extracts the lowercased media type and its parameters from a
+// Content-Type or Accept entry, so "application/json; charset=utf-8" is
+// recognized as application/json. Malformed parameters are dropped rather
+// than hiding a valid media type; ok is false when the media type itself is invalid.
+func parseMediaType(value string) (mediaType string, params map[string]string, ok bool) {
+	mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(value))
+	if errors.Is(err, mime.ErrInvalidMediaParameter) {
+		return mediaType, map[string]string{}, true
+	}
+	if err != nil {
+		return "", nil, false
+	}
+	return mediaType, params, true
+}
+
+// ContentTypeMiddleware creates a middleware that rejects request bodies whose
+// Content-Type isn't one of the allowed media types with 415 Unsupported Media
+// Type. Parameters such as charset are ignored, and requests without a body
+// are let through, including chunked ones that turn out to be empty.
+func ContentTypeMiddleware(allowed ...string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.ContentLength == 0 || (r.ContentLength < 0 && emptyBody(r)) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			mediaType, _, ok := parseMediaType(r.Header.Get("Content-Type"))
+			if !ok || !slices.Contains(allowed, mediaType) {
+				errorResponse(w, r, http.StatusUnsupportedMediaType, "Unsupported content type, expected "+strings.Join(allowed, " or "))
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// emptyBody reports whether a request of unknown length, such as a chunked
+// one, has an empty body. It reads the first byte to find out, and puts it
+// back in front of the rest of the body.
+func emptyBody(r *http.Request) bool {
+	var first [1]byte
+	n, err := io.ReadFull(r.Body, first[:])
+	if n == 0 && errors.Is(err, io.EOF) {
+		return true
+	}
+
+	r.Body = struct {
+		io.Reader
+		io.Closer
+	}{io.MultiReader(bytes.NewReader(first[:n]), r.Body), r.Body}
+	return false
+}
@@ -0,0 +1,83 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestParseMediaType(t *testing.T) {
+	testCases := []struct {
+		name      string
+		value     string
+		mediaType string
+		charset   string
+		ok        bool
+	}{
+		{name: "Plain", value: "application/json", mediaType: "application/json", ok: true},
+		{name: "With Charset", value: "application/json; charset=utf-8", mediaType: "application/json", charset: "utf-8", ok: true},
+		{name: "Uppercase", value: "Application/JSON; Charset=UTF-8", mediaType: "application/json", charset: "UTF-8", ok: true},
+		{name: "Surrounding Whitespace", value: "  application/xml  ", mediaType: "application/xml", ok: true},
+		{name: "Malformed Parameter", value: "application/json; charset", mediaType: "application/json", ok: true},
+		{name: "Empty", value: "", ok: false},
+		{name: "Missing Subtype", value: "application/", ok: false},
+		{name: "Garbage", value: ";;;", ok: false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			mediaType, params, ok := parseMediaType(tc.value)
+			if ok != tc.ok {
+				t.Fatalf("parseMediaType(%q) ok = %v, want %v", tc.value, ok, tc.ok)
+			}
+			if mediaType != tc.mediaType {
+				t.Errorf("wrong media type: got %q want %q", mediaType, tc.mediaType)
+			}
+			if params["charset"] != tc.charset {
+				t.Errorf("wrong charset: got %q want %q", params["charset"], tc.charset)
+			}
+		})
+	}
+}
+
+func TestContentTypeMiddleware(t *testing.T) {
+	testCases := []struct {
+		name           string
+		contentType    string
+		body           string
+		chunked        bool
+		expectedStatus int
+	}{
+		{name: "JSON", contentType: "application/json", body: `{"name":"Test"}`, expectedStatus: http.StatusCreated},
+		{name: "JSON With Charset", contentType: "application/json; charset=utf-8", body: `{"name":"Test"}`, expectedStatus: http.StatusCreated},
+		{name: "Form", contentType: "application/x-www-form-urlencoded", body: "name=Test", expectedStatus: http.StatusUnsupportedMediaType},
+		{name: "Missing", contentType: "", body: `{"name":"Test"}`, expectedStatus: http.StatusUnsupportedMediaType},
+		{name: "No Body", contentType: "", body: "", expectedStatus: http.StatusBadRequest},
+		{name: "Chunked", contentType: "application/json", body: `{"name":"Test"}`, chunked: true, expectedStatus: http.StatusCreated},
+		{name: "Chunked Missing", contentType: "", body: `{"name":"Test"}`, chunked: true, expectedStatus: http.StatusUnsupportedMediaType},
+		{name: "Chunked No Body", contentType: "", body: "", chunked: true, expectedStatus: http.StatusBadRequest},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			api, _ := newTestAPI(t, nil)
+			handler := ContentTypeMiddleware("application/json")(api.Routes())
+
+			req := httptest.NewRequest("POST", "/api/users", strings.NewReader(tc.body))
+			if tc.contentType != "" {
+				req.Header.Set("Content-Type", tc.contentType)
+			}
+			if tc.chunked {
+				req.ContentLength = -1
+				req.TransferEncoding = []string{"chunked"}
+			}
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			if status := rr.Code; status != tc.expectedStatus {
+				t.Errorf("handler returned wrong status code: got %v want %v", status, tc.expectedStatus)
+			}
+		})
+	}
+}
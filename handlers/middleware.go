@@ -1,41 +1,126 @@
 package handlers
 
 import (
+	"bufio"
+	"context"
+	"fmt"
 	"log/slog"
+	"net"
 	"net/http"
+	"sync/atomic"
 	"time"
 )
 
-// LoggingMiddleware creates a middleware that logs request details
-func LoggingMiddleware(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		start := time.Now()
+// requestsServed counts every request completed by LoggingMiddleware
+var requestsServed atomic.Uint64
 
-		// Create a response wrapper to capture the status code
-		rw := newResponseWriter(w)
+// RequestsServed returns the total number of requests served since startup
+func RequestsServed() uint64 {
+	return requestsServed.Load()
+}
 
-		// Process the request
-		next.ServeHTTP(rw, r)
+// LoggingMiddleware creates a middleware that logs request details, including
+// the name of the handler that served the request when it is an API route.
+// Requests are logged at debug level, except those that take longer than
+// slowThreshold, which are logged as warnings with slow=true; a non-positive
+// slowThreshold never flags a request as slow.
+// Response sizes are measured where LoggingMiddleware wraps the writer, so it
+// must sit outside CompressionMiddleware: "size" is the number of body bytes
+// sent on the wire and "uncompressed_size" that of the body as written by the
+// handler, which CompressionMiddleware reports back through the request context.
+func LoggingMiddleware(slowThreshold time.Duration) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
 
-		// Calculate duration
-		duration := time.Since(start)
+			// Create a response wrapper to capture the status code and size
+			rw := newResponseWriter(w)
+			details := &requestDetails{}
+			r = r.WithContext(context.WithValue(r.Context(), requestDetailsKey, details))
 
-		// Log the request details
-		slog.Info("Request completed",
-			"method", r.Method,
-			"path", r.URL.Path,
-			"status", rw.statusCode,
-			"duration", duration,
-			"ip", r.RemoteAddr,
-			"user_agent", r.UserAgent(),
-		)
-	})
+			// Process the request
+			next.ServeHTTP(rw, r)
+
+			uncompressed := rw.bytesWritten
+			if details.compressed {
+				uncompressed = details.uncompressed
+			}
+
+			// Calculate duration
+			duration := time.Since(start)
+			requestsServed.Add(1)
+
+			attrs := traceIDs(r.Context())
+			if details.owner != "" {
+				attrs = append(attrs, "owner", details.owner)
+			}
+
+			level := slog.LevelDebug
+			if slowThreshold > 0 && duration > slowThreshold {
+				level = slog.LevelWarn
+				attrs = append(attrs, "slow", true)
+			}
+
+			// Log the request details
+			slog.Log(r.Context(), level, "Request completed", append([]any{
+				"request_id", RequestIDFromContext(r.Context()),
+				"method", r.Method,
+				"path", r.URL.Path,
+				"handler", details.handler,
+				"status", rw.statusCode,
+				"size", rw.bytesWritten,
+				"uncompressed_size", uncompressed,
+				"duration", duration,
+				"ip", r.RemoteAddr,
+				"user_agent", r.UserAgent(),
+			}, attrs...)...)
+		})
+	}
+}
+
+// requestDetails carries what inner handlers learn about a request back to
+// LoggingMiddleware: the name of the matched handler, from APIKeyMiddleware,
+// the owner of the API key and, from CompressionMiddleware, the uncompressed
+// size of the response
+type requestDetails struct {
+	handler      string
+	owner        string
+	uncompressed int64
+	compressed   bool
+}
+
+// recordUncompressedSize reports the size of a compressed response's original body
+// to LoggingMiddleware, if it is logging the request
+func recordUncompressedSize(ctx context.Context, n int64) {
+	if details, ok := ctx.Value(requestDetailsKey).(*requestDetails); ok {
+		details.uncompressed = n
+		details.compressed = true
+	}
+}
+
+// recordHandlerName reports the name of the handler serving a request
+// to LoggingMiddleware, if it is logging the request
+func recordHandlerName(ctx context.Context, name string) {
+	if details, ok := ctx.Value(requestDetailsKey).(*requestDetails); ok {
+		details.handler = name
+	}
+}
+
+// recordOwner reports the owner of the API key a request was made with
+// to LoggingMiddleware, if it is logging the request
+func recordOwner(ctx context.Context, owner string) {
+	if details, ok := ctx.Value(requestDetailsKey).(*requestDetails); ok {
+		details.owner = owner
+	}
 }
 
 // responseWriter is a wrapper for http.ResponseWriter that captures the status code
+// and the number of body bytes written
 type responseWriter struct {
 	http.ResponseWriter
-	statusCode int
+	statusCode   int
+	bytesWritten int64
+	wroteHeader  bool
 }
 
 // newResponseWriter creates a new responseWriter
@@ -46,8 +131,85 @@ func newResponseWriter(w http.ResponseWriter) *responseWriter {
 	}
 }
 
-// WriteHeader captures the status code before calling the underlying WriteHeader
+// WriteHeader captures the status code before calling the underlying WriteHeader.
+// Only the first call takes effect; later ones are logged and ignored.
+// Informational 1xx statuses such as 103 Early Hints precede the final status,
+// so they are passed through without being captured.
 func (rw *responseWriter) WriteHeader(statusCode int) {
+	if !rw.wroteHeader && isInformational(statusCode) {
+		rw.ResponseWriter.WriteHeader(statusCode)
+		return
+	}
+
+	if rw.wroteHeader {
+		slog.Warn("Ignoring superfluous WriteHeader call",
+			"status", rw.statusCode,
+			"ignored_status", statusCode)
+		return
+	}
+
+	rw.wroteHeader = true
 	rw.statusCode = statusCode
 	rw.ResponseWriter.WriteHeader(statusCode)
 }
+
+// Write counts the bytes written to the underlying writer.
+// Writing without calling WriteHeader first sends the implicit 200 status.
+func (rw *responseWriter) Write(b []byte) (int, error) {
+	rw.wroteHeader = true
+	n, err := rw.ResponseWriter.Write(b)
+	rw.bytesWritten += int64(n)
+	return n, err
+}
+
+// Flush sends any buffered data to the client, if the underlying writer supports it.
+// Like Write, flushing before WriteHeader sends the implicit 200 status.
+func (rw *responseWriter) Flush() {
+	rw.wroteHeader = true
+	if f, ok := rw.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// isInformational reports whether status is a 1xx status that can precede the
+// final one; 101 Switching Protocols is final, as the connection changes protocol
+func isInformational(status int) bool {
+	return status >= 100 && status < 200 && status != http.StatusSwitchingProtocols
+}
+
+// Hijack lets the caller take over the connection, if the underlying writer supports it
+func (rw *responseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h, ok := rw.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, fmt.Errorf("%w: response writer does not support hijacking", http.ErrNotSupported)
+	}
+	return h.Hijack()
+}
+
+// Unwrap returns the underlying writer, for use by http.ResponseController
+func (rw *responseWriter) Unwrap() http.ResponseWriter {
+	return rw.ResponseWriter
+}
+
+// HeadMiddleware creates a middleware that serves HEAD requests with the
+// handler registered for GET, keeping the headers but discarding the body
+func HeadMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodHead {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		next.ServeHTTP(headResponseWriter{ResponseWriter: w}, r)
+	})
+}
+
+// headResponseWriter is a wrapper for http.ResponseWriter that discards the response body
+type headResponseWriter struct {
+	http.ResponseWriter
+}
+
+// Write discards the body while reporting it as written
+func (hw headResponseWriter) Write(b []byte) (int, error) {
+	return len(b), nil
+}
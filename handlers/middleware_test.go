@@ -0,0 +1,567 @@
+package handlers
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/time/rate"
+
+	"github.com/kakkoyun/demo-web-service/models"
+)
+
+func TestRateLimitMiddleware(t *testing.T) {
+	okHandler := http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	// A very low rate so the bucket doesn't refill while the test runs
+	handler := RateLimitMiddleware(0.1, 3)(okHandler)
+
+	// Hammer the middleware from a single client
+	var limited *httptest.ResponseRecorder
+	allowed := 0
+	for i := 0; i < 10; i++ {
+		req := httptest.NewRequest("GET", "/api/users", nil)
+		req.RemoteAddr = "192.0.2.1:" + strconv.Itoa(10000+i)
+
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+
+		switch rr.Code {
+		case http.StatusOK:
+			allowed++
+		case http.StatusTooManyRequests:
+			if limited == nil {
+				limited = rr
+			}
+		default:
+			t.Fatalf("unexpected status code: got %v", rr.Code)
+		}
+	}
+
+	// Requests from different ports of the same IP share the burst
+	if allowed != 3 {
+		t.Errorf("wrong number of allowed requests: got %v want %v", allowed, 3)
+	}
+
+	if limited == nil {
+		t.Fatal("rate limit never kicked in")
+	}
+
+	retryAfter, err := strconv.Atoi(limited.Header().Get("Retry-After"))
+	if err != nil || retryAfter <= 0 {
+		t.Errorf("invalid Retry-After header: %q", limited.Header().Get("Retry-After"))
+	}
+
+	// Another client still has its own budget
+	req := httptest.NewRequest("GET", "/api/users", nil)
+	req.RemoteAddr = "192.0.2.2:10000"
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusOK {
+		t.Errorf("other client was rate limited: got %v want %v", rr.Code, http.StatusOK)
+	}
+}
+
+func TestIPRateLimiterSweepsIdleVisitors(t *testing.T) {
+	limiter := newIPRateLimiter(rate.Limit(1), 1, time.Minute)
+	start := time.Now()
+
+	limiter.allow("192.0.2.1", start)
+	limiter.allow("192.0.2.2", start.Add(30*time.Second))
+
+	if got := limiter.size(); got != 2 {
+		t.Fatalf("wrong number of visitors: got %v want %v", got, 2)
+	}
+
+	// Only the first visitor has been idle for longer than the TTL
+	limiter.allow("192.0.2.3", start.Add(80*time.Second))
+
+	if got := limiter.size(); got != 2 {
+		t.Errorf("idle visitor was not removed: got %v visitors want %v", got, 2)
+	}
+}
+
+func TestRequestIDMiddleware(t *testing.T) {
+	var seen string
+	handler := RequestIDMiddleware(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+		seen = RequestIDFromContext(r.Context())
+	}))
+
+	t.Run("Client Supplied ID", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/api/users", nil)
+		req.Header.Set(RequestIDHeader, "client-id-123")
+
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+
+		if seen != "client-id-123" {
+			t.Errorf("context has wrong request ID: got %q want %q", seen, "client-id-123")
+		}
+
+		if got := rr.Header().Get(RequestIDHeader); got != "client-id-123" {
+			t.Errorf("response has wrong request ID: got %q want %q", got, "client-id-123")
+		}
+	})
+
+	t.Run("Generated ID", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/api/users", nil)
+
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+
+		if len(seen) != 36 {
+			t.Errorf("generated request ID is not UUID formatted: got %q", seen)
+		}
+
+		if got := rr.Header().Get(RequestIDHeader); got != seen {
+			t.Errorf("response has wrong request ID: got %q want %q", got, seen)
+		}
+	})
+
+	t.Run("Oversized ID Is Replaced", func(t *testing.T) {
+		req := httptest.NewRequest("GET", "/api/users", nil)
+		req.Header.Set(RequestIDHeader, strings.Repeat("x", maxRequestIDLength+1))
+
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+
+		if len(seen) != 36 {
+			t.Errorf("oversized request ID was not replaced: got %d characters", len(seen))
+		}
+	})
+}
+
+func TestRequestIDFromContextMissing(t *testing.T) {
+	if id := RequestIDFromContext(context.Background()); id != "" {
+		t.Errorf("expected empty request ID, got %q", id)
+	}
+}
+
+func TestHeadMiddleware(t *testing.T) {
+	api, _ := newTestAPI(t, nil)
+	handler := HeadMiddleware(http.HandlerFunc(api.GetUsers))
+
+	// Get the full response for comparison
+	getRR := httptest.NewRecorder()
+	handler.ServeHTTP(getRR, httptest.NewRequest("GET", "/api/users", nil))
+
+	if getRR.Body.Len() == 0 {
+		t.Fatal("GET response has no body")
+	}
+
+	headRR := httptest.NewRecorder()
+	handler.ServeHTTP(headRR, httptest.NewRequest("HEAD", "/api/users", nil))
+
+	if status := headRR.Code; status != http.StatusOK {
+		t.Errorf("handler returned wrong status code: got %v want %v", status, http.StatusOK)
+	}
+
+	if contentType := headRR.Header().Get("Content-Type"); contentType != "application/json" {
+		t.Errorf("handler returned wrong content type: got %v want %v", contentType, "application/json")
+	}
+
+	expectedLength := strconv.Itoa(getRR.Body.Len())
+	if length := headRR.Header().Get("Content-Length"); length != expectedLength {
+		t.Errorf("handler returned wrong content length: got %v want %v", length, expectedLength)
+	}
+
+	if headRR.Body.Len() != 0 {
+		t.Errorf("HEAD response has a body: %q", headRR.Body.String())
+	}
+}
+
+func TestLoggingMiddlewareCountsRequests(t *testing.T) {
+	api, _ := newTestAPI(t, nil)
+	handler := LoggingMiddleware(0)(http.HandlerFunc(api.HealthCheck))
+	before := RequestsServed()
+
+	const requests = 5
+	for i := 0; i < requests; i++ {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/api/health", nil))
+	}
+
+	if got := RequestsServed() - before; got != requests {
+		t.Errorf("counter did not reflect requests: got %v want %v", got, requests)
+	}
+
+	// The health check reports the requests completed before it; its own request
+	// is only counted once it has been served
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest("GET", "/api/health", nil))
+
+	var response models.HealthResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+		t.Fatalf("could not parse response body: %v", err)
+	}
+
+	if response.RequestsServed != before+requests {
+		t.Errorf("health check returned wrong requests served: got %v want %v", response.RequestsServed, before+requests)
+	}
+}
+
+// captureLogs makes the default logger write JSON to the returned buffer for the duration of a test
+func captureLogs(t *testing.T) *bytes.Buffer {
+	t.Helper()
+
+	var buf bytes.Buffer
+	previous := slog.Default()
+	slog.SetDefault(slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug})))
+	t.Cleanup(func() { slog.SetDefault(previous) })
+
+	return &buf
+}
+
+func TestLoggingMiddlewareLogsResponseSizes(t *testing.T) {
+	body := strings.Repeat("a", 2000)
+
+	testCases := []struct {
+		name           string
+		acceptEncoding string
+		compressed     bool
+	}{
+		{name: "Gzip", acceptEncoding: "gzip", compressed: true},
+		{name: "Plain", acceptEncoding: "", compressed: false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			logs := captureLogs(t)
+
+			var handler http.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				_, _ = w.Write([]byte(body))
+			})
+			handler = CompressionMiddleware(1024)(handler)
+			handler = LoggingMiddleware(0)(handler)
+
+			req := httptest.NewRequest("GET", "/api/users", nil)
+			if tc.acceptEncoding != "" {
+				req.Header.Set("Accept-Encoding", tc.acceptEncoding)
+			}
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			var entry struct {
+				Msg              string `json:"msg"`
+				Size             int    `json:"size"`
+				UncompressedSize int    `json:"uncompressed_size"`
+			}
+			if err := json.Unmarshal(logs.Bytes(), &entry); err != nil {
+				t.Fatalf("could not parse log entry: %v", err)
+			}
+
+			if entry.Size != rr.Body.Len() {
+				t.Errorf("logged wrong wire size: got %v want %v", entry.Size, rr.Body.Len())
+			}
+			if entry.UncompressedSize != len(body) {
+				t.Errorf("logged wrong uncompressed size: got %v want %v", entry.UncompressedSize, len(body))
+			}
+			if compressed := entry.Size < entry.UncompressedSize; compressed != tc.compressed {
+				t.Errorf("wire size %v does not reflect compression for uncompressed size %v", entry.Size, entry.UncompressedSize)
+			}
+		})
+	}
+}
+
+func TestLoggingMiddlewareLogsHandlerName(t *testing.T) {
+	testCases := []struct {
+		name     string
+		method   string
+		path     string
+		expected string
+	}{
+		{name: "List Users", method: "GET", path: "/api/users", expected: "GetUsersHandler"},
+		{name: "Get User", method: "GET", path: "/api/users/1", expected: "GetUserHandler"},
+		{name: "Liveness", method: "GET", path: "/api/health/live", expected: "LivenessHandler"},
+		{name: "Method Not Allowed", method: "DELETE", path: "/api/users", expected: ""},
+		{name: "Unknown Path", method: "GET", path: "/nonexistent", expected: ""},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			logs := captureLogs(t)
+
+			api, _ := newTestAPI(t, nil)
+			handler := LoggingMiddleware(0)(api.Routes())
+
+			req := httptest.NewRequest(tc.method, tc.path, nil)
+			handler.ServeHTTP(httptest.NewRecorder(), req)
+
+			// The access log line is the last one, after the handler's own logs
+			lines := strings.Split(strings.TrimSpace(logs.String()), "\n")
+			var entry struct {
+				Msg     string `json:"msg"`
+				Handler string `json:"handler"`
+			}
+			if err := json.Unmarshal([]byte(lines[len(lines)-1]), &entry); err != nil {
+				t.Fatalf("could not parse log entry: %v", err)
+			}
+
+			if entry.Msg != "Request completed" {
+				t.Fatalf("last log entry is not the access log: got %q", entry.Msg)
+			}
+			if entry.Handler != tc.expected {
+				t.Errorf("logged wrong handler name: got %q want %q", entry.Handler, tc.expected)
+			}
+		})
+	}
+}
+
+func TestLoggingMiddlewareSlowRequests(t *testing.T) {
+	testCases := []struct {
+		name          string
+		delay         time.Duration
+		threshold     time.Duration
+		expectedLevel string
+		expectedSlow  bool
+	}{
+		{name: "Fast Request", threshold: time.Second, expectedLevel: "DEBUG"},
+		{name: "Slow Request", delay: 20 * time.Millisecond, threshold: 5 * time.Millisecond, expectedLevel: "WARN", expectedSlow: true},
+		{name: "Threshold Disabled", delay: 20 * time.Millisecond, expectedLevel: "DEBUG"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			logs := captureLogs(t)
+
+			handler := LoggingMiddleware(tc.threshold)(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+				time.Sleep(tc.delay)
+				w.WriteHeader(http.StatusOK)
+			}))
+			handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/api/users", nil))
+
+			var entry struct {
+				Msg   string `json:"msg"`
+				Level string `json:"level"`
+				Slow  bool   `json:"slow"`
+			}
+			if err := json.Unmarshal(logs.Bytes(), &entry); err != nil {
+				t.Fatalf("could not parse log entry: %v", err)
+			}
+
+			if entry.Msg != "Request completed" {
+				t.Fatalf("log entry is not the access log: got %q", entry.Msg)
+			}
+			if entry.Level != tc.expectedLevel {
+				t.Errorf("access log has wrong level: got %v want %v", entry.Level, tc.expectedLevel)
+			}
+			if entry.Slow != tc.expectedSlow {
+				t.Errorf("access log has wrong slow attribute: got %v want %v", entry.Slow, tc.expectedSlow)
+			}
+		})
+	}
+}
+
+// hijackableRecorder is a ResponseRecorder whose connection can be hijacked
+type hijackableRecorder struct {
+	*httptest.ResponseRecorder
+	hijacked bool
+}
+
+func (h *hijackableRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h.hijacked = true
+	return nil, nil, nil
+}
+
+func TestResponseWriterFlush(t *testing.T) {
+	rr := httptest.NewRecorder()
+	rw := newResponseWriter(rr)
+
+	var w http.ResponseWriter = rw
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		t.Fatal("responseWriter does not implement http.Flusher")
+	}
+
+	_, _ = rw.Write([]byte("data: hello\n\n"))
+	flusher.Flush()
+
+	if !rr.Flushed {
+		t.Error("flush was not passed through to the underlying writer")
+	}
+
+	// Flushing a writer without flush support is a no-op
+	newResponseWriter(struct{ http.ResponseWriter }{rr}).Flush()
+}
+
+func TestResponseWriterHijack(t *testing.T) {
+	hr := &hijackableRecorder{ResponseRecorder: httptest.NewRecorder()}
+	if _, _, err := newResponseWriter(hr).Hijack(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !hr.hijacked {
+		t.Error("hijack was not passed through to the underlying writer")
+	}
+
+	// ResponseRecorder does not support hijacking
+	if _, _, err := newResponseWriter(httptest.NewRecorder()).Hijack(); !errors.Is(err, http.ErrNotSupported) {
+		t.Errorf("wrong error: got %v want %v", err, http.ErrNotSupported)
+	}
+}
+
+func TestResponseWriterResponseController(t *testing.T) {
+	rr := httptest.NewRecorder()
+
+	// ResponseController finds the underlying writer's capabilities through Unwrap
+	if err := http.NewResponseController(newResponseWriter(rr)).Flush(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !rr.Flushed {
+		t.Error("flush was not passed through to the underlying writer")
+	}
+}
+
+func TestLoggingMiddlewareLogsSizeWithoutWriteHeader(t *testing.T) {
+	logs := captureLogs(t)
+
+	const body = `{"message":"written without WriteHeader"}`
+	handler := LoggingMiddleware(0)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Write in two parts, relying on the implicit 200
+		_, _ = w.Write([]byte(body[:10]))
+		_, _ = w.Write([]byte(body[10:]))
+	}))
+
+	req := httptest.NewRequest("GET", "/", nil)
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	var entry struct {
+		Status int `json:"status"`
+		Size   int `json:"size"`
+	}
+	if err := json.Unmarshal(logs.Bytes(), &entry); err != nil {
+		t.Fatalf("could not parse log entry: %v", err)
+	}
+
+	if entry.Status != http.StatusOK {
+		t.Errorf("logged wrong status: got %v want %v", entry.Status, http.StatusOK)
+	}
+	if entry.Size != len(body) {
+		t.Errorf("logged wrong size: got %v want %v", entry.Size, len(body))
+	}
+}
+
+func TestLoggingMiddlewareLogsImplicitStatus(t *testing.T) {
+	testCases := []struct {
+		handler  http.HandlerFunc
+		name     string
+		expected int
+	}{
+		{
+			name: "Write Only",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				_, _ = w.Write([]byte("ok"))
+			},
+			expected: http.StatusOK,
+		},
+		{
+			name:     "Nothing Written",
+			handler:  func(w http.ResponseWriter, r *http.Request) {},
+			expected: http.StatusOK,
+		},
+		{
+			name: "No Content",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusNoContent)
+			},
+			expected: http.StatusNoContent,
+		},
+		{
+			name: "Early Hints Before Final Status",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Link", "</style.css>; rel=preload; as=style")
+				w.WriteHeader(http.StatusEarlyHints)
+				w.WriteHeader(http.StatusCreated)
+				_, _ = w.Write([]byte("created"))
+			},
+			expected: http.StatusCreated,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			logs := captureLogs(t)
+
+			req := httptest.NewRequest("GET", "/", nil)
+			LoggingMiddleware(0)(tc.handler).ServeHTTP(httptest.NewRecorder(), req)
+
+			var entry struct {
+				Status int `json:"status"`
+			}
+			if err := json.Unmarshal(logs.Bytes(), &entry); err != nil {
+				t.Fatalf("could not parse log entry: %v", err)
+			}
+
+			if entry.Status != tc.expected {
+				t.Errorf("logged wrong status: got %v want %v", entry.Status, tc.expected)
+			}
+			if strings.Contains(logs.String(), "Ignoring superfluous WriteHeader call") {
+				t.Errorf("status was logged as superfluous: %s", logs.String())
+			}
+		})
+	}
+}
+
+func TestResponseWriterIgnoresSuperfluousWriteHeader(t *testing.T) {
+	testCases := []struct {
+		write    func(w http.ResponseWriter)
+		name     string
+		expected int
+	}{
+		{
+			name: "WriteHeader Twice",
+			write: func(w http.ResponseWriter) {
+				w.WriteHeader(http.StatusCreated)
+				w.WriteHeader(http.StatusInternalServerError)
+			},
+			expected: http.StatusCreated,
+		},
+		{
+			name: "WriteHeader After Write",
+			write: func(w http.ResponseWriter) {
+				_, _ = w.Write([]byte("ok"))
+				w.WriteHeader(http.StatusInternalServerError)
+			},
+			expected: http.StatusOK,
+		},
+		{
+			name: "WriteHeader After Flush",
+			write: func(w http.ResponseWriter) {
+				w.(http.Flusher).Flush()
+				w.WriteHeader(http.StatusInternalServerError)
+			},
+			expected: http.StatusOK,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			logs := captureLogs(t)
+
+			rr := httptest.NewRecorder()
+			rw := newResponseWriter(rr)
+			tc.write(rw)
+
+			if rw.statusCode != tc.expected {
+				t.Errorf("captured wrong status code: got %v want %v", rw.statusCode, tc.expected)
+			}
+			if rr.Code != tc.expected {
+				t.Errorf("sent wrong status code: got %v want %v", rr.Code, tc.expected)
+			}
+			if !strings.Contains(logs.String(), "Ignoring superfluous WriteHeader call") {
+				t.Errorf("second WriteHeader call was not logged: %s", logs.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,57 @@
+package handlers
+
+import (
+	"bytes"
+	"crypto/sha256"
+	_ "embed"
+	"encoding/base64"
+	"net/http"
+	"strings"
+)
+
+// openAPISpec is the OpenAPI 3 description of the API. It is written by hand,
+// and a test checks that it documents every route the API serves.
+//
+//go:embed openapi.json
+var openAPISpec []byte
+
+// docsPage is a Swagger UI page rendering openAPISpec. Swagger UI is loaded
+// from a CDN, pinned to an exact version.
+//
+//go:embed docs.html
+var docsPage []byte
+
+// swaggerUIBase is where docsPage loads the pinned version of Swagger UI from
+const swaggerUIBase = "https://unpkg.com/swagger-ui-dist@5.17.14/"
+
+// docsPolicy is the Content-Security-Policy of docsPage, replacing the one
+// configured for the API: scripts and styles may only come from the pinned
+// Swagger UI release, besides the page's own inline script, and requests may
+// only go back to this server
+var docsPolicy = "default-src 'none'; " +
+	"script-src " + swaggerUIBase + " '" + inlineScriptHash(docsPage) + "'; " +
+	"style-src " + swaggerUIBase + " 'unsafe-inline'; " +
+	"img-src 'self' data:; " +
+	"connect-src 'self'; " +
+	"base-uri 'none'; " +
+	"form-action 'none'; " +
+	"frame-ancestors 'none'"
+
+// inlineScriptHash returns the CSP hash source of the first inline script of page
+func inlineScriptHash(page []byte) string {
+	_, rest, _ := strings.Cut(string(page), "<script>")
+	script, _, _ := strings.Cut(rest, "</script>")
+	sum := sha256.Sum256([]byte(script))
+	return "sha256-" + base64.StdEncoding.EncodeToString(sum[:])
+}
+
+// OpenAPI serves the OpenAPI description of the API
+func OpenAPI(w http.ResponseWriter, _ *http.Request) {
+	writeBody(w, http.StatusOK, "application/json", bytes.NewBuffer(openAPISpec))
+}
+
+// Docs serves a Swagger UI page for browsing the OpenAPI description
+func Docs(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Security-Policy", docsPolicy)
+	writeBody(w, http.StatusOK, "text/html; charset=utf-8", bytes.NewBuffer(docsPage))
+}
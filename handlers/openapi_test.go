@@ -0,0 +1,163 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// openAPIDocument is the part of an OpenAPI document the tests look at
+type openAPIDocument struct {
+	Paths   map[string]map[string]json.RawMessage `json:"paths"`
+	OpenAPI string                                `json:"openapi"`
+}
+
+// fetchOpenAPISpec gets and parses the OpenAPI document served by api
+func fetchOpenAPISpec(t *testing.T, api *API) openAPIDocument {
+	t.Helper()
+
+	rr := httptest.NewRecorder()
+	api.Routes().ServeHTTP(rr, httptest.NewRequest("GET", "/openapi.json", nil))
+
+	if status := rr.Code; status != http.StatusOK {
+		t.Fatalf("handler returned wrong status code: got %v want %v", status, http.StatusOK)
+	}
+	if ct := rr.Header().Get("Content-Type"); ct != "application/json" {
+		t.Errorf("handler returned wrong content type: got %q want %q", ct, "application/json")
+	}
+
+	var doc openAPIDocument
+	if err := json.Unmarshal(rr.Body.Bytes(), &doc); err != nil {
+		t.Fatalf("OpenAPI document is not valid JSON: %v", err)
+	}
+	return doc
+}
+
+func TestOpenAPISpec(t *testing.T) {
+	api, _ := newTestAPI(t, nil)
+	doc := fetchOpenAPISpec(t, api)
+
+	if !strings.HasPrefix(doc.OpenAPI, "3.0.") {
+		t.Errorf("document has wrong OpenAPI version: got %q want 3.0.x", doc.OpenAPI)
+	}
+
+	expected := map[string][]string{
+		"/api/users":        {"get", "post"},
+		"/api/users/count":  {"get"},
+		"/api/users/stream": {"get"},
+		"/api/users/events": {"get"},
+		"/api/users/import": {"post"},
+		"/api/users/{id}":   {"get", "patch"},
+		"/api/health":       {"get"},
+		"/api/health/live":  {"get"},
+		"/api/health/ready": {"get"},
+		"/api/version":      {"get"},
+	}
+	for path, methods := range expected {
+		for _, method := range methods {
+			if _, ok := doc.Paths[path][method]; !ok {
+				t.Errorf("OpenAPI document is missing %s %s", strings.ToUpper(method), path)
+			}
+		}
+	}
+}
+
+func TestOpenAPISpecDocumentsRoutes(t *testing.T) {
+	api, _ := newTestAPI(t, nil)
+	doc := fetchOpenAPISpec(t, api)
+
+	// The documentation itself and debug-only endpoints aren't part of the API
+	undocumented := map[string]bool{
+		"/openapi.json":    true,
+		"/docs":            true,
+		"/api/admin/reset": true,
+	}
+
+	for _, rt := range api.routes() {
+		if undocumented[rt.pattern] {
+			continue
+		}
+
+		// ServeMux patterns use the same {name} wildcards as OpenAPI paths
+		path := strings.TrimSuffix(rt.pattern, "{$}")
+		if path == "" {
+			path = "/"
+		}
+		if _, ok := doc.Paths[path][strings.ToLower(rt.method)]; !ok {
+			t.Errorf("route %s %s (%s) is not in the OpenAPI document", rt.method, rt.pattern, rt.name)
+		}
+	}
+}
+
+func TestOpenAPISpecReferencesResolve(t *testing.T) {
+	var doc map[string]any
+	if err := json.Unmarshal(openAPISpec, &doc); err != nil {
+		t.Fatalf("OpenAPI document is not valid JSON: %v", err)
+	}
+
+	var check func(v any)
+	check = func(v any) {
+		switch v := v.(type) {
+		case map[string]any:
+			if ref, ok := v["$ref"].(string); ok && !resolvesInDocument(doc, ref) {
+				t.Errorf("reference %q does not resolve", ref)
+			}
+			for _, child := range v {
+				check(child)
+			}
+		case []any:
+			for _, child := range v {
+				check(child)
+			}
+		}
+	}
+	check(doc)
+}
+
+// resolvesInDocument reports whether ref, a local JSON pointer such as
+// "#/components/schemas/User", points at a value in doc
+func resolvesInDocument(doc map[string]any, ref string) bool {
+	pointer, ok := strings.CutPrefix(ref, "#/")
+	if !ok {
+		return false
+	}
+
+	var node any = doc
+	for _, token := range strings.Split(pointer, "/") {
+		object, ok := node.(map[string]any)
+		if !ok {
+			return false
+		}
+		if node, ok = object[token]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+func TestDocsPage(t *testing.T) {
+	api, _ := newTestAPI(t, nil)
+
+	rr := httptest.NewRecorder()
+	api.Routes().ServeHTTP(rr, httptest.NewRequest("GET", "/docs", nil))
+
+	if status := rr.Code; status != http.StatusOK {
+		t.Fatalf("handler returned wrong status code: got %v want %v", status, http.StatusOK)
+	}
+	if ct := rr.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/html") {
+		t.Errorf("handler returned wrong content type: got %q want text/html", ct)
+	}
+	if !strings.Contains(rr.Body.String(), `url: "/openapi.json"`) {
+		t.Errorf("docs page does not point at the OpenAPI document: %s", rr.Body)
+	}
+
+	csp := rr.Header().Get("Content-Security-Policy")
+	if !strings.Contains(csp, "'sha256-DYj02ueSF3nRkeIO+boPvZQ8NOQwaBf1D7vAcDEdq4c='") {
+		t.Errorf("Content-Security-Policy does not allow the page's inline script: %q", csp)
+	}
+	if strings.Count(rr.Body.String(), swaggerUIBase) != 2 {
+		t.Errorf("docs page does not load Swagger UI from %s: %s", swaggerUIBase, rr.Body)
+	}
+}
@@ -0,0 +1,141 @@
+package handlers
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/kakkoyun/demo-web-service/models"
+)
+
+const (
+	// DefaultPageSize is the number of users on a page when a paginated
+	// request doesn't give a limit
+	DefaultPageSize = 50
+	// MaxPageSize is the largest limit a paginated request may ask for
+	MaxPageSize = 100
+)
+
+// Pagination errors
+var (
+	ErrInvalidCursor = errors.New("invalid cursor")
+	ErrInvalidLimit  = errors.New("invalid limit")
+)
+
+// page is the slice of a user listing a request asks for
+type page struct {
+	// after is the ID of the last user on the previous page; 0 starts at the beginning
+	after int
+	limit int
+}
+
+// parsePage reads the cursor and limit query parameters. It reports false
+// when the request gives neither, meaning it wants the whole listing.
+func parsePage(r *http.Request) (page, bool, error) {
+	query := r.URL.Query()
+	if !query.Has("cursor") && !query.Has("limit") {
+		return page{}, false, nil
+	}
+
+	p := page{limit: DefaultPageSize}
+	if cursor := query.Get("cursor"); cursor != "" {
+		after, err := decodeCursor(cursor)
+		if err != nil {
+			return page{}, true, err
+		}
+		p.after = after
+	}
+	if limit := query.Get("limit"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil || n < 1 || n > MaxPageSize {
+			return page{}, true, fmt.Errorf("%w: %q is not a number between 1 and %d", ErrInvalidLimit, limit, MaxPageSize)
+		}
+		p.limit = n
+	}
+	return p, true, nil
+}
+
+// slice returns the users of the page from users, which must be sorted by
+// ID, and the pages before and after it, which are nil on the first and last
+// page respectively. Paging by ID rather than by offset means users created
+// in the meantime don't shift pages, so no user is skipped or repeated.
+func (p page) slice(users []models.User) (items []models.User, prev, next *page) {
+	start := 0
+	for start < len(users) && users[start].ID <= p.after {
+		start++
+	}
+	if start > 0 {
+		prev = &page{limit: p.limit}
+		if prevStart := start - p.limit; prevStart > 0 {
+			prev.after = users[prevStart-1].ID
+		}
+	}
+	users = users[start:]
+
+	if len(users) <= p.limit {
+		return users, prev, nil
+	}
+	users = users[:p.limit]
+	return users, prev, &page{after: users[len(users)-1].ID, limit: p.limit}
+}
+
+// cursor returns the cursor of the page, or an empty string for the first page
+func (p *page) cursor() string {
+	if p == nil || p.after == 0 {
+		return ""
+	}
+	return encodeCursor(p.after)
+}
+
+// pageLinks returns a Link header value (RFC 8288) with the URLs of the next
+// and previous pages, or an empty string when there are neither. The URLs
+// keep the other query parameters of the request.
+func pageLinks(r *http.Request, prev, next *page) string {
+	var links []string
+	for _, rel := range []struct {
+		page *page
+		name string
+	}{
+		{page: next, name: "next"},
+		{page: prev, name: "prev"},
+	} {
+		if rel.page == nil {
+			continue
+		}
+
+		query := r.URL.Query()
+		// The limit is always given, since without it or a cursor the
+		// first page would turn into the whole listing
+		query.Set("limit", strconv.Itoa(rel.page.limit))
+		if cursor := rel.page.cursor(); cursor != "" {
+			query.Set("cursor", cursor)
+		} else {
+			query.Del("cursor")
+		}
+		link := url.URL{Path: r.URL.Path, RawQuery: query.Encode()}
+		links = append(links, fmt.Sprintf("<%s>; rel=%q", link.String(), rel.name))
+	}
+	return strings.Join(links, ", ")
+}
+
+// encodeCursor returns the opaque cursor of the page after the user with the given ID
+func encodeCursor(id int) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(strconv.Itoa(id)))
+}
+
+// decodeCursor returns the user ID a cursor made by encodeCursor points after
+func decodeCursor(cursor string) (int, error) {
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %q", ErrInvalidCursor, cursor)
+	}
+	id, err := strconv.Atoi(string(data))
+	if err != nil || id < 1 {
+		return 0, fmt.Errorf("%w: %q", ErrInvalidCursor, cursor)
+	}
+	return id, nil
+}
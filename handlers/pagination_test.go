@@ -0,0 +1,259 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/kakkoyun/demo-web-service/models"
+)
+
+func TestGetUsersCursorPagination(t *testing.T) {
+	var seed []models.User
+	for id := 1; id <= 7; id++ {
+		seed = append(seed, models.User{ID: id, Name: fmt.Sprintf("User %d", id)})
+	}
+	api, users := newTestAPI(t, nil, seed...)
+	handler := api.Routes()
+
+	seen := map[int]bool{}
+	var ids []int
+	cursor := ""
+	for pages := 0; ; pages++ {
+		if pages > 10 {
+			t.Fatal("pagination did not terminate")
+		}
+
+		req := httptest.NewRequest("GET", "/api/users?limit=3&cursor="+url.QueryEscape(cursor), nil)
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+
+		if status := rr.Code; status != http.StatusOK {
+			t.Fatalf("handler returned wrong status code: got %v want %v", status, http.StatusOK)
+		}
+
+		var response models.UserListResponse
+		if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+			t.Fatalf("could not parse response body: %v", err)
+		}
+		if response.Pagination == nil {
+			t.Fatal("handler returned no pagination metadata")
+		}
+		if response.Count != len(response.Users) || response.Count > 3 {
+			t.Errorf("handler returned wrong page size: count %d, %d users", response.Count, len(response.Users))
+		}
+
+		for _, user := range response.Users {
+			if seen[user.ID] {
+				t.Errorf("user %d returned twice", user.ID)
+			}
+			seen[user.ID] = true
+			ids = append(ids, user.ID)
+		}
+
+		// A user created while paging lands after the cursor instead of shifting pages
+		if pages == 0 {
+			if _, err := users.Create(t.Context(), models.User{Name: "Late User"}); err != nil {
+				t.Fatalf("Failed to create user: %v", err)
+			}
+		}
+
+		cursor = response.Pagination.NextCursor
+		if cursor == "" {
+			break
+		}
+	}
+
+	want := []int{1, 2, 3, 4, 5, 6, 7, 8}
+	if fmt.Sprint(ids) != fmt.Sprint(want) {
+		t.Errorf("paging returned wrong users: got %v want %v", ids, want)
+	}
+}
+
+func TestGetUsersPaginationParameters(t *testing.T) {
+	testCases := []struct {
+		name           string
+		query          string
+		expectedCode   string
+		expectedStatus int
+		paginated      bool
+	}{
+		{name: "Whole Listing", query: "", expectedStatus: http.StatusOK},
+		{name: "Default Limit", query: "?cursor=", expectedStatus: http.StatusOK, paginated: true},
+		{name: "Cursor Past The End", query: "?cursor=" + encodeCursor(100), expectedStatus: http.StatusOK, paginated: true},
+		{name: "Cursor Not Base64", query: "?cursor=%25%25", expectedStatus: http.StatusBadRequest, expectedCode: CodeInvalidCursor},
+		{name: "Cursor Not An ID", query: "?cursor=" + encodeCursor(0), expectedStatus: http.StatusBadRequest, expectedCode: CodeInvalidCursor},
+		{name: "Zero Limit", query: "?limit=0", expectedStatus: http.StatusBadRequest, expectedCode: CodeInvalidLimit},
+		{name: "Limit Too Large", query: "?limit=1000", expectedStatus: http.StatusBadRequest, expectedCode: CodeInvalidLimit},
+	}
+
+	api, _ := newTestAPI(t, nil)
+	handler := api.Routes()
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/api/users"+tc.query, nil)
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			if status := rr.Code; status != tc.expectedStatus {
+				t.Fatalf("handler returned wrong status code: got %v want %v", status, tc.expectedStatus)
+			}
+			if tc.expectedCode != "" && !strings.Contains(rr.Body.String(), tc.expectedCode) {
+				t.Errorf("handler returned wrong error code: got %s want %s", rr.Body.String(), tc.expectedCode)
+			}
+			if tc.expectedStatus != http.StatusOK {
+				return
+			}
+
+			var response models.UserListResponse
+			if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+				t.Fatalf("could not parse response body: %v", err)
+			}
+			if paginated := response.Pagination != nil; paginated != tc.paginated {
+				t.Errorf("handler returned wrong pagination metadata: got %+v", response.Pagination)
+			}
+		})
+	}
+}
+
+func TestGetUsersLinkHeader(t *testing.T) {
+	var seed []models.User
+	for id := 1; id <= 10; id++ {
+		seed = append(seed, models.User{ID: id, Name: fmt.Sprintf("User %d", id)})
+	}
+	api, _ := newTestAPI(t, nil, seed...)
+	handler := api.Routes()
+
+	// Pages of 3 are users 1-3, 4-6, 7-9 and 10
+	testCases := []struct {
+		name         string
+		query        string
+		expectedLink string
+	}{
+		{
+			name:         "Whole Listing",
+			query:        "",
+			expectedLink: "",
+		},
+		{
+			name:         "First Page",
+			query:        "?limit=3&pretty=false",
+			expectedLink: `</api/users?cursor=` + encodeCursor(3) + `&limit=3&pretty=false>; rel="next"`,
+		},
+		{
+			name:  "Second Page",
+			query: "?limit=3&cursor=" + encodeCursor(3) + "&pretty=false",
+			expectedLink: `</api/users?cursor=` + encodeCursor(6) + `&limit=3&pretty=false>; rel="next", ` +
+				`</api/users?limit=3&pretty=false>; rel="prev"`,
+		},
+		{
+			name:  "Middle Page",
+			query: "?limit=3&cursor=" + encodeCursor(6) + "&pretty=false",
+			expectedLink: `</api/users?cursor=` + encodeCursor(9) + `&limit=3&pretty=false>; rel="next", ` +
+				`</api/users?cursor=` + encodeCursor(3) + `&limit=3&pretty=false>; rel="prev"`,
+		},
+		{
+			name:         "Last Page",
+			query:        "?limit=3&cursor=" + encodeCursor(9) + "&pretty=false",
+			expectedLink: `</api/users?cursor=` + encodeCursor(6) + `&limit=3&pretty=false>; rel="prev"`,
+		},
+		{
+			name:         "Only Page",
+			query:        "?limit=50",
+			expectedLink: "",
+		},
+		{
+			name:         "Default Limit",
+			query:        "?cursor=" + encodeCursor(9),
+			expectedLink: `</api/users?limit=50>; rel="prev"`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/api/users"+tc.query, nil)
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			if status := rr.Code; status != http.StatusOK {
+				t.Fatalf("handler returned wrong status code: got %v want %v", status, http.StatusOK)
+			}
+			if link := rr.Header().Get("Link"); link != tc.expectedLink {
+				t.Errorf("handler returned wrong Link header:\ngot  %s\nwant %s", link, tc.expectedLink)
+			}
+		})
+	}
+}
+
+func TestGetUsersLinkHeaderFollowsPages(t *testing.T) {
+	var seed []models.User
+	for id := 1; id <= 7; id++ {
+		seed = append(seed, models.User{ID: id, Name: fmt.Sprintf("User %d", id)})
+	}
+	api, _ := newTestAPI(t, nil, seed...)
+	handler := api.Routes()
+
+	// getPage returns the IDs of the users on the page at target and its links by rel
+	getPage := func(target string) ([]int, map[string]string) {
+		t.Helper()
+
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, httptest.NewRequest("GET", target, nil))
+		if status := rr.Code; status != http.StatusOK {
+			t.Fatalf("handler returned wrong status code: got %v want %v", status, http.StatusOK)
+		}
+
+		var response models.UserListResponse
+		if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+			t.Fatalf("could not parse response body: %v", err)
+		}
+		var ids []int
+		for _, user := range response.Users {
+			ids = append(ids, user.ID)
+		}
+
+		links := map[string]string{}
+		for link := range strings.SplitSeq(rr.Header().Get("Link"), ", ") {
+			target, rel, ok := strings.Cut(link, "; rel=")
+			if ok {
+				links[strings.Trim(rel, `"`)] = strings.Trim(target, "<>")
+			}
+		}
+		return ids, links
+	}
+
+	// Walk forward to the last page, then back to the first
+	var forward, backward []string
+	target := "/api/users?limit=3"
+	for {
+		ids, links := getPage(target)
+		forward = append(forward, fmt.Sprint(ids))
+		if links["next"] == "" {
+			break
+		}
+		target = links["next"]
+	}
+	for {
+		ids, links := getPage(target)
+		backward = append(backward, fmt.Sprint(ids))
+		if links["prev"] == "" {
+			break
+		}
+		target = links["prev"]
+	}
+
+	want := []string{"[1 2 3]", "[4 5 6]", "[7]"}
+	if fmt.Sprint(forward) != fmt.Sprint(want) {
+		t.Errorf("following next links returned wrong pages: got %v want %v", forward, want)
+	}
+	slices.Reverse(want)
+	if fmt.Sprint(backward) != fmt.Sprint(want) {
+		t.Errorf("following prev links returned wrong pages: got %v want %v", backward, want)
+	}
+}
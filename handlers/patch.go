@@ -0,0 +1,124 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"braces.dev/errtrace"
+
+	"github.com/kakkoyun/demo-web-service/models"
+	"github.com/kakkoyun/demo-web-service/store"
+)
+
+// PatchUser partially updates a user with a JSON object holding only the
+// fields to change, responding with the merged user.
+// Absent fields are left unchanged and unknown fields are rejected.
+// A body with Content-Type application/json-patch+json is instead applied as
+// a JSON Patch; if one of its test operations fails, nothing is changed and
+// the client gets a 409.
+func (a *API) PatchUser(w http.ResponseWriter, r *http.Request) {
+	a.log(r.Context()).Info("Patching user", "id", r.PathValue("id"), "path", r.URL.Path)
+
+	id, ok := a.pathUserID(w, r)
+	if !ok {
+		return
+	}
+
+	apply, err := decodePatch(r)
+	if err != nil {
+		statusCode := http.StatusBadRequest
+		if errors.Is(err, ErrBodyTooLarge) {
+			statusCode = http.StatusRequestEntityTooLarge
+		}
+
+		a.log(r.Context()).Error("Invalid user patch",
+			"id", id,
+			"error", err,
+			"status", statusCode)
+		errorResponseFor(w, r, statusCode, err, err.Error())
+		return
+	}
+
+	user, err := a.users.Get(r.Context(), id)
+	if err == nil {
+		user, err = apply(user)
+	}
+	if errors.Is(err, ErrPatchTestFailed) {
+		a.log(r.Context()).Warn("User patch test failed",
+			"id", id,
+			"error", err)
+		errorResponseFor(w, r, http.StatusConflict, err, err.Error())
+		return
+	}
+	if err == nil {
+		err = validateUser(user)
+	}
+	if err == nil {
+		err = a.validateUserSchema(user)
+	}
+	if errors.Is(err, ErrValidation) {
+		a.log(r.Context()).Error("Patched user is invalid",
+			"id", id,
+			"error", err)
+		errorResponseFor(w, r, http.StatusUnprocessableEntity, err, err.Error())
+		return
+	}
+	if err == nil {
+		user, err = a.users.Update(r.Context(), user)
+	}
+	if errors.Is(err, store.ErrNotFound) {
+		notFoundErr := fmt.Errorf("%w: ID %d", ErrUserNotFound, id)
+		a.log(r.Context()).Error("User not found",
+			"id", id,
+			"error", notFoundErr)
+
+		errorResponseFor(w, r, http.StatusNotFound, notFoundErr, fmt.Sprintf("User with ID %d not found", id))
+		return
+	}
+	if err != nil {
+		a.log(r.Context()).Error("Failed to patch user",
+			"id", id,
+			"error", err)
+		errorResponse(w, r, http.StatusInternalServerError, "Failed to update user")
+		return
+	}
+
+	response := models.UserResponse{
+		Status:  "success",
+		Message: "User updated successfully",
+		User:    &user,
+	}
+
+	respond(w, r, http.StatusOK, response)
+}
+
+// decodePatch decodes the body of a patch request, as a JSON Patch or a plain
+// user patch depending on its Content-Type, returning a function applying it
+func decodePatch(r *http.Request) (func(models.User) (models.User, error), error) {
+	if isJSONPatch(r) {
+		patch, err := decodeJSONPatch(r)
+		if err != nil {
+			return nil, errtrace.Wrap(err)
+		}
+		return patch.Apply, nil
+	}
+
+	patch, err := decodeUserPatch(r)
+	if err != nil {
+		return nil, errtrace.Wrap(err)
+	}
+	return func(user models.User) (models.User, error) {
+		return patch.Apply(user), nil
+	}, nil
+}
+
+// decodeUserPatch decodes and validates the body of a user patch
+func decodeUserPatch(r *http.Request) (models.UserPatch, error) {
+	var patch models.UserPatch
+	if err := decodeJSONStrict(r, &patch); err != nil {
+		return models.UserPatch{}, errtrace.Wrap(err)
+	}
+
+	return patch, nil
+}
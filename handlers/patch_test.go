@@ -0,0 +1,134 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/kakkoyun/demo-web-service/models"
+)
+
+func TestPatchUserHandler(t *testing.T) {
+	testCases := []struct {
+		name           string
+		path           string
+		body           string
+		expectedName   string
+		expectedStatus int
+	}{
+		{name: "Name Only", path: "/api/users/1", body: `{"name":"Johnny Doe"}`, expectedStatus: http.StatusOK, expectedName: "Johnny Doe"},
+		{name: "No Fields", path: "/api/users/1", body: `{}`, expectedStatus: http.StatusOK, expectedName: "John Doe"},
+		{name: "Unknown Field", path: "/api/users/1", body: `{"name":"Johnny Doe","email":"john@example.com"}`, expectedStatus: http.StatusBadRequest, expectedName: "John Doe"},
+		{name: "ID Cannot Change", path: "/api/users/1", body: `{"id":7}`, expectedStatus: http.StatusBadRequest, expectedName: "John Doe"},
+		{name: "Empty Name", path: "/api/users/1", body: `{"name":"  "}`, expectedStatus: http.StatusUnprocessableEntity, expectedName: "John Doe"},
+		{name: "Empty Body", path: "/api/users/1", body: ``, expectedStatus: http.StatusBadRequest, expectedName: "John Doe"},
+		{name: "Invalid ID", path: "/api/users/abc", body: `{"name":"Johnny Doe"}`, expectedStatus: http.StatusBadRequest, expectedName: "John Doe"},
+		{name: "Missing User", path: "/api/users/42", body: `{"name":"Johnny Doe"}`, expectedStatus: http.StatusNotFound, expectedName: "John Doe"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			api, s := newTestAPI(t, nil)
+
+			req := httptest.NewRequest("PATCH", tc.path, strings.NewReader(tc.body))
+			rr := httptest.NewRecorder()
+			api.Routes().ServeHTTP(rr, req)
+
+			if status := rr.Code; status != tc.expectedStatus {
+				t.Fatalf("handler returned wrong status code: got %v want %v", status, tc.expectedStatus)
+			}
+
+			stored, err := s.Get(req.Context(), 1)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if stored.Name != tc.expectedName {
+				t.Errorf("stored user has wrong name: got %v want %v", stored.Name, tc.expectedName)
+			}
+
+			if tc.expectedStatus != http.StatusOK {
+				return
+			}
+
+			var response models.UserResponse
+			if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+				t.Fatalf("could not parse response body: %v", err)
+			}
+			if response.User == nil {
+				t.Fatal("response does not contain the user")
+			}
+			if response.User.ID != 1 {
+				t.Errorf("handler changed the user ID: got %v want %v", response.User.ID, 1)
+			}
+			if response.User.Name != tc.expectedName {
+				t.Errorf("handler returned wrong name: got %v want %v", response.User.Name, tc.expectedName)
+			}
+			if response.User.PublicID != stored.PublicID || stored.PublicID == "" {
+				t.Errorf("handler did not keep the public ID: got %q want %q", response.User.PublicID, stored.PublicID)
+			}
+		})
+	}
+}
+
+func TestPatchUserJSONPatch(t *testing.T) {
+	testCases := []struct {
+		name           string
+		body           string
+		expectedName   string
+		expectedCode   string
+		expectedStatus int
+	}{
+		{name: "Replace", body: `[{"op":"replace","path":"/name","value":"Johnny Doe"}]`, expectedStatus: http.StatusOK, expectedName: "Johnny Doe"},
+		{name: "Test Then Replace", body: `[{"op":"test","path":"/name","value":"John Doe"},{"op":"replace","path":"/name","value":"Johnny Doe"}]`, expectedStatus: http.StatusOK, expectedName: "Johnny Doe"},
+		{name: "Failed Test", body: `[{"op":"test","path":"/name","value":"Jane Smith"},{"op":"replace","path":"/name","value":"Johnny Doe"}]`, expectedStatus: http.StatusConflict, expectedName: "John Doe", expectedCode: CodePatchTestFailed},
+		{name: "Unsupported Op", body: `[{"op":"remove","path":"/name"}]`, expectedStatus: http.StatusBadRequest, expectedName: "John Doe", expectedCode: CodeValidation},
+		{name: "Unsupported Path", body: `[{"op":"replace","path":"/id","value":7}]`, expectedStatus: http.StatusBadRequest, expectedName: "John Doe", expectedCode: CodeValidation},
+		{name: "Value Not A String", body: `[{"op":"replace","path":"/name","value":7}]`, expectedStatus: http.StatusBadRequest, expectedName: "John Doe", expectedCode: CodeValidation},
+		{name: "Empty Name", body: `[{"op":"replace","path":"/name","value":" "}]`, expectedStatus: http.StatusUnprocessableEntity, expectedName: "John Doe", expectedCode: CodeValidation},
+		{name: "Not An Array", body: `{"name":"Johnny Doe"}`, expectedStatus: http.StatusBadRequest, expectedName: "John Doe", expectedCode: CodeValidation},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			api, s := newTestAPI(t, nil)
+
+			req := httptest.NewRequest("PATCH", "/api/users/1", strings.NewReader(tc.body))
+			req.Header.Set("Content-Type", JSONPatchContentType)
+			rr := httptest.NewRecorder()
+			api.Routes().ServeHTTP(rr, req)
+
+			if status := rr.Code; status != tc.expectedStatus {
+				t.Fatalf("handler returned wrong status code: got %v want %v", status, tc.expectedStatus)
+			}
+
+			stored, err := s.Get(req.Context(), 1)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if stored.Name != tc.expectedName {
+				t.Errorf("stored user has wrong name: got %v want %v", stored.Name, tc.expectedName)
+			}
+
+			if tc.expectedStatus == http.StatusOK {
+				var response models.UserResponse
+				if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+					t.Fatalf("could not parse response body: %v", err)
+				}
+				if response.User == nil || response.User.Name != tc.expectedName {
+					t.Errorf("handler returned wrong user: got %+v", response.User)
+				}
+				return
+			}
+
+			var response models.ErrorResponse
+			if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+				t.Fatalf("could not parse response body: %v", err)
+			}
+			if response.Code != tc.expectedCode {
+				t.Errorf("handler returned wrong error code: got %v want %v", response.Code, tc.expectedCode)
+			}
+		})
+	}
+}
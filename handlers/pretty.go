@@ -0,0 +1,38 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+)
+
+// PrettyJSONMiddleware creates a middleware that sets whether JSON responses
+// are indented by default, e.g. only outside of production.
+// Requests can override the default with the pretty query parameter.
+func PrettyJSONMiddleware(enabled bool) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := context.WithValue(r.Context(), prettyJSONKey, enabled)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// prettyJSON reports whether the JSON response to r should be indented.
+// A pretty query parameter without a value or with a true value enables it,
+// a false value disables it, and otherwise the middleware's default applies.
+func prettyJSON(r *http.Request) bool {
+	query := r.URL.Query()
+	if query.Has("pretty") {
+		value := query.Get("pretty")
+		if value == "" {
+			return true
+		}
+		if pretty, err := strconv.ParseBool(value); err == nil {
+			return pretty
+		}
+	}
+
+	pretty, _ := r.Context().Value(prettyJSONKey).(bool)
+	return pretty
+}
@@ -0,0 +1,48 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestPrettyJSONMiddleware(t *testing.T) {
+	testCases := []struct {
+		name     string
+		query    string
+		enabled  bool
+		expected bool
+	}{
+		{name: "Development", enabled: true, expected: true},
+		{name: "Production", enabled: false, expected: false},
+		{name: "Query Enables In Production", query: "?pretty", enabled: false, expected: true},
+		{name: "Query True Enables In Production", query: "?pretty=true", enabled: false, expected: true},
+		{name: "Query Disables In Development", query: "?pretty=false", enabled: true, expected: false},
+		{name: "Invalid Query Keeps Default", query: "?pretty=maybe", enabled: false, expected: false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			api, _ := newTestAPI(t, nil)
+			handler := PrettyJSONMiddleware(tc.enabled)(api.Routes())
+
+			req := httptest.NewRequest("GET", "/api/users"+tc.query, nil)
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			if status := rr.Code; status != http.StatusOK {
+				t.Fatalf("handler returned wrong status code: got %v want %v", status, http.StatusOK)
+			}
+
+			body := rr.Body.String()
+			if indented := strings.Contains(body, "\n  \""); indented != tc.expected {
+				t.Errorf("wrong indentation: got indented %v want %v: %s", indented, tc.expected, body)
+			}
+			if !json.Valid(rr.Body.Bytes()) {
+				t.Errorf("handler returned invalid JSON: %s", body)
+			}
+		})
+	}
+}
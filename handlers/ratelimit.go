@@ -0,0 +1,129 @@
+package handlers
+
+import (
+	"log/slog"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// limiterIdleTTL is how long a client's limiter is kept after its last request
+const limiterIdleTTL = 3 * time.Minute
+
+// RateLimitMiddleware creates a middleware that limits each client IP to rps
+// requests per second with the given burst. Requests over the limit receive a
+// 429 response with a Retry-After header.
+func RateLimitMiddleware(rps float64, burst int) func(http.Handler) http.Handler {
+	limiter := newIPRateLimiter(rate.Limit(rps), burst, limiterIdleTTL)
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ip := clientIP(r)
+
+			allowed, retryAfter := limiter.allow(ip, time.Now())
+			if !allowed {
+				slog.Warn("Rate limit exceeded", "ip", ip, "retry_after", retryAfter)
+
+				w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(retryAfter.Seconds()))))
+				errorResponse(w, r, http.StatusTooManyRequests, "Too many requests")
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// clientIP returns the IP address of the client, stripped of its port
+func clientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// visitor tracks the limiter for a single client and when it was last seen
+type visitor struct {
+	limiter  *rate.Limiter
+	lastSeen time.Time
+}
+
+// ipRateLimiter holds a token bucket per client IP.
+// Idle visitors are swept lazily while serving requests, so memory stays
+// bounded without needing a background goroutine.
+type ipRateLimiter struct {
+	mu        sync.Mutex
+	visitors  map[string]*visitor
+	limit     rate.Limit
+	burst     int
+	idleTTL   time.Duration
+	lastSweep time.Time
+}
+
+// newIPRateLimiter creates a new ipRateLimiter
+func newIPRateLimiter(limit rate.Limit, burst int, idleTTL time.Duration) *ipRateLimiter {
+	return &ipRateLimiter{
+		visitors: make(map[string]*visitor),
+		limit:    limit,
+		burst:    burst,
+		idleTTL:  idleTTL,
+	}
+}
+
+// allow reports whether a request from ip at now is allowed.
+// When it isn't, it also returns how long the client should wait before retrying.
+func (l *ipRateLimiter) allow(ip string, now time.Time) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.sweep(now)
+
+	v, ok := l.visitors[ip]
+	if !ok {
+		v = &visitor{limiter: rate.NewLimiter(l.limit, l.burst)}
+		l.visitors[ip] = v
+	}
+	v.lastSeen = now
+
+	reservation := v.limiter.ReserveN(now, 1)
+	if !reservation.OK() {
+		// The burst is too small to ever satisfy the request
+		return false, time.Second
+	}
+
+	if delay := reservation.DelayFrom(now); delay > 0 {
+		// Give the token back, the request is rejected rather than delayed
+		reservation.CancelAt(now)
+		return false, delay
+	}
+
+	return true, 0
+}
+
+// sweep removes visitors that have been idle for longer than the idle TTL.
+// It runs at most once per TTL period. The caller must hold l.mu.
+func (l *ipRateLimiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < l.idleTTL {
+		return
+	}
+	l.lastSweep = now
+
+	for ip, v := range l.visitors {
+		if now.Sub(v.lastSeen) > l.idleTTL {
+			delete(l.visitors, ip)
+		}
+	}
+}
+
+// size returns the number of tracked visitors
+func (l *ipRateLimiter) size() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return len(l.visitors)
+}
@@ -0,0 +1,60 @@
+package handlers
+
+import (
+	"context"
+	"crypto/rand"
+	"fmt"
+	"net/http"
+
+	"github.com/kakkoyun/demo-web-service/internal/ctxkeys"
+)
+
+// RequestIDHeader is the header used to read and propagate request IDs
+const RequestIDHeader = "X-Request-ID"
+
+// maxRequestIDLength bounds client-supplied request IDs so they can't bloat logs
+const maxRequestIDLength = 128
+
+// contextKey is the type for keys stored in a request context by this package.
+// Values shared with other packages use the keys of ctxkeys instead.
+type contextKey int
+
+const (
+	requestDetailsKey contextKey = iota
+	prettyJSONKey
+	sentResponseKey
+)
+
+// RequestIDMiddleware creates a middleware that makes sure every request has an ID.
+// An ID supplied by the client in the X-Request-ID header is reused, otherwise a
+// new one is generated. The ID is stored in the request context and echoed back
+// in the response header.
+func RequestIDMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(RequestIDHeader)
+		if id == "" || len(id) > maxRequestIDLength {
+			id = newRequestID()
+		}
+
+		w.Header().Set(RequestIDHeader, id)
+
+		next.ServeHTTP(w, r.WithContext(ctxkeys.WithRequestID(r.Context(), id)))
+	})
+}
+
+// RequestIDFromContext returns the request ID stored in ctx, or an empty string if there is none
+func RequestIDFromContext(ctx context.Context) string {
+	return ctxkeys.RequestID(ctx)
+}
+
+// newRequestID generates a random, UUID-formatted (version 4) request ID
+func newRequestID() string {
+	var b [16]byte
+	// crypto/rand.Read never returns an error
+	_, _ = rand.Read(b[:])
+
+	b[6] = (b[6] & 0x0f) | 0x40 // Version 4
+	b[8] = (b[8] & 0x3f) | 0x80 // RFC 4122 variant
+
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
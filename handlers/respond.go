@@ -0,0 +1,82 @@
+package handlers
+
+import (
+	"encoding/xml"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// respond sends data encoded in the format the client prefers according to its
+// Accept header: XML when application/xml is preferred, JSON otherwise.
+// JSON is indented as decided by prettyJSON.
+func respond(w http.ResponseWriter, r *http.Request, status int, data interface{}) {
+	// Caches must keep the JSON and XML variants apart
+	w.Header().Add("Vary", "Accept")
+	recordSent(r, data)
+
+	if prefersXML(r.Header.Get("Accept")) {
+		xmlResponse(w, status, data)
+		return
+	}
+
+	encodeJSON(w, status, data, prettyJSON(r))
+}
+
+// xmlResponse sends an XML response with data as the <response> root element
+func xmlResponse(w http.ResponseWriter, status int, data interface{}) {
+	buf := getBuffer()
+	defer putBuffer(buf)
+	buf.WriteString(xml.Header)
+
+	root := xml.StartElement{Name: xml.Name{Local: "response"}}
+	if err := encodeSafely(func() error { return xml.NewEncoder(buf).EncodeElement(data, root) }); err != nil {
+		slog.Error("Failed to encode XML response", "error", err)
+		xmlResponse(w, http.StatusInternalServerError, encodeFailure)
+		return
+	}
+	buf.WriteByte('\n')
+
+	writeBody(w, status, "application/xml", buf)
+}
+
+// maxAcceptEntries bounds how many media ranges of an Accept header are
+// considered, so that pathological headers can't slow down negotiation
+const maxAcceptEntries = 32
+
+// prefersXML reports whether an Accept header value ranks application/xml above
+// application/json. Ties, wildcards and missing or malformed headers favor JSON.
+// Only the first maxAcceptEntries media ranges are considered.
+func prefersXML(accept string) bool {
+	entries := strings.SplitN(accept, ",", maxAcceptEntries+1)
+	if len(entries) > maxAcceptEntries {
+		// The last element holds the unsplit rest of the header
+		entries = entries[:maxAcceptEntries]
+	}
+
+	jsonQ, xmlQ := -1.0, -1.0
+	for _, entry := range entries {
+		mediaType, params, ok := parseMediaType(entry)
+		if !ok {
+			continue
+		}
+
+		q := 1.0
+		if v, found := params["q"]; found {
+			var err error
+			if q, err = strconv.ParseFloat(v, 64); err != nil || q < 0 || q > 1 {
+				continue
+			}
+		}
+
+		switch mediaType {
+		case "application/xml", "text/xml":
+			xmlQ = max(xmlQ, q)
+		case "application/json", "application/*", "*/*":
+			jsonQ = max(jsonQ, q)
+		}
+	}
+
+	return xmlQ > 0 && xmlQ > jsonQ
+}
@@ -0,0 +1,210 @@
+package handlers
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/kakkoyun/demo-web-service/models"
+)
+
+func TestPrefersXML(t *testing.T) {
+	testCases := []struct {
+		name     string
+		accept   string
+		expected bool
+	}{
+		{name: "Absent", accept: "", expected: false},
+		{name: "Wildcard", accept: "*/*", expected: false},
+		{name: "JSON", accept: "application/json", expected: false},
+		{name: "XML", accept: "application/xml", expected: true},
+		{name: "Text XML", accept: "text/xml", expected: true},
+		{name: "XML With Parameters", accept: "application/xml; charset=utf-8", expected: true},
+		{name: "XML Preferred Over Wildcard", accept: "application/xml, */*;q=0.8", expected: true},
+		{name: "JSON Preferred By Quality", accept: "application/xml;q=0.5, application/json", expected: false},
+		{name: "Tie Favors JSON", accept: "application/xml, application/json", expected: false},
+		{name: "XML Refused", accept: "application/xml;q=0", expected: false},
+		{name: "Malformed", accept: "application/xml;q=abc", expected: false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := prefersXML(tc.accept); got != tc.expected {
+				t.Errorf("prefersXML(%q) = %v, want %v", tc.accept, got, tc.expected)
+			}
+		})
+	}
+}
+
+func TestContentNegotiation(t *testing.T) {
+	testCases := []struct {
+		name        string
+		accept      string
+		path        string
+		contentType string
+		status      int
+	}{
+		{name: "Default", accept: "", path: "/api/users/1", contentType: "application/json", status: http.StatusOK},
+		{name: "Wildcard", accept: "*/*", path: "/api/users/1", contentType: "application/json", status: http.StatusOK},
+		{name: "JSON", accept: "application/json", path: "/api/users/1", contentType: "application/json", status: http.StatusOK},
+		{name: "XML", accept: "application/xml", path: "/api/users/1", contentType: "application/xml", status: http.StatusOK},
+		{name: "XML Error", accept: "application/xml", path: "/api/users/42", contentType: "application/xml", status: http.StatusNotFound},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			api, _ := newTestAPI(t, nil)
+
+			req := httptest.NewRequest("GET", tc.path, nil)
+			if tc.accept != "" {
+				req.Header.Set("Accept", tc.accept)
+			}
+			rr := httptest.NewRecorder()
+			api.Routes().ServeHTTP(rr, req)
+
+			if status := rr.Code; status != tc.status {
+				t.Fatalf("handler returned wrong status code: got %v want %v", status, tc.status)
+			}
+			if ct := rr.Header().Get("Content-Type"); ct != tc.contentType {
+				t.Errorf("wrong Content-Type: got %q want %q", ct, tc.contentType)
+			}
+			if vary := rr.Header().Get("Vary"); vary != "Accept" {
+				t.Errorf("wrong Vary header: got %q want %q", vary, "Accept")
+			}
+
+			switch {
+			case tc.contentType == "application/json" && tc.status == http.StatusOK:
+				var response models.UserResponse
+				if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+					t.Fatalf("could not parse JSON body: %v", err)
+				}
+				if response.User == nil || response.User.Name != "John Doe" {
+					t.Errorf("handler returned wrong user: got %+v", response.User)
+				}
+			case tc.status == http.StatusOK:
+				if !strings.HasPrefix(rr.Body.String(), xml.Header) {
+					t.Errorf("body is missing the XML declaration: %q", rr.Body.String())
+				}
+				var response models.UserResponse
+				if err := xml.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+					t.Fatalf("could not parse XML body: %v", err)
+				}
+				if response.User == nil || response.User.Name != "John Doe" || response.User.ID != 1 {
+					t.Errorf("handler returned wrong user: got %+v", response.User)
+				}
+			default:
+				var response models.ErrorResponse
+				if err := xml.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+					t.Fatalf("could not parse XML body: %v", err)
+				}
+				if response.Status != "error" || response.Message == "" {
+					t.Errorf("handler returned wrong error: got %+v", response)
+				}
+			}
+		})
+	}
+}
+
+func TestXMLUserList(t *testing.T) {
+	api, _ := newTestAPI(t, nil)
+
+	req := httptest.NewRequest("GET", "/api/users", nil)
+	req.Header.Set("Accept", "application/xml")
+	rr := httptest.NewRecorder()
+	api.Routes().ServeHTTP(rr, req)
+
+	var response models.UserResponse
+	if err := xml.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+		t.Fatalf("could not parse XML body: %v", err)
+	}
+	if len(response.Users) != 2 {
+		t.Errorf("handler returned wrong number of users: got %v want %v", len(response.Users), 2)
+	}
+	if !strings.Contains(rr.Body.String(), "<users><user>") {
+		t.Errorf("users are not wrapped in a <users> element: %q", rr.Body.String())
+	}
+}
+
+func TestOversizedAcceptHeader(t *testing.T) {
+	filler := strings.Repeat("text/plain;q=0.1, ", 5000)
+
+	testCases := []struct {
+		name        string
+		accept      string
+		expectedXML bool
+	}{
+		{name: "XML Beyond Limit", accept: filler + "application/xml", expectedXML: false},
+		{name: "XML Within Limit", accept: "application/xml, " + filler, expectedXML: true},
+		{name: "XML At Limit", accept: strings.Repeat("text/plain, ", maxAcceptEntries-1) + "application/xml", expectedXML: true},
+		{name: "XML Just Past Limit", accept: strings.Repeat("text/plain, ", maxAcceptEntries) + "application/xml", expectedXML: false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := prefersXML(tc.accept); got != tc.expectedXML {
+				t.Errorf("wrong preference: got XML %v want %v", got, tc.expectedXML)
+			}
+		})
+	}
+
+	// The response falls back to JSON
+	api, _ := newTestAPI(t, nil)
+	req := httptest.NewRequest("GET", "/api/users", nil)
+	req.Header.Set("Accept", filler+"application/xml")
+	rr := httptest.NewRecorder()
+	api.Routes().ServeHTTP(rr, req)
+
+	if ct := rr.Header().Get("Content-Type"); ct != "application/json" {
+		t.Errorf("handler returned wrong content type: got %v want %v", ct, "application/json")
+	}
+}
+
+// panickingValue panics when it is encoded
+type panickingValue struct{}
+
+func (panickingValue) MarshalJSON() ([]byte, error) { panic("broken marshaler") }
+
+func (panickingValue) MarshalXML(*xml.Encoder, xml.StartElement) error { panic("broken marshaler") }
+
+func TestRespondRecoversEncoderPanics(t *testing.T) {
+	testCases := []struct {
+		name        string
+		accept      string
+		contentType string
+		unmarshal   func([]byte, any) error
+	}{
+		{name: "JSON", accept: "application/json", contentType: "application/json", unmarshal: json.Unmarshal},
+		{name: "XML", accept: "application/xml", contentType: "application/xml", unmarshal: xml.Unmarshal},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/", nil)
+			req.Header.Set("Accept", tc.accept)
+			rr := httptest.NewRecorder()
+
+			data := struct {
+				Value panickingValue `json:"value" xml:"value"`
+			}{}
+			respond(rr, req, http.StatusOK, data)
+
+			if status := rr.Code; status != http.StatusInternalServerError {
+				t.Errorf("handler returned wrong status code: got %v want %v", status, http.StatusInternalServerError)
+			}
+			if contentType := rr.Header().Get("Content-Type"); contentType != tc.contentType {
+				t.Errorf("handler returned wrong content type: got %v want %v", contentType, tc.contentType)
+			}
+
+			var response models.ErrorResponse
+			if err := tc.unmarshal(rr.Body.Bytes(), &response); err != nil {
+				t.Fatalf("could not parse response body %q: %v", rr.Body.String(), err)
+			}
+			if response.Status != "error" || response.Code != "INTERNAL_SERVER_ERROR" {
+				t.Errorf("handler returned wrong error: got %+v", response)
+			}
+		})
+	}
+}
@@ -0,0 +1,72 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// ResponseTimeHeader reports how long the server took to start the response
+const ResponseTimeHeader = "X-Response-Time"
+
+// ResponseTimeMiddleware creates a middleware that sets the X-Response-Time
+// header to the milliseconds the handler took until it started the response,
+// e.g. "12.345". Headers can't change once the status is sent, so the header
+// is set when the handler first writes the status, body or flushes, rather
+// than when it returns.
+func ResponseTimeMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tw := &responseTimeWriter{ResponseWriter: w, start: time.Now()}
+		next.ServeHTTP(tw, r)
+
+		// A handler that writes nothing still sends a response once it returns
+		tw.setHeader()
+	})
+}
+
+// responseTimeWriter is a wrapper for http.ResponseWriter that sets the
+// X-Response-Time header just before the response starts
+type responseTimeWriter struct {
+	http.ResponseWriter
+	start     time.Time
+	headerSet bool
+}
+
+// setHeader sets the X-Response-Time header, once
+func (tw *responseTimeWriter) setHeader() {
+	if tw.headerSet {
+		return
+	}
+	tw.headerSet = true
+
+	elapsed := float64(time.Since(tw.start)) / float64(time.Millisecond)
+	tw.Header().Set(ResponseTimeHeader, strconv.FormatFloat(elapsed, 'f', 3, 64))
+}
+
+// WriteHeader sets the X-Response-Time header before sending a final status
+func (tw *responseTimeWriter) WriteHeader(statusCode int) {
+	if !isInformational(statusCode) {
+		tw.setHeader()
+	}
+	tw.ResponseWriter.WriteHeader(statusCode)
+}
+
+// Write sets the X-Response-Time header before the implicit 200 status
+func (tw *responseTimeWriter) Write(b []byte) (int, error) {
+	tw.setHeader()
+	return tw.ResponseWriter.Write(b)
+}
+
+// Flush sets the X-Response-Time header before sending buffered data to the
+// client, if the underlying writer supports it
+func (tw *responseTimeWriter) Flush() {
+	tw.setHeader()
+	if f, ok := tw.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Unwrap returns the underlying writer, for use by http.ResponseController
+func (tw *responseTimeWriter) Unwrap() http.ResponseWriter {
+	return tw.ResponseWriter
+}
@@ -0,0 +1,53 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestResponseTimeMiddleware(t *testing.T) {
+	testCases := []struct {
+		handler http.HandlerFunc
+		name    string
+		minimum float64
+	}{
+		{
+			name: "Write Header",
+			handler: func(w http.ResponseWriter, _ *http.Request) {
+				time.Sleep(20 * time.Millisecond)
+				w.WriteHeader(http.StatusCreated)
+			},
+			minimum: 20,
+		},
+		{
+			name: "Write Body",
+			handler: func(w http.ResponseWriter, _ *http.Request) {
+				_, _ = w.Write([]byte("ok"))
+			},
+		},
+		{
+			name:    "Empty Response",
+			handler: func(http.ResponseWriter, *http.Request) {},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			rr := httptest.NewRecorder()
+			ResponseTimeMiddleware(tc.handler).ServeHTTP(rr, httptest.NewRequest("GET", "/", nil))
+
+			// The recorder snapshots the headers when the status is written
+			value := rr.Result().Header.Get(ResponseTimeHeader)
+			elapsed, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				t.Fatalf("%s header is not a number: %q", ResponseTimeHeader, value)
+			}
+			if elapsed < tc.minimum {
+				t.Errorf("%s is too small: got %v want at least %v", ResponseTimeHeader, elapsed, tc.minimum)
+			}
+		})
+	}
+}
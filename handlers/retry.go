@@ -0,0 +1,124 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+const (
+	// DefaultRetryBackoff is the default delay before the first retry
+	DefaultRetryBackoff = 50 * time.Millisecond
+	// maxRetryBackoff caps the delay between retries, however many there were
+	maxRetryBackoff = 2 * time.Second
+)
+
+// transientError marks a failure that may succeed when retried, such as a timeout
+type transientError struct {
+	err error
+}
+
+func (e transientError) Error() string { return e.err.Error() }
+
+func (e transientError) Unwrap() error { return e.err }
+
+// transient marks err as worth retrying
+func transient(err error) error {
+	return transientError{err: err}
+}
+
+// isTransient reports whether err, or any error it wraps, is worth retrying
+func isTransient(err error) bool {
+	var te transientError
+	return errors.As(err, &te)
+}
+
+// RetryBudget caps the total number of retries per second across all requests.
+// Without a shared budget, every request retrying on its own multiplies the load
+// on a struggling dependency; once the budget is spent, failures are returned
+// immediately instead of being retried.
+type RetryBudget struct {
+	limiter *rate.Limiter
+}
+
+// NewRetryBudget creates a RetryBudget that refills at perSecond retries per
+// second and allows bursts of up to burst retries
+func NewRetryBudget(perSecond float64, burst int) *RetryBudget {
+	return &RetryBudget{limiter: rate.NewLimiter(rate.Limit(perSecond), burst)}
+}
+
+// allow spends one retry from the budget, reporting whether one was available
+func (b *RetryBudget) allow() bool {
+	return b.limiter.Allow()
+}
+
+// WithRetries retries failed database queries up to maxRetries times, as long
+// as budget has retries left; 0 disables retries
+func WithRetries(maxRetries int, budget *RetryBudget) Option {
+	return func(a *API) {
+		a.maxRetries = maxRetries
+		a.retryBudget = budget
+	}
+}
+
+// WithRetryBackoff sets the delay before the first retry, which doubles with
+// every further retry; 0 retries immediately
+func WithRetryBackoff(d time.Duration) Option {
+	return func(a *API) {
+		a.retryBackoff = d
+	}
+}
+
+// withRetries runs fn, retrying transient failures with exponential backoff
+// while the API's retry limit and the shared retry budget allow.
+// Other failures are returned right away, and so is the last failure once ctx
+// is done, even in the middle of a backoff.
+func (a *API) withRetries(ctx context.Context, op string, fn func(context.Context) error) error {
+	err := fn(ctx)
+	for attempt := 1; err != nil && attempt <= a.maxRetries; attempt++ {
+		if !isTransient(err) || ctx.Err() != nil {
+			return err
+		}
+		if a.retryBudget != nil && !a.retryBudget.allow() {
+			a.log(ctx).Warn("Retry budget exhausted, not retrying",
+				"op", op,
+				"attempt", attempt,
+				"error", err)
+			return err
+		}
+
+		delay := backoff(a.retryBackoff, attempt)
+		a.log(ctx).Info("Retrying failed operation",
+			"op", op,
+			"attempt", attempt,
+			"delay", delay,
+			"error", err)
+		if sleepContext(ctx, delay) != nil {
+			return err
+		}
+
+		err = fn(ctx)
+	}
+	return err
+}
+
+// backoff returns how long to wait before the given retry: base doubled for
+// every earlier retry and capped at maxRetryBackoff, with random jitter of up
+// to half of it so that requests failing together don't retry in lockstep
+func backoff(base time.Duration, attempt int) time.Duration {
+	if base <= 0 {
+		return 0
+	}
+
+	delay := base
+	for i := 1; i < attempt && delay < maxRetryBackoff; i++ {
+		delay *= 2
+	}
+	delay = min(delay, maxRetryBackoff)
+
+	half := delay / 2
+	return half + rand.N(delay-half+1)
+}
@@ -0,0 +1,170 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"expvar"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/kakkoyun/demo-web-service/models"
+)
+
+func TestRetryBudget(t *testing.T) {
+	// The budget never refills, so the first three retries use it up
+	metrics := new(expvar.Map)
+	api, _ := newTestAPI(t, []Option{
+		WithFaultInjector(stubFaults{OpQueryTimeout: true}),
+		WithDBErrorMetrics(metrics),
+		WithRetries(2, NewRetryBudget(0, 3)),
+		WithRetryBackoff(0),
+	})
+	handler := api.Routes()
+
+	// Each request makes one attempt plus as many retries as the budget allows
+	expectedAttempts := []int64{3, 2, 1, 1}
+
+	var total int64
+	for i, want := range expectedAttempts {
+		req := httptest.NewRequest("GET", "/api/users/5", nil)
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+
+		if status := rr.Code; status != http.StatusInternalServerError {
+			t.Errorf("request %d: handler returned wrong status code: got %v want %v", i, status, http.StatusInternalServerError)
+		}
+
+		counter, ok := metrics.Get(OpQueryTimeout).(*expvar.Int)
+		if !ok {
+			t.Fatalf("request %d: no counter for %s errors", i, OpQueryTimeout)
+		}
+		if got := counter.Value() - total; got != want {
+			t.Errorf("request %d: wrong number of query attempts: got %v want %v", i, got, want)
+		}
+		total = counter.Value()
+	}
+}
+
+func TestRetriesRecoverFromTransientFailures(t *testing.T) {
+	// Fail only the first query attempt
+	faults := &failOnce{op: OpQueryTimeout}
+	api, _ := newTestAPI(t, []Option{
+		WithFaultInjector(faults),
+		WithRetries(2, NewRetryBudget(10, 10)),
+		WithRetryBackoff(0),
+	}, models.User{ID: 5, Name: "Eve"})
+
+	req := httptest.NewRequest("GET", "/api/users/5", nil)
+	rr := httptest.NewRecorder()
+	api.Routes().ServeHTTP(rr, req)
+
+	if status := rr.Code; status != http.StatusOK {
+		t.Errorf("handler returned wrong status code: got %v want %v", status, http.StatusOK)
+	}
+}
+
+// failOnce is a FaultInjector that fails the first check of op only
+type failOnce struct {
+	op     string
+	failed bool
+}
+
+func (f *failOnce) ShouldFail(op string) bool {
+	if op != f.op || f.failed {
+		return false
+	}
+	f.failed = true
+	return true
+}
+
+func (*failOnce) Delay(string) time.Duration { return 0 }
+
+// failTimes returns an operation that fails with err the first n times it runs,
+// and a pointer to the number of times it ran
+func failTimes(n int, err error) (func(context.Context) error, *int) {
+	calls := 0
+	return func(context.Context) error {
+		calls++
+		if calls <= n {
+			return err
+		}
+		return nil
+	}, &calls
+}
+
+func TestWithRetries(t *testing.T) {
+	testCases := []struct {
+		err           error
+		name          string
+		failures      int
+		expectedCalls int
+		expectErr     bool
+	}{
+		{name: "Transient Failures Then Success", err: transient(errors.New("connection timeout")), failures: 2, expectedCalls: 3},
+		{name: "Transient Failures Exceed Retries", err: transient(errors.New("connection timeout")), failures: 5, expectedCalls: 4, expectErr: true},
+		{name: "Permanent Failure", err: errors.New("primary key constraint violation"), failures: 5, expectedCalls: 1, expectErr: true},
+		{name: "Success", failures: 0, expectedCalls: 1},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			api, _ := newTestAPI(t, []Option{WithRetries(3, nil), WithRetryBackoff(time.Millisecond)})
+			op, calls := failTimes(tc.failures, tc.err)
+
+			err := api.withRetries(context.Background(), "test", op)
+
+			if (err != nil) != tc.expectErr {
+				t.Errorf("withRetries returned wrong error: got %v, want error %v", err, tc.expectErr)
+			}
+			if *calls != tc.expectedCalls {
+				t.Errorf("operation ran wrong number of times: got %v want %v", *calls, tc.expectedCalls)
+			}
+		})
+	}
+}
+
+func TestWithRetriesStopsWhenContextIsDone(t *testing.T) {
+	api, _ := newTestAPI(t, []Option{WithRetries(3, nil), WithRetryBackoff(time.Hour)})
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	op, calls := failTimes(5, transient(errors.New("connection timeout")))
+
+	start := time.Now()
+	if err := api.withRetries(ctx, "test", op); err == nil {
+		t.Error("withRetries succeeded after the context was done")
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("withRetries kept backing off after the context was done: took %v", elapsed)
+	}
+	if *calls != 1 {
+		t.Errorf("operation ran wrong number of times: got %v want %v", *calls, 1)
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	testCases := []struct {
+		name    string
+		base    time.Duration
+		attempt int
+		min     time.Duration
+		max     time.Duration
+	}{
+		{name: "First Retry", base: 100 * time.Millisecond, attempt: 1, min: 50 * time.Millisecond, max: 100 * time.Millisecond},
+		{name: "Third Retry", base: 100 * time.Millisecond, attempt: 3, min: 200 * time.Millisecond, max: 400 * time.Millisecond},
+		{name: "Capped", base: 100 * time.Millisecond, attempt: 50, min: maxRetryBackoff / 2, max: maxRetryBackoff},
+		{name: "Disabled", base: 0, attempt: 2, min: 0, max: 0},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			for range 100 {
+				if d := backoff(tc.base, tc.attempt); d < tc.min || d > tc.max {
+					t.Fatalf("backoff out of range: got %v want between %v and %v", d, tc.min, tc.max)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,134 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+
+	"braces.dev/errtrace"
+	"github.com/santhosh-tekuri/jsonschema/v6"
+	"github.com/santhosh-tekuri/jsonschema/v6/kind"
+	"golang.org/x/text/language"
+	"golang.org/x/text/message"
+
+	"github.com/kakkoyun/demo-web-service/models"
+)
+
+// schemaURL identifies the schema among the compiler's resources; it is never fetched
+const schemaURL = "mem://user.schema.json"
+
+// schemaMessages renders schema violations in English
+var schemaMessages = message.NewPrinter(language.English)
+
+// SchemaValidator checks JSON request bodies against a JSON Schema
+type SchemaValidator struct {
+	schema *jsonschema.Schema
+}
+
+// NewSchemaValidator compiles a JSON Schema document.
+// Schemas without a $schema keyword are treated as draft 2020-12, and
+// references to other documents are not resolved.
+func NewSchemaValidator(schema []byte) (*SchemaValidator, error) {
+	doc, err := jsonschema.UnmarshalJSON(bytes.NewReader(schema))
+	if err != nil {
+		return nil, errtrace.Wrap(fmt.Errorf("parsing JSON schema: %w", err))
+	}
+
+	c := jsonschema.NewCompiler()
+	if err := c.AddResource(schemaURL, doc); err != nil {
+		return nil, errtrace.Wrap(fmt.Errorf("adding JSON schema: %w", err))
+	}
+	compiled, err := c.Compile(schemaURL)
+	if err != nil {
+		return nil, errtrace.Wrap(fmt.Errorf("compiling JSON schema: %w", err))
+	}
+	return &SchemaValidator{schema: compiled}, nil
+}
+
+// Validate returns an InvalidFieldsError listing every schema violation if
+// body, a JSON document, doesn't conform to the schema
+func (v *SchemaValidator) Validate(body []byte) error {
+	doc, err := jsonschema.UnmarshalJSON(bytes.NewReader(body))
+	if err != nil {
+		return errtrace.Wrap(fmt.Errorf("%w: invalid JSON body: %w", ErrValidation, err))
+	}
+
+	err = v.schema.Validate(doc)
+	var violation *jsonschema.ValidationError
+	if errors.As(err, &violation) {
+		return &InvalidFieldsError{Fields: schemaFieldErrors(violation, nil)}
+	}
+	return errtrace.Wrap(err)
+}
+
+// schemaFieldErrors flattens a schema violation into the field errors of its
+// leaf causes, which name the keywords that actually failed
+func schemaFieldErrors(violation *jsonschema.ValidationError, fields []models.FieldError) []models.FieldError {
+	if len(violation.Causes) > 0 {
+		for _, cause := range violation.Causes {
+			fields = schemaFieldErrors(cause, fields)
+		}
+		return fields
+	}
+
+	// Report each missing property on its own, like validateUser does
+	if required, ok := violation.ErrorKind.(*kind.Required); ok {
+		for _, name := range required.Missing {
+			fields = append(fields, models.FieldError{
+				Field:   schemaField(slices.Concat(violation.InstanceLocation, []string{name})),
+				Message: "is required",
+			})
+		}
+		return fields
+	}
+
+	return append(fields, models.FieldError{
+		Field:   schemaField(violation.InstanceLocation),
+		Message: "is invalid: " + violation.ErrorKind.LocalizedString(schemaMessages),
+	})
+}
+
+// schemaField names the field at a location in a JSON document, e.g.
+// "address.city"; the document itself is "body"
+func schemaField(location []string) string {
+	if len(location) == 0 {
+		return "body"
+	}
+	return strings.Join(location, ".")
+}
+
+// decodeUserJSON decodes a JSON request body into v like decodeJSON, first
+// checking the body against the API's user schema if there is one
+func (a *API) decodeUserJSON(r *http.Request, v any) error {
+	if a.userSchema == nil {
+		return errtrace.Wrap(decodeJSON(r, v))
+	}
+
+	var body json.RawMessage
+	if err := decodeJSON(r, &body); err != nil {
+		return errtrace.Wrap(err)
+	}
+	if err := a.userSchema.Validate(body); err != nil {
+		return errtrace.Wrap(err)
+	}
+	return errtrace.Wrap(decodeWith(json.NewDecoder(bytes.NewReader(body)), v))
+}
+
+// validateUserSchema checks the JSON encoding of user against the API's user
+// schema, if there is one. Patches are partial, so it is the patched user
+// rather than the request body that must conform.
+func (a *API) validateUserSchema(user models.User) error {
+	if a.userSchema == nil {
+		return nil
+	}
+
+	body, err := json.Marshal(user)
+	if err != nil {
+		return errtrace.Wrap(fmt.Errorf("encoding user: %w", err))
+	}
+	return errtrace.Wrap(a.userSchema.Validate(body))
+}
@@ -0,0 +1,171 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/kakkoyun/demo-web-service/models"
+)
+
+// testUserSchema requires users to have a non-empty name
+const testUserSchema = `{
+	"type": "object",
+	"required": ["name"],
+	"properties": {
+		"name": {"type": "string", "minLength": 1}
+	}
+}`
+
+func newTestSchemaValidator(t *testing.T, schema string) *SchemaValidator {
+	t.Helper()
+
+	v, err := NewSchemaValidator([]byte(schema))
+	if err != nil {
+		t.Fatalf("NewSchemaValidator() error = %v", err)
+	}
+	return v
+}
+
+func TestNewSchemaValidatorRejectsInvalidSchemas(t *testing.T) {
+	for name, schema := range map[string]string{
+		"Malformed JSON":   `{"type":`,
+		"Unknown Type":     `{"type": "user"}`,
+		"Negative Length":  `{"minLength": -1}`,
+		"Unresolvable Ref": `{"$ref": "#/$defs/missing"}`,
+	} {
+		t.Run(name, func(t *testing.T) {
+			if _, err := NewSchemaValidator([]byte(schema)); err == nil {
+				t.Errorf("NewSchemaValidator(%s) succeeded, want an error", schema)
+			}
+		})
+	}
+}
+
+func TestCreateUserHandlerSchema(t *testing.T) {
+	testCases := []struct {
+		name           string
+		body           string
+		expectedStatus int
+		expected       []models.FieldError
+	}{
+		{
+			name:           "Valid User",
+			body:           `{"name":"Alice"}`,
+			expectedStatus: http.StatusCreated,
+		},
+		{
+			name:           "Missing Name",
+			body:           `{}`,
+			expectedStatus: http.StatusUnprocessableEntity,
+			expected:       []models.FieldError{{Field: "name", Message: "is required"}},
+		},
+		{
+			name:           "Empty Name",
+			body:           `{"name":""}`,
+			expectedStatus: http.StatusUnprocessableEntity,
+			expected:       []models.FieldError{{Field: "name", Message: "is invalid: minLength: got 0, want 1"}},
+		},
+		{
+			name:           "Name Of Wrong Type",
+			body:           `{"name":42}`,
+			expectedStatus: http.StatusUnprocessableEntity,
+			expected:       []models.FieldError{{Field: "name", Message: "is invalid: got number, want string"}},
+		},
+		{
+			name:           "Not An Object",
+			body:           `["Alice"]`,
+			expectedStatus: http.StatusUnprocessableEntity,
+			expected:       []models.FieldError{{Field: "body", Message: "is invalid: got array, want object"}},
+		},
+		{
+			name:           "Malformed JSON",
+			body:           `{"name":`,
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			api, _ := newTestAPI(t, []Option{WithUserSchema(newTestSchemaValidator(t, testUserSchema))})
+
+			req := httptest.NewRequest("POST", "/api/users", strings.NewReader(tc.body))
+			rr := httptest.NewRecorder()
+			api.Routes().ServeHTTP(rr, req)
+
+			if status := rr.Code; status != tc.expectedStatus {
+				t.Fatalf("handler returned wrong status code: got %v want %v: %s", status, tc.expectedStatus, rr.Body)
+			}
+			if tc.expectedStatus == http.StatusCreated {
+				return
+			}
+
+			var response models.ErrorResponse
+			if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+				t.Fatalf("could not parse response body: %v", err)
+			}
+			if response.Code != CodeValidation {
+				t.Errorf("handler returned wrong error code: got %v want %v", response.Code, CodeValidation)
+			}
+			if !slices.Equal(response.Details, tc.expected) {
+				t.Errorf("handler returned wrong field errors: got %v want %v", response.Details, tc.expected)
+			}
+		})
+	}
+}
+
+func TestPatchUserHandlerSchema(t *testing.T) {
+	// Stricter than the built-in validation, so only the schema rejects long names
+	schema := newTestSchemaValidator(t, `{
+		"type": "object",
+		"required": ["name"],
+		"properties": {"name": {"type": "string", "maxLength": 8}}
+	}`)
+
+	testCases := []struct {
+		name           string
+		body           string
+		expectedStatus int
+		expectedName   string
+	}{
+		{
+			name:           "Conforming Patch",
+			body:           `{"name":"Johnny"}`,
+			expectedStatus: http.StatusOK,
+			expectedName:   "Johnny",
+		},
+		{
+			// The built-in validation accepts the name, the schema does not
+			name:           "Patched User Violates Schema",
+			body:           `{"name":"Jonathan Doe"}`,
+			expectedStatus: http.StatusUnprocessableEntity,
+			expectedName:   "John Doe",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			api, users := newTestAPI(t, []Option{WithUserSchema(schema)})
+
+			req := httptest.NewRequest("PATCH", "/api/users/1", strings.NewReader(tc.body))
+			req.Header.Set("Content-Type", "application/json")
+			rr := httptest.NewRecorder()
+			api.Routes().ServeHTTP(rr, req)
+
+			if status := rr.Code; status != tc.expectedStatus {
+				t.Fatalf("handler returned wrong status code: got %v want %v: %s", status, tc.expectedStatus, rr.Body)
+			}
+
+			user, err := users.Get(t.Context(), 1)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if user.Name != tc.expectedName {
+				t.Errorf("stored user has wrong name: got %q want %q", user.Name, tc.expectedName)
+			}
+		})
+	}
+}
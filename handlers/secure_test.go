@@ -0,0 +1,49 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestSecureHeadersMiddleware(t *testing.T) {
+	headers := DefaultSecureHeaders()
+	headers.ContentSecurityPolicy = "default-src 'none'"
+
+	api, _ := newTestAPI(t, nil)
+	handler := SecureHeadersMiddleware(headers)(api.Routes())
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest("GET", "/api/users", nil))
+
+	expected := map[string]string{
+		"X-Content-Type-Options":  "nosniff",
+		"X-Frame-Options":         "DENY",
+		"Referrer-Policy":         "no-referrer",
+		"Content-Security-Policy": "default-src 'none'",
+	}
+	for name, want := range expected {
+		if got := rr.Header().Get(name); got != want {
+			t.Errorf("wrong %s header: got %q want %q", name, got, want)
+		}
+	}
+}
+
+func TestSecureHeadersMiddlewareDisabledHeaders(t *testing.T) {
+	headers := DefaultSecureHeaders()
+	headers.FrameOptions = ""
+
+	handler := SecureHeadersMiddleware(headers)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest("GET", "/", nil))
+
+	for _, name := range []string{"X-Frame-Options", "Content-Security-Policy"} {
+		if _, ok := rr.Header()[name]; ok {
+			t.Errorf("disabled header %s was set", name)
+		}
+	}
+	if got := rr.Header().Get("X-Content-Type-Options"); got != "nosniff" {
+		t.Errorf("wrong X-Content-Type-Options header: got %q want %q", got, "nosniff")
+	}
+}
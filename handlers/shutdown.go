@@ -0,0 +1,141 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/kakkoyun/demo-web-service/models"
+)
+
+// ErrShuttingDown is reported by the shutdown readiness check while the server drains
+var ErrShuttingDown = errors.New("server is shutting down")
+
+// ShutdownTracker tracks open connections, in-flight requests and the progress
+// of a graceful shutdown. It serves the shutdown status endpoint, which keeps
+// answering during the shutdown grace period, and during the drain on
+// connections that are still open, since the listeners are closed by then.
+type ShutdownTracker struct {
+	started     time.Time
+	now         func() time.Time
+	connections atomic.Int64
+	requests    atomic.Int64
+	mu          sync.RWMutex
+}
+
+// NewShutdownTracker creates a ShutdownTracker with no shutdown in progress
+func NewShutdownTracker() *ShutdownTracker {
+	return &ShutdownTracker{now: time.Now}
+}
+
+// ConnState counts open connections; install it as the http.Server's ConnState hook
+func (st *ShutdownTracker) ConnState(_ net.Conn, state http.ConnState) {
+	switch state {
+	case http.StateNew:
+		st.connections.Add(1)
+	case http.StateHijacked, http.StateClosed:
+		st.connections.Add(-1)
+	}
+}
+
+// shutdownExemptPaths are still served once a shutdown has begun, so that
+// operators and load balancers can follow its progress
+var shutdownExemptPaths = []string{"/api/health", "/api/shutdown-status"}
+
+// Middleware counts the requests in flight through next; it should wrap the
+// whole handler chain so every request is counted.
+// Once a shutdown has begun, requests other than health checks and the
+// shutdown status are answered with a 503, and every response asks the client
+// to close its connection, which won't be served much longer.
+func (st *ShutdownTracker) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		st.requests.Add(1)
+		defer st.requests.Add(-1)
+
+		if st.Status().ShuttingDown {
+			w.Header().Set("Connection", "close")
+			if !hasPathPrefix(r.URL.Path, shutdownExemptPaths) {
+				errorResponse(w, r, http.StatusServiceUnavailable, "Server is shutting down")
+				return
+			}
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// Begin records that a graceful shutdown has started; later calls have no effect
+func (st *ShutdownTracker) Begin() {
+	st.mu.Lock()
+	defer st.mu.Unlock()
+
+	if st.started.IsZero() {
+		st.started = st.now()
+	}
+}
+
+// Status returns the current shutdown progress
+func (st *ShutdownTracker) Status() models.ShutdownStatus {
+	st.mu.RLock()
+	defer st.mu.RUnlock()
+
+	status := models.ShutdownStatus{
+		ShuttingDown:      !st.started.IsZero(),
+		ActiveConnections: st.connections.Load(),
+		InFlightRequests:  st.requests.Load(),
+	}
+	if status.ShuttingDown {
+		status.DrainSeconds = st.now().Sub(st.started).Seconds()
+	}
+
+	return status
+}
+
+// LogDrain logs how many requests are in flight as the drain begins, then
+// every interval until none are left or ctx is done, e.g. when the shutdown
+// deadline passes or the server has shut down
+func (st *ShutdownTracker) LogDrain(ctx context.Context, logger *slog.Logger, interval time.Duration) {
+	logger.Info("Draining in-flight requests", "in_flight", st.requests.Load())
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if st.requests.Load() == 0 {
+			logger.Info("All in-flight requests finished")
+			return
+		}
+
+		select {
+		case <-ctx.Done():
+			if inFlight := st.requests.Load(); inFlight > 0 {
+				logger.Warn("Stopped waiting for in-flight requests", "in_flight", inFlight)
+				return
+			}
+		case <-ticker.C:
+			if inFlight := st.requests.Load(); inFlight > 0 {
+				logger.Info("Waiting for in-flight requests",
+					"in_flight", inFlight,
+					"drain_seconds", st.Status().DrainSeconds)
+			}
+		}
+	}
+}
+
+// Check fails once a shutdown has started, so load balancers stop sending traffic
+func (st *ShutdownTracker) Check(_ context.Context) error {
+	if st.Status().ShuttingDown {
+		return ErrShuttingDown
+	}
+	return nil
+}
+
+// ServeHTTP reports the shutdown progress
+func (st *ShutdownTracker) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	jsonResponse(w, http.StatusOK, st.Status())
+}
@@ -0,0 +1,169 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/kakkoyun/demo-web-service/models"
+)
+
+// shutdownStatus fetches the shutdown status from the tracker's handler
+func shutdownStatus(t *testing.T, st *ShutdownTracker) models.ShutdownStatus {
+	t.Helper()
+
+	rr := httptest.NewRecorder()
+	st.ServeHTTP(rr, httptest.NewRequest("GET", "/api/shutdown-status", nil))
+
+	if status := rr.Code; status != http.StatusOK {
+		t.Fatalf("handler returned wrong status code: got %v want %v", status, http.StatusOK)
+	}
+
+	var response models.ShutdownStatus
+	if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+		t.Fatalf("could not parse response body: %v", err)
+	}
+	return response
+}
+
+func TestShutdownTracker(t *testing.T) {
+	st := NewShutdownTracker()
+
+	// A request that stays in flight until released
+	inFlight := make(chan struct{})
+	release := make(chan struct{})
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(inFlight)
+		<-release
+	}))
+	srv.Config.ConnState = st.ConnState
+	srv.Start()
+	defer srv.Close()
+
+	if got := shutdownStatus(t, st); got.ShuttingDown {
+		t.Errorf("shutdown reported before it began: %+v", got)
+	}
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		resp, err := srv.Client().Get(srv.URL)
+		if err == nil {
+			resp.Body.Close()
+		}
+	}()
+	<-inFlight
+
+	// Begin draining while the request is still in flight, 3 seconds ago
+	now := time.Now()
+	st.now = func() time.Time { return now }
+	st.Begin()
+	now = now.Add(3 * time.Second)
+
+	shutdownDone := make(chan error, 1)
+	go func() {
+		shutdownDone <- srv.Config.Shutdown(context.Background())
+	}()
+
+	got := shutdownStatus(t, st)
+	if !got.ShuttingDown {
+		t.Errorf("shutdown not reported as in progress: %+v", got)
+	}
+	if got.ActiveConnections != 1 {
+		t.Errorf("wrong number of active connections: got %v want %v", got.ActiveConnections, 1)
+	}
+	if got.DrainSeconds != 3 {
+		t.Errorf("wrong drain time: got %v want %v", got.DrainSeconds, 3)
+	}
+	if err := st.Check(context.Background()); err != ErrShuttingDown {
+		t.Errorf("readiness check returned wrong error: got %v want %v", err, ErrShuttingDown)
+	}
+
+	// Once the request finishes the drain completes and the connection closes
+	close(release)
+	<-done
+	if err := <-shutdownDone; err != nil {
+		t.Fatal(err)
+	}
+	if got := shutdownStatus(t, st); got.ActiveConnections != 0 {
+		t.Errorf("connections left open after shutdown: got %v", got.ActiveConnections)
+	}
+}
+
+func TestShutdownTrackerBegin(t *testing.T) {
+	st := NewShutdownTracker()
+	if err := st.Check(context.Background()); err != nil {
+		t.Errorf("readiness check failed before shutdown: %v", err)
+	}
+
+	st.Begin()
+	first := st.started
+	st.Begin()
+
+	// Repeated signals don't restart the drain clock
+	if st.started != first {
+		t.Errorf("drain start changed on second Begin: got %v want %v", st.started, first)
+	}
+	if got := shutdownStatus(t, st); !got.ShuttingDown {
+		t.Errorf("shutdown not reported as in progress: %+v", got)
+	}
+}
+
+func TestShutdownTrackerLogDrain(t *testing.T) {
+	st := NewShutdownTracker()
+
+	// A request that stays in flight until released
+	inFlight := make(chan struct{})
+	release := make(chan struct{})
+	handler := st.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(inFlight)
+		<-release
+	}))
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/api/users", nil))
+	}()
+	<-inFlight
+	st.Begin()
+
+	if got := shutdownStatus(t, st); got.InFlightRequests != 1 {
+		t.Errorf("wrong number of in-flight requests: got %v want %v", got.InFlightRequests, 1)
+	}
+
+	// The drain deadline passes while the request is still in flight
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, nil))
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	st.LogDrain(ctx, logger, 10*time.Millisecond)
+
+	counts := map[string]float64{}
+	for line := range bytes.Lines(buf.Bytes()) {
+		var entry map[string]any
+		if err := json.Unmarshal(line, &entry); err != nil {
+			t.Fatalf("could not parse log line %q: %v", line, err)
+		}
+		msg, _ := entry["msg"].(string)
+		counts[msg], _ = entry["in_flight"].(float64)
+	}
+	for _, msg := range []string{"Draining in-flight requests", "Waiting for in-flight requests", "Stopped waiting for in-flight requests"} {
+		if got, ok := counts[msg]; !ok || got != 1 {
+			t.Errorf("drain log %q has wrong in-flight count: got %v (logged: %v) want %v", msg, got, ok, 1)
+		}
+	}
+
+	// Once the request finishes the drain is reported as complete
+	close(release)
+	<-done
+	buf.Reset()
+	st.LogDrain(context.Background(), logger, 10*time.Millisecond)
+	if !bytes.Contains(buf.Bytes(), []byte("All in-flight requests finished")) {
+		t.Errorf("drain completion not logged: got %q", buf.String())
+	}
+}
@@ -0,0 +1,82 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+)
+
+// streamBatchSize is how many users StreamUsers reads from the store, and
+// encodes, between flushes
+const streamBatchSize = 100
+
+// StreamUsers sends all users as a JSON array like GetUsers, but writes it
+// incrementally: users are read from the store in batches, encoded one at a
+// time and flushed after every batch, so memory use doesn't grow with the
+// number of users. Once the response has started, a failure can no longer
+// change its status, so the array is left unterminated and clients see
+// invalid JSON rather than a silently truncated list.
+// The stream is always compact JSON: the Accept header and the pretty query
+// parameter are ignored, since XML and indentation would need the whole list.
+func (a *API) StreamUsers(w http.ResponseWriter, r *http.Request) {
+	a.log(r.Context()).Info("Streaming all users", "path", r.URL.Path)
+
+	// Read the first batch before responding, so a failing store still gets a proper error
+	batch, err := a.users.ListAfter(r.Context(), 0, streamBatchSize)
+	if err != nil {
+		a.log(r.Context()).Error("Failed to list users", "error", err)
+		errorResponse(w, r, http.StatusInternalServerError, "Failed to retrieve users")
+		return
+	}
+
+	rc := http.NewResponseController(w)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+
+	enc := json.NewEncoder(w)
+	count := 0
+	write := func(b string) bool {
+		if _, err := w.Write([]byte(b)); err != nil {
+			a.log(r.Context()).Debug("Failed to write user stream", "error", err)
+			return false
+		}
+		return true
+	}
+
+	if !write("[") {
+		return
+	}
+	for len(batch) > 0 {
+		for _, user := range batch {
+			if count > 0 && !write(",") {
+				return
+			}
+			if err := enc.Encode(user); err != nil {
+				a.log(r.Context()).Error("Failed to encode streamed user", "id", user.ID, "error", err)
+				return
+			}
+			count++
+		}
+
+		if err := rc.Flush(); err != nil && !errors.Is(err, http.ErrNotSupported) {
+			a.log(r.Context()).Debug("Failed to flush user stream", "error", err)
+			return
+		}
+		if len(batch) < streamBatchSize {
+			break
+		}
+
+		batch, err = a.users.ListAfter(r.Context(), batch[len(batch)-1].ID, streamBatchSize)
+		if err != nil {
+			a.log(r.Context()).Error("Failed to list users, user stream is truncated",
+				"streamed", count,
+				"error", err)
+			return
+		}
+	}
+	if !write("]\n") {
+		return
+	}
+
+	a.log(r.Context()).Info("Users streamed", "count", count)
+}
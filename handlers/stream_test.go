@@ -0,0 +1,119 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/kakkoyun/demo-web-service/models"
+	"github.com/kakkoyun/demo-web-service/store"
+)
+
+// failingBatchesStore is a UserStore whose ListAfter fails after the first few batches
+type failingBatchesStore struct {
+	store.UserStore
+	batches int
+}
+
+func (s *failingBatchesStore) ListAfter(ctx context.Context, after, limit int) ([]models.User, error) {
+	if s.batches == 0 {
+		return nil, errors.New("database connection lost")
+	}
+	s.batches--
+	return s.UserStore.ListAfter(ctx, after, limit)
+}
+
+// seedUsers returns n users with IDs 1 to n
+func seedUsers(n int) []models.User {
+	users := make([]models.User, n)
+	for i := range users {
+		users[i] = models.User{ID: i + 1, Name: fmt.Sprintf("User %d", i+1)}
+	}
+	return users
+}
+
+func TestStreamUsersHandler(t *testing.T) {
+	testCases := []struct {
+		name  string
+		users int
+	}{
+		{name: "Empty Store", users: 0},
+		{name: "Single Batch", users: streamBatchSize - 1},
+		{name: "Exact Batches", users: 2 * streamBatchSize},
+		{name: "Large Store", users: 10*streamBatchSize + 7},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			api := NewAPI(store.NewMemoryStore(seedUsers(tc.users)...), nil, WithFaultInjector(NoFaults{}))
+
+			req := httptest.NewRequest("GET", "/api/users/stream", nil)
+			rr := httptest.NewRecorder()
+			api.Routes().ServeHTTP(rr, req)
+
+			if status := rr.Code; status != http.StatusOK {
+				t.Fatalf("handler returned wrong status code: got %v want %v", status, http.StatusOK)
+			}
+			if contentType := rr.Header().Get("Content-Type"); contentType != "application/json" {
+				t.Errorf("handler returned wrong Content-Type: got %q want %q", contentType, "application/json")
+			}
+			if tc.users > 0 && !rr.Flushed {
+				t.Error("handler did not flush the stream")
+			}
+
+			var users []models.User
+			if err := json.Unmarshal(rr.Body.Bytes(), &users); err != nil {
+				t.Fatalf("stream is not a valid JSON array: %v", err)
+			}
+			if len(users) != tc.users {
+				t.Fatalf("stream has wrong number of users: got %d want %d", len(users), tc.users)
+			}
+			for i, user := range users {
+				if user.ID != i+1 {
+					t.Fatalf("stream has wrong user at %d: got ID %d want %d", i, user.ID, i+1)
+				}
+			}
+		})
+	}
+}
+
+func TestStreamUsersHandlerStoreFailure(t *testing.T) {
+	testCases := []struct {
+		name           string
+		batches        int
+		expectedStatus int
+	}{
+		{name: "Before The First Batch", batches: 0, expectedStatus: http.StatusInternalServerError},
+		{name: "Mid Stream", batches: 2, expectedStatus: http.StatusOK},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			users := &failingBatchesStore{UserStore: store.NewMemoryStore(seedUsers(5 * streamBatchSize)...), batches: tc.batches}
+			api := NewAPI(users, nil, WithFaultInjector(NoFaults{}))
+
+			req := httptest.NewRequest("GET", "/api/users/stream", nil)
+			rr := httptest.NewRecorder()
+			api.Routes().ServeHTTP(rr, req)
+
+			if status := rr.Code; status != tc.expectedStatus {
+				t.Fatalf("handler returned wrong status code: got %v want %v", status, tc.expectedStatus)
+			}
+
+			// A stream cut short must not look like a complete list
+			if tc.expectedStatus == http.StatusOK {
+				if json.Valid(rr.Body.Bytes()) {
+					t.Error("truncated stream is valid JSON")
+				}
+				if !strings.HasPrefix(rr.Body.String(), "[") {
+					t.Errorf("stream does not start with an array: %.20q", rr.Body.String())
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,130 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+)
+
+// TimeoutMiddleware creates a middleware that bounds each request with a
+// context deadline of d. Handlers are expected to pass the request context down
+// and give up once it is done. Anything they write after the deadline is
+// dropped, and the client gets a 503 instead. A non-positive d disables the timeout.
+// Streaming routes are long-lived by design and should skip it, see API.Streaming.
+func TimeoutMiddleware(d time.Duration) func(http.Handler) http.Handler {
+	return MethodTimeoutMiddleware(d, d)
+}
+
+// MethodTimeoutMiddleware works like TimeoutMiddleware, but bounds reads (GET,
+// HEAD and OPTIONS requests) by read and requests that change state by write,
+// which usually need longer. A non-positive timeout disables it for that kind
+// of request.
+func MethodTimeoutMiddleware(read, write time.Duration) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if read <= 0 && write <= 0 {
+			return next
+		}
+
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			d := write
+			if isReadMethod(r.Method) {
+				d = read
+			}
+			if d <= 0 {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			ctx, cancel := context.WithTimeout(r.Context(), d)
+			defer cancel()
+
+			tw := &timeoutWriter{ResponseWriter: w, ctx: ctx}
+			next.ServeHTTP(tw, r.WithContext(ctx))
+
+			if !tw.wroteHeader && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				errorResponse(w, r, http.StatusServiceUnavailable, "Request timed out")
+			}
+		})
+	}
+}
+
+// isReadMethod reports whether method only reads state
+func isReadMethod(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return true
+	default:
+		return false
+	}
+}
+
+// timeoutWriter is a wrapper for http.ResponseWriter that rejects writes once
+// the request's deadline has passed
+type timeoutWriter struct {
+	http.ResponseWriter
+	ctx         context.Context
+	wroteHeader bool
+}
+
+// WriteHeader passes the status code on unless the request has timed out
+func (tw *timeoutWriter) WriteHeader(statusCode int) {
+	if tw.wroteHeader || tw.timedOut() {
+		return
+	}
+	tw.wroteHeader = true
+	tw.ResponseWriter.WriteHeader(statusCode)
+}
+
+// Write passes the body on unless the request timed out before the response started
+func (tw *timeoutWriter) Write(b []byte) (int, error) {
+	if !tw.wroteHeader {
+		if tw.timedOut() {
+			return 0, http.ErrHandlerTimeout
+		}
+		tw.wroteHeader = true
+	}
+	return tw.ResponseWriter.Write(b)
+}
+
+// Flush sends buffered data to the client unless the request timed out before
+// the response started
+func (tw *timeoutWriter) Flush() {
+	if !tw.wroteHeader {
+		if tw.timedOut() {
+			return
+		}
+		tw.wroteHeader = true
+	}
+
+	if f, ok := tw.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Unwrap returns the underlying writer, for use by http.ResponseController
+func (tw *timeoutWriter) Unwrap() http.ResponseWriter {
+	return tw.ResponseWriter
+}
+
+// timedOut reports whether the request's deadline has passed
+func (tw *timeoutWriter) timedOut() bool {
+	return errors.Is(tw.ctx.Err(), context.DeadlineExceeded)
+}
+
+// sleepContext waits for d, returning early with the context's error when it is done first
+func sleepContext(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
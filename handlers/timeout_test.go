@@ -0,0 +1,117 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// slowFaults is a FaultInjector that never fails but delays every operation
+type slowFaults time.Duration
+
+func (slowFaults) ShouldFail(string) bool { return false }
+
+func (s slowFaults) Delay(string) time.Duration { return time.Duration(s) }
+
+func TestTimeoutMiddleware(t *testing.T) {
+	testCases := []struct {
+		name           string
+		accept         string
+		delay          time.Duration
+		expectedStatus int
+	}{
+		{name: "Slow Handler", delay: time.Second, expectedStatus: http.StatusServiceUnavailable},
+		{name: "Fast Handler", delay: 0, expectedStatus: http.StatusCreated},
+		// Only streaming routes are exempt, whatever the client accepts
+		{name: "Slow Handler Accepting Event Stream", accept: "text/event-stream", delay: time.Second, expectedStatus: http.StatusServiceUnavailable},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			api, _ := newTestAPI(t, []Option{WithFaultInjector(slowFaults(tc.delay))})
+			handler := Unless(api.Streaming, TimeoutMiddleware(50*time.Millisecond))(api.Routes())
+
+			req := httptest.NewRequest("POST", "/api/users", strings.NewReader(`{"name":"Slow User"}`))
+			if tc.accept != "" {
+				req.Header.Set("Accept", tc.accept)
+			}
+			rr := httptest.NewRecorder()
+
+			start := time.Now()
+			handler.ServeHTTP(rr, req)
+
+			if elapsed := time.Since(start); elapsed >= tc.delay && tc.delay > 0 {
+				t.Errorf("handler did not abort early: took %v", elapsed)
+			}
+			if status := rr.Code; status != tc.expectedStatus {
+				t.Fatalf("handler returned wrong status code: got %v want %v", status, tc.expectedStatus)
+			}
+
+			if tc.expectedStatus == http.StatusServiceUnavailable {
+				var response map[string]string
+				if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+					t.Fatalf("could not parse response body: %v", err)
+				}
+				if response["message"] != "Request timed out" {
+					t.Errorf("handler returned wrong message: got %q want %q", response["message"], "Request timed out")
+				}
+			}
+		})
+	}
+}
+
+func TestTimeoutMiddlewareDropsLateWrites(t *testing.T) {
+	handler := TimeoutMiddleware(10 * time.Millisecond)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// A handler that ignores its context and answers late
+		time.Sleep(30 * time.Millisecond)
+		if _, err := w.Write([]byte("late")); err != http.ErrHandlerTimeout {
+			t.Errorf("late write returned wrong error: got %v want %v", err, http.ErrHandlerTimeout)
+		}
+	}))
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest("GET", "/", nil))
+
+	if status := rr.Code; status != http.StatusServiceUnavailable {
+		t.Errorf("handler returned wrong status code: got %v want %v", status, http.StatusServiceUnavailable)
+	}
+	if strings.Contains(rr.Body.String(), "late") {
+		t.Errorf("late write reached the client: %q", rr.Body.String())
+	}
+}
+
+func TestMethodTimeoutMiddleware(t *testing.T) {
+	// The handler takes longer than reads may, but not as long as writes may
+	slow := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := sleepContext(r.Context(), 100*time.Millisecond); err != nil {
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	})
+	handler := MethodTimeoutMiddleware(20*time.Millisecond, time.Second)(slow)
+
+	testCases := []struct {
+		method         string
+		expectedStatus int
+	}{
+		{method: "GET", expectedStatus: http.StatusServiceUnavailable},
+		{method: "HEAD", expectedStatus: http.StatusServiceUnavailable},
+		{method: "POST", expectedStatus: http.StatusOK},
+		{method: "PATCH", expectedStatus: http.StatusOK},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.method, func(t *testing.T) {
+			req := httptest.NewRequest(tc.method, "/api/users", nil)
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			if status := rr.Code; status != tc.expectedStatus {
+				t.Errorf("handler returned wrong status code: got %v want %v", status, tc.expectedStatus)
+			}
+		})
+	}
+}
@@ -0,0 +1,55 @@
+package handlers
+
+import (
+	"net/http"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// tracerName identifies the spans started by this package
+const tracerName = "github.com/kakkoyun/demo-web-service/handlers"
+
+// TracingMiddleware creates a middleware that traces every request with a
+// server span from tp, continuing any trace propagated in the request headers.
+// The span is stored in the request context, where API routes rename it after
+// their pattern, e.g. "GET /api/users/{id}". It records the response status,
+// and 5xx responses mark it as failed.
+func TracingMiddleware(tp trace.TracerProvider, propagator propagation.TextMapPropagator) func(http.Handler) http.Handler {
+	tracer := tp.Tracer(tracerName)
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ctx := propagator.Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+			ctx, span := tracer.Start(ctx, r.Method,
+				trace.WithSpanKind(trace.SpanKindServer),
+				trace.WithAttributes(
+					attribute.String("http.request.method", r.Method),
+					attribute.String("url.path", r.URL.Path),
+				),
+			)
+			defer span.End()
+
+			rw := newResponseWriter(w)
+			next.ServeHTTP(rw, r.WithContext(ctx))
+
+			span.SetAttributes(attribute.Int("http.response.status_code", rw.statusCode))
+			if rw.statusCode >= http.StatusInternalServerError {
+				span.SetStatus(codes.Error, http.StatusText(rw.statusCode))
+			}
+		})
+	}
+}
+
+// nameSpan names the request's span after the pattern of the route that matched it
+func nameSpan(r *http.Request) {
+	if r.Pattern == "" {
+		return
+	}
+
+	span := trace.SpanFromContext(r.Context())
+	span.SetName(r.Pattern)
+	span.SetAttributes(attribute.String("http.route", r.Pattern))
+}
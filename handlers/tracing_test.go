@@ -0,0 +1,129 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/kakkoyun/demo-web-service/store"
+)
+
+// newTracedAPI returns a handler serving the API with tracing, and the recorder its spans end up in
+func newTracedAPI(t *testing.T, opts []Option) (http.Handler, *tracetest.SpanRecorder) {
+	t.Helper()
+
+	recorder := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	t.Cleanup(func() { _ = tp.Shutdown(t.Context()) })
+
+	users := store.NewTracingUserStore(store.NewDemoStore(), tp)
+	opts = append([]Option{WithFaultInjector(NoFaults{})}, opts...)
+	api := NewAPI(users, nil, opts...)
+
+	return TracingMiddleware(tp, propagation.TraceContext{})(api.Routes()), recorder
+}
+
+// spanAttribute returns the value of the attribute key of span
+func spanAttribute(span sdktrace.ReadOnlySpan, key attribute.Key) (attribute.Value, bool) {
+	for _, kv := range span.Attributes() {
+		if kv.Key == key {
+			return kv.Value, true
+		}
+	}
+	return attribute.Value{}, false
+}
+
+func TestTracingMiddleware(t *testing.T) {
+	testCases := []struct {
+		name           string
+		path           string
+		faults         FaultInjector
+		expectedName   string
+		expectedStatus int
+		expectedChild  string
+	}{
+		{name: "Get User", path: "/api/users/1", expectedName: "GET /api/users/{id}", expectedStatus: http.StatusOK, expectedChild: "UserStore.Get"},
+		{name: "List Users", path: "/api/users", expectedName: "GET /api/users", expectedStatus: http.StatusOK, expectedChild: "UserStore.List"},
+		{name: "Server Error", path: "/api/users", faults: stubFaults{OpListUsers: true}, expectedName: "GET /api/users", expectedStatus: http.StatusInternalServerError},
+		{name: "Unknown Route", path: "/nowhere", expectedName: "GET", expectedStatus: http.StatusNotFound},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var opts []Option
+			if tc.faults != nil {
+				opts = append(opts, WithFaultInjector(tc.faults))
+			}
+			handler, recorder := newTracedAPI(t, opts)
+
+			req := httptest.NewRequest("GET", tc.path, nil)
+			handler.ServeHTTP(httptest.NewRecorder(), req)
+
+			var server sdktrace.ReadOnlySpan
+			for _, span := range recorder.Ended() {
+				if span.SpanKind() == trace.SpanKindServer {
+					if server != nil {
+						t.Fatal("more than one server span for a request")
+					}
+					server = span
+				}
+			}
+			if server == nil {
+				t.Fatal("no server span for the request")
+			}
+
+			if server.Name() != tc.expectedName {
+				t.Errorf("span has wrong name: got %q want %q", server.Name(), tc.expectedName)
+			}
+			if status, _ := spanAttribute(server, "http.response.status_code"); status.AsInt64() != int64(tc.expectedStatus) {
+				t.Errorf("span has wrong status code: got %v want %v", status.AsInt64(), tc.expectedStatus)
+			}
+			if failed := server.Status().Code == codes.Error; failed != (tc.expectedStatus >= 500) {
+				t.Errorf("span has wrong status: got %v", server.Status())
+			}
+
+			if tc.expectedChild == "" {
+				return
+			}
+			var child sdktrace.ReadOnlySpan
+			for _, span := range recorder.Ended() {
+				if span.Name() == tc.expectedChild {
+					child = span
+				}
+			}
+			if child == nil {
+				t.Fatalf("no %s span for the request", tc.expectedChild)
+			}
+			if child.Parent().SpanID() != server.SpanContext().SpanID() {
+				t.Errorf("%s span is not a child of the server span", tc.expectedChild)
+			}
+		})
+	}
+}
+
+func TestTracingMiddlewareContinuesPropagatedTrace(t *testing.T) {
+	handler, recorder := newTracedAPI(t, nil)
+
+	const traceID = "4bf92f3577b34da6a3ce929d0e0e4736"
+	req := httptest.NewRequest("GET", "/api/health", nil)
+	req.Header.Set("traceparent", "00-"+traceID+"-00f067aa0ba902b7-01")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	spans := recorder.Ended()
+	if len(spans) != 1 {
+		t.Fatalf("wrong number of spans: got %v want %v", len(spans), 1)
+	}
+	if got := spans[0].SpanContext().TraceID().String(); got != traceID {
+		t.Errorf("span has wrong trace ID: got %v want %v", got, traceID)
+	}
+	if !spans[0].Parent().IsRemote() {
+		t.Error("span's parent is not the propagated remote span")
+	}
+}
@@ -0,0 +1,220 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+
+	"braces.dev/errtrace"
+
+	"github.com/kakkoyun/demo-web-service/models"
+)
+
+// DefaultWebhookBackoff is the default delay before the first retry of a delivery
+const DefaultWebhookBackoff = 500 * time.Millisecond
+
+// webhookCategory is how webhook delivery is reported as pending background work
+const webhookCategory = "webhook"
+
+// userWebhook is a created user waiting to be sent to the webhook URL
+type userWebhook struct {
+	// requestID is the ID of the request that created the user, passed on so
+	// that the delivery can be tied to it
+	requestID string
+	user      models.User
+}
+
+// WebhookDispatcher POSTs every created user as JSON to a webhook URL.
+// Deliveries happen one at a time on a single background worker, so a slow
+// receiver can't pile up goroutines; users created while the queue is full
+// are dropped rather than slowing down the API. Failed deliveries are retried
+// with exponential backoff, unless the receiver rejected the request.
+type WebhookDispatcher struct {
+	// ctx is canceled to abandon the remaining deliveries, see Close
+	ctx        context.Context
+	cancel     context.CancelFunc
+	client     *http.Client
+	queue      chan userWebhook
+	done       chan struct{}
+	stopped    chan struct{}
+	url        string
+	backoff    time.Duration
+	maxRetries int
+	closeOnce  sync.Once
+}
+
+// NewWebhookDispatcher creates a WebhookDispatcher sending users to url.
+// Up to queueSize users wait for delivery, each delivery attempt is limited to
+// timeout and failed deliveries are retried up to maxRetries times.
+// It starts delivering once it is passed to NewAPI with WithWebhooks.
+func NewWebhookDispatcher(url string, queueSize, maxRetries int, timeout time.Duration) *WebhookDispatcher {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &WebhookDispatcher{
+		ctx:        ctx,
+		cancel:     cancel,
+		client:     &http.Client{Timeout: timeout},
+		queue:      make(chan userWebhook, max(queueSize, 1)),
+		done:       make(chan struct{}),
+		url:        url,
+		backoff:    DefaultWebhookBackoff,
+		maxRetries: maxRetries,
+	}
+}
+
+// start runs the delivery worker as tracked background work, so that a
+// graceful shutdown waits for the queue to drain
+func (d *WebhookDispatcher) start(background *Background) {
+	d.stopped = make(chan struct{})
+	background.Go(webhookCategory, func() {
+		defer close(d.stopped)
+		d.run(d.ctx, background.log())
+	})
+}
+
+// Close stops accepting users and waits for the worker to deliver the users
+// already queued. Once ctx is done, the delivery in progress is canceled and
+// the users still queued are logged and dropped, so a shutdown deadline bounds
+// how long Close takes.
+func (d *WebhookDispatcher) Close(ctx context.Context) {
+	d.closeOnce.Do(func() { close(d.done) })
+	if d.stopped == nil {
+		return
+	}
+
+	stop := context.AfterFunc(ctx, d.cancel)
+	defer stop()
+	<-d.stopped
+}
+
+// enqueue queues user for delivery without blocking. It reports false if the
+// user was dropped because the queue is full or the dispatcher is closed.
+func (d *WebhookDispatcher) enqueue(ctx context.Context, user models.User) bool {
+	select {
+	case <-d.done:
+		return false
+	default:
+	}
+
+	select {
+	case d.queue <- userWebhook{requestID: RequestIDFromContext(ctx), user: user}:
+		return true
+	default:
+		return false
+	}
+}
+
+// run delivers queued users until the dispatcher is closed and the queue is
+// empty, or until ctx is done
+func (d *WebhookDispatcher) run(ctx context.Context, logger *slog.Logger) {
+	for {
+		var hook userWebhook
+		select {
+		case hook = <-d.queue:
+		case <-ctx.Done():
+			d.drop(logger)
+			return
+		case <-d.done:
+			select {
+			case hook = <-d.queue:
+			default:
+				return
+			}
+		}
+
+		if ctx.Err() != nil {
+			d.drop(logger, hook)
+			return
+		}
+		d.deliver(ctx, logger, hook)
+	}
+}
+
+// drop empties the queue, logging the users whose webhooks won't be sent,
+// including those already taken from the queue
+func (d *WebhookDispatcher) drop(logger *slog.Logger, taken ...userWebhook) {
+	var ids []int
+	for _, hook := range taken {
+		ids = append(ids, hook.user.ID)
+	}
+	for {
+		select {
+		case hook := <-d.queue:
+			ids = append(ids, hook.user.ID)
+		default:
+			if len(ids) > 0 {
+				logger.Warn("Webhooks dropped before delivery", "count", len(ids), "user_ids", ids)
+			}
+			return
+		}
+	}
+}
+
+// deliver sends hook to the webhook URL, retrying transient failures until
+// ctx is done
+func (d *WebhookDispatcher) deliver(ctx context.Context, logger *slog.Logger, hook userWebhook) {
+	logger = logger.With("request_id", hook.requestID, "user_id", hook.user.ID)
+
+	body, err := json.Marshal(hook.user)
+	if err != nil {
+		logger.Error("Failed to encode webhook", "error", err)
+		return
+	}
+
+	err = d.post(ctx, hook.requestID, body)
+	for attempt := 1; err != nil && isTransient(err) && ctx.Err() == nil && attempt <= d.maxRetries; attempt++ {
+		delay := backoff(d.backoff, attempt)
+		logger.Info("Retrying webhook delivery", "attempt", attempt, "delay", delay, "error", err)
+		if sleepContext(ctx, delay) != nil {
+			break
+		}
+
+		err = d.post(ctx, hook.requestID, body)
+	}
+	if err != nil {
+		logger.Error("Webhook delivery failed", "url", d.url, "error", err)
+		return
+	}
+
+	logger.Debug("Webhook delivered", "url", d.url)
+}
+
+// errWebhookRejected is returned when the receiver answers with a status that
+// retrying won't change, such as 400
+var errWebhookRejected = errors.New("webhook rejected")
+
+// post makes a single delivery attempt. Network errors, 429s and server
+// errors are transient; other unsuccessful statuses are not.
+func (d *WebhookDispatcher) post(ctx context.Context, requestID string, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, d.url, bytes.NewReader(body))
+	if err != nil {
+		return errtrace.Wrap(fmt.Errorf("creating webhook request: %w", err))
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if requestID != "" {
+		req.Header.Set(RequestIDHeader, requestID)
+	}
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return errtrace.Wrap(transient(fmt.Errorf("sending webhook: %w", err)))
+	}
+	defer resp.Body.Close()
+	// Read the body so that the connection can be reused
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 1<<16))
+
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		return nil
+	case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500:
+		return errtrace.Wrap(transient(fmt.Errorf("webhook receiver returned %s", resp.Status)))
+	default:
+		return errtrace.Wrap(fmt.Errorf("%w: receiver returned %s", errWebhookRejected, resp.Status))
+	}
+}
@@ -0,0 +1,234 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/kakkoyun/demo-web-service/models"
+)
+
+// webhookReceiver records the webhooks it receives and answers with the
+// statuses it is given in turn, then with 204
+type webhookReceiver struct {
+	statuses []int
+	bodies   []string
+	headers  []http.Header
+	mu       sync.Mutex
+}
+
+func (rcv *webhookReceiver) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+
+	rcv.mu.Lock()
+	defer rcv.mu.Unlock()
+	rcv.bodies = append(rcv.bodies, string(body))
+	rcv.headers = append(rcv.headers, r.Header.Clone())
+
+	status := http.StatusNoContent
+	if len(rcv.statuses) > 0 {
+		status, rcv.statuses = rcv.statuses[0], rcv.statuses[1:]
+	}
+	w.WriteHeader(status)
+}
+
+// received returns the bodies of the webhooks received so far
+func (rcv *webhookReceiver) received() []string {
+	rcv.mu.Lock()
+	defer rcv.mu.Unlock()
+	return append([]string(nil), rcv.bodies...)
+}
+
+// newWebhookTestAPI creates an API sending webhooks to url with fast retries
+func newWebhookTestAPI(t *testing.T, url string) (*API, *WebhookDispatcher) {
+	t.Helper()
+
+	webhooks := NewWebhookDispatcher(url, 100, 2, time.Second)
+	webhooks.backoff = time.Millisecond
+	api, _ := newTestAPI(t, []Option{WithWebhooks(webhooks)})
+	t.Cleanup(func() {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		webhooks.Close(ctx)
+		_ = api.Background().Wait(context.Background())
+	})
+	return api, webhooks
+}
+
+// drainWebhooks closes webhooks and waits until every queued webhook is delivered
+func drainWebhooks(t *testing.T, api *API, webhooks *WebhookDispatcher) {
+	t.Helper()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	webhooks.Close(ctx)
+	if err := api.Background().Wait(ctx); err != nil {
+		t.Fatalf("webhooks were not delivered: %v", err)
+	}
+}
+
+// postUser creates a user named name through handler and checks it succeeded
+func postUser(t *testing.T, handler http.Handler, name string) models.User {
+	t.Helper()
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest("POST", "/api/users", strings.NewReader(`{"name":"`+name+`"}`)))
+	if status := rr.Code; status != http.StatusCreated {
+		t.Fatalf("handler returned wrong status code: got %v want %v", status, http.StatusCreated)
+	}
+
+	var response models.UserResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+		t.Fatalf("could not parse response body: %v", err)
+	}
+	return *response.User
+}
+
+func TestWebhookDeliversCreatedUser(t *testing.T) {
+	receiver := &webhookReceiver{}
+	server := httptest.NewServer(receiver)
+	defer server.Close()
+
+	api, webhooks := newWebhookTestAPI(t, server.URL)
+	handler := RequestIDMiddleware(api.Routes())
+
+	req := httptest.NewRequest("POST", "/api/users", strings.NewReader(`{"name":"Alice"}`))
+	req.Header.Set(RequestIDHeader, "req-42")
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	if status := rr.Code; status != http.StatusCreated {
+		t.Fatalf("handler returned wrong status code: got %v want %v", status, http.StatusCreated)
+	}
+	var response models.UserResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &response); err != nil {
+		t.Fatalf("could not parse response body: %v", err)
+	}
+
+	drainWebhooks(t, api, webhooks)
+
+	bodies := receiver.received()
+	if len(bodies) != 1 {
+		t.Fatalf("receiver got wrong number of webhooks: got %d want 1", len(bodies))
+	}
+	var user models.User
+	if err := json.Unmarshal([]byte(bodies[0]), &user); err != nil {
+		t.Fatalf("could not parse webhook body: %v", err)
+	}
+	if user != *response.User {
+		t.Errorf("webhook has wrong user: got %+v want %+v", user, *response.User)
+	}
+	if ct := receiver.headers[0].Get("Content-Type"); ct != "application/json" {
+		t.Errorf("webhook has wrong content type: got %q want %q", ct, "application/json")
+	}
+	if id := receiver.headers[0].Get(RequestIDHeader); id != "req-42" {
+		t.Errorf("webhook has wrong request ID: got %q want %q", id, "req-42")
+	}
+}
+
+func TestWebhookRetries(t *testing.T) {
+	testCases := []struct {
+		name             string
+		statuses         []int
+		expectedAttempts int
+	}{
+		{name: "Success", statuses: nil, expectedAttempts: 1},
+		{name: "Recovers From Server Errors", statuses: []int{http.StatusServiceUnavailable, http.StatusBadGateway}, expectedAttempts: 3},
+		{name: "Recovers From Rate Limiting", statuses: []int{http.StatusTooManyRequests}, expectedAttempts: 2},
+		{name: "Gives Up After Max Retries", statuses: []int{500, 502, 503, 504}, expectedAttempts: 3},
+		{name: "Rejected", statuses: []int{http.StatusBadRequest}, expectedAttempts: 1},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			receiver := &webhookReceiver{statuses: tc.statuses}
+			server := httptest.NewServer(receiver)
+			defer server.Close()
+
+			api, webhooks := newWebhookTestAPI(t, server.URL)
+			postUser(t, api.Routes(), "Alice")
+			drainWebhooks(t, api, webhooks)
+
+			if attempts := len(receiver.received()); attempts != tc.expectedAttempts {
+				t.Errorf("receiver got wrong number of attempts: got %d want %d", attempts, tc.expectedAttempts)
+			}
+		})
+	}
+}
+
+func TestWebhookFailureDoesNotAffectResponse(t *testing.T) {
+	// Nothing listens on a closed server, so every delivery fails
+	server := httptest.NewServer(http.NotFoundHandler())
+	server.Close()
+
+	api, webhooks := newWebhookTestAPI(t, server.URL)
+	postUser(t, api.Routes(), "Alice")
+	drainWebhooks(t, api, webhooks)
+}
+
+func TestWebhookDrainsQueueOnClose(t *testing.T) {
+	receiver := &webhookReceiver{}
+	server := httptest.NewServer(receiver)
+	defer server.Close()
+
+	api, webhooks := newWebhookTestAPI(t, server.URL)
+	for _, name := range []string{"Alice", "Bob", "Carol", "Dave", "Eve"} {
+		postUser(t, api.Routes(), name)
+	}
+	drainWebhooks(t, api, webhooks)
+
+	if got := len(receiver.received()); got != 5 {
+		t.Errorf("receiver got wrong number of webhooks: got %d want 5", got)
+	}
+
+	// Users created after closing are no longer sent
+	postUser(t, api.Routes(), "Frank")
+	if got := len(receiver.received()); got != 5 {
+		t.Errorf("receiver got webhook after close: got %d want 5", got)
+	}
+}
+
+func TestWebhookEnqueueDropsWhenFull(t *testing.T) {
+	// Without an API the worker never runs, so the queue fills up
+	webhooks := NewWebhookDispatcher("http://localhost", 1, 0, time.Second)
+
+	if !webhooks.enqueue(context.Background(), models.User{ID: 1}) {
+		t.Errorf("enqueue dropped webhook with room in the queue")
+	}
+	if webhooks.enqueue(context.Background(), models.User{ID: 2}) {
+		t.Errorf("enqueue accepted webhook into a full queue")
+	}
+}
+
+func TestWebhookCloseCancelsDeliveriesAtDeadline(t *testing.T) {
+	// The receiver never answers, holding every delivery until it is canceled.
+	// Its request context is only canceled once the body has been read.
+	server := httptest.NewServer(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+		_, _ = io.Copy(io.Discard, r.Body)
+		<-r.Context().Done()
+	}))
+	defer server.Close()
+
+	logs := captureLogs(t)
+	api, webhooks := newWebhookTestAPI(t, server.URL)
+	for _, name := range []string{"Alice", "Bob", "Carol"} {
+		postUser(t, api.Routes(), name)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	webhooks.Close(ctx)
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("Close kept delivering after the deadline: took %v", elapsed)
+	}
+
+	if !strings.Contains(logs.String(), "Webhooks dropped before delivery") {
+		t.Errorf("webhooks left in the queue were not logged: %s", logs)
+	}
+}
@@ -0,0 +1,66 @@
+// Package ctxkeys stores request-scoped values shared across packages in a
+// context.Context. Its keys are of an unexported type, so they can't collide
+// with keys defined anywhere else, and every value has a typed setter and
+// getter. Getters return the zero value when the key is absent.
+package ctxkeys
+
+import (
+	"context"
+	"log/slog"
+)
+
+// key is the type for keys stored in a context by this package
+type key int
+
+const (
+	requestIDKey key = iota
+	loggerKey
+	subjectKey
+	ownerKey
+)
+
+// WithRequestID returns a copy of ctx carrying the request ID id
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey, id)
+}
+
+// RequestID returns the request ID stored in ctx, or an empty string if there is none
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey).(string)
+	return id
+}
+
+// WithLogger returns a copy of ctx carrying the request-scoped logger
+func WithLogger(ctx context.Context, logger *slog.Logger) context.Context {
+	return context.WithValue(ctx, loggerKey, logger)
+}
+
+// Logger returns the request-scoped logger stored in ctx, or nil if there is none
+func Logger(ctx context.Context) *slog.Logger {
+	logger, _ := ctx.Value(loggerKey).(*slog.Logger)
+	return logger
+}
+
+// WithSubject returns a copy of ctx carrying the authenticated subject of a
+// bearer token, i.e. the user the request is made on behalf of
+func WithSubject(ctx context.Context, subject string) context.Context {
+	return context.WithValue(ctx, subjectKey, subject)
+}
+
+// Subject returns the authenticated subject stored in ctx, or an empty string if there is none
+func Subject(ctx context.Context) string {
+	subject, _ := ctx.Value(subjectKey).(string)
+	return subject
+}
+
+// WithOwner returns a copy of ctx carrying the owner of the API key a request
+// was authenticated with
+func WithOwner(ctx context.Context, owner string) context.Context {
+	return context.WithValue(ctx, ownerKey, owner)
+}
+
+// Owner returns the API key owner stored in ctx, or an empty string if there is none
+func Owner(ctx context.Context) string {
+	owner, _ := ctx.Value(ownerKey).(string)
+	return owner
+}
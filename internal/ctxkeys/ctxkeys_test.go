@@ -0,0 +1,56 @@
+package ctxkeys
+
+import (
+	"context"
+	"log/slog"
+	"testing"
+)
+
+func TestGettersReturnZeroValuesWhenAbsent(t *testing.T) {
+	ctx := context.Background()
+
+	if got := RequestID(ctx); got != "" {
+		t.Errorf("RequestID() = %q, want empty", got)
+	}
+	if got := Logger(ctx); got != nil {
+		t.Errorf("Logger() = %v, want nil", got)
+	}
+	if got := Subject(ctx); got != "" {
+		t.Errorf("Subject() = %q, want empty", got)
+	}
+	if got := Owner(ctx); got != "" {
+		t.Errorf("Owner() = %q, want empty", got)
+	}
+}
+
+func TestSettersRoundTrip(t *testing.T) {
+	logger := slog.New(slog.DiscardHandler)
+
+	ctx := context.Background()
+	ctx = WithRequestID(ctx, "req-1")
+	ctx = WithLogger(ctx, logger)
+	ctx = WithSubject(ctx, "alice")
+	ctx = WithOwner(ctx, "ci")
+
+	if got := RequestID(ctx); got != "req-1" {
+		t.Errorf("RequestID() = %q, want %q", got, "req-1")
+	}
+	if got := Logger(ctx); got != logger {
+		t.Errorf("Logger() = %v, want %v", got, logger)
+	}
+	if got := Subject(ctx); got != "alice" {
+		t.Errorf("Subject() = %q, want %q", got, "alice")
+	}
+	if got := Owner(ctx); got != "ci" {
+		t.Errorf("Owner() = %q, want %q", got, "ci")
+	}
+}
+
+func TestKeysDontCollideWithOtherKeys(t *testing.T) {
+	// Keys of another type with the same underlying value are distinct keys
+	ctx := context.WithValue(context.Background(), int(requestIDKey), "foreign")
+
+	if got := RequestID(ctx); got != "" {
+		t.Errorf("RequestID() = %q, want empty", got)
+	}
+}
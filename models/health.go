@@ -0,0 +1,39 @@
+package models
+
+import "time"
+
+// HealthResponse is the response format for the health check endpoint
+type HealthResponse struct {
+	// Timestamp is the time of the check, truncated to seconds
+	Timestamp time.Time `json:"timestamp" xml:"timestamp"`
+	Status    string    `json:"status" xml:"status"`
+	Version   string    `json:"version" xml:"version"`
+	// Uptime is the number of seconds since the process started
+	Uptime         float64 `json:"uptime" xml:"uptime"`
+	RequestsServed uint64  `json:"requests_served" xml:"requests_served"`
+}
+
+// CheckResult is the outcome of a single readiness check
+type CheckResult struct {
+	Name     string `json:"name"`
+	Status   string `json:"status"`
+	Error    string `json:"error,omitempty"`
+	Critical bool   `json:"critical"`
+	// Fatal checks make the service not ready whatever the readiness policy
+	Fatal bool `json:"fatal,omitempty"`
+}
+
+// ReadinessResponse is the response format for the readiness endpoint.
+// Status is "ready", "degraded" when only tolerated checks fail, or "not ready".
+type ReadinessResponse struct {
+	Status string        `json:"status"`
+	Checks []CheckResult `json:"checks,omitempty"`
+}
+
+// ShutdownStatus is the response format for the shutdown status endpoint
+type ShutdownStatus struct {
+	ActiveConnections int64   `json:"active_connections"`
+	InFlightRequests  int64   `json:"in_flight_requests"`
+	DrainSeconds      float64 `json:"drain_seconds"`
+	ShuttingDown      bool    `json:"shutting_down"`
+}
@@ -0,0 +1,32 @@
+package models
+
+// ErrorResponse is the standard format for error responses.
+// Code is a stable, machine-readable identifier such as USER_NOT_FOUND, while
+// Message is meant for humans and may change.
+type ErrorResponse struct {
+	Status  string       `json:"status" xml:"status"`
+	Code    string       `json:"code" xml:"code"`
+	Message string       `json:"message" xml:"message"`
+	Details []FieldError `json:"details,omitempty" xml:"details>detail,omitempty"`
+}
+
+// FieldError describes why the value of a request field is invalid
+type FieldError struct {
+	Field   string `json:"field" xml:"field"`
+	Message string `json:"message" xml:"message"`
+}
+
+// String returns the field and message, e.g. "name is required"
+func (e FieldError) String() string {
+	return e.Field + " " + e.Message
+}
+
+// MessageResponse is the format for responses that only carry a message
+type MessageResponse struct {
+	Message string `json:"message" xml:"message"`
+}
+
+// StatusResponse is the format for responses that only carry a status
+type StatusResponse struct {
+	Status string `json:"status" xml:"status"`
+}
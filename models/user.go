@@ -1,17 +1,154 @@
 package models
 
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
 // User represents a user in the system
 type User struct {
-	Name string `json:"name"`
-	ID   int    `json:"id"`
+	// CreatedAt and UpdatedAt are set by the store and encoded in RFC 3339 format
+	CreatedAt time.Time `json:"created_at" xml:"created_at"`
+	UpdatedAt time.Time `json:"updated_at" xml:"updated_at"`
+	Name      string    `json:"name" xml:"name"`
+	// PublicID is the user's externally visible ID, which may be opaque
+	PublicID string `json:"public_id,omitempty" xml:"public_id,omitempty"`
+	ID       int    `json:"id" xml:"id"`
+}
+
+// UnmarshalJSON decodes a user, accepting its ID either as a number or as a
+// string holding an integer, e.g. "5", as some clients send IDs as strings
+func (u *User) UnmarshalJSON(data []byte) error {
+	// user has User's fields but not its methods, avoiding infinite recursion
+	type user User
+	aux := struct {
+		*user
+		ID json.RawMessage `json:"id"`
+	}{user: (*user)(u)}
+
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+	if len(aux.ID) == 0 || bytes.Equal(aux.ID, []byte("null")) {
+		return nil
+	}
+
+	id, err := parseID(aux.ID)
+	if err != nil {
+		return err
+	}
+	u.ID = id
+
+	return nil
+}
+
+// parseID parses a JSON number or a string holding an integer as a user ID
+func parseID(raw json.RawMessage) (int, error) {
+	var id int
+	if err := json.Unmarshal(raw, &id); err == nil {
+		return id, nil
+	}
+
+	var s string
+	if err := json.Unmarshal(raw, &s); err == nil {
+		if id, err := strconv.Atoi(strings.TrimSpace(s)); err == nil {
+			return id, nil
+		}
+	}
+
+	return 0, fmt.Errorf("id must be an integer or a string holding one, got %s", raw)
+}
+
+// MaxNameLength is the maximum number of characters in a user's name
+const MaxNameLength = 100
+
+// Validate checks the user's fields and returns an error for every invalid
+// one, or nil if the user is valid
+func (u User) Validate() []FieldError {
+	var problems []FieldError
+
+	if u.ID < 0 {
+		problems = append(problems, FieldError{Field: "id", Message: "must not be negative"})
+	}
+
+	switch name := strings.TrimSpace(u.Name); {
+	case name == "":
+		problems = append(problems, FieldError{Field: "name", Message: "is required"})
+	case utf8.RuneCountInString(name) > MaxNameLength:
+		problems = append(problems, FieldError{Field: "name", Message: fmt.Sprintf("must be at most %d characters", MaxNameLength)})
+	}
+	if strings.ContainsFunc(u.Name, unicode.IsControl) {
+		problems = append(problems, FieldError{Field: "name", Message: "must not contain control characters"})
+	}
+
+	return problems
+}
+
+// UserPatch is a partial update of a user; nil fields are left unchanged
+type UserPatch struct {
+	Name *string `json:"name,omitempty"`
+}
+
+// Apply returns the user with the patch's fields applied
+func (p UserPatch) Apply(user User) User {
+	if p.Name != nil {
+		user.Name = *p.Name
+	}
+	return user
 }
 
 // UserResponse is the standard format for User responses
 type UserResponse struct {
-	Status  string `json:"status,omitempty"`
-	Message string `json:"message,omitempty"`
-	User    *User  `json:"user,omitempty"`
-	Users   []User `json:"users,omitempty"`
+	Status  string `json:"status,omitempty" xml:"status,omitempty"`
+	Message string `json:"message,omitempty" xml:"message,omitempty"`
+	User    *User  `json:"user,omitempty" xml:"user,omitempty"`
+	Users   []User `json:"users,omitempty" xml:"users>user,omitempty"`
+}
+
+// UserListResponse is the response format for user listings.
+// Users and Count are always present, so an empty list can't be mistaken for a
+// response that went wrong.
+// Pagination is only present when the request asked for a page.
+type UserListResponse struct {
+	Pagination *Pagination `json:"pagination,omitempty" xml:"pagination,omitempty"`
+	Status     string      `json:"status" xml:"status"`
+	Users      []User      `json:"users" xml:"users>user"`
+	Count      int         `json:"count" xml:"count"`
+}
+
+// Pagination describes a page of a listing.
+// NextCursor is the opaque cursor of the next page, empty on the last one.
+type Pagination struct {
+	NextCursor string `json:"next_cursor,omitempty" xml:"next_cursor,omitempty"`
+	Limit      int    `json:"limit" xml:"limit"`
+}
+
+// UserCountResponse is the response format for user counts
+type UserCountResponse struct {
+	Status string `json:"status" xml:"status"`
+	Count  int    `json:"count" xml:"count"`
+}
+
+// ImportSummary reports the outcome of importing users
+type ImportSummary struct {
+	Policy      string `json:"policy" xml:"policy"`
+	SkippedIDs  []int  `json:"skipped_ids,omitempty" xml:"skipped_ids>id,omitempty"`
+	Imported    int    `json:"imported" xml:"imported"`
+	Overwritten int    `json:"overwritten" xml:"overwritten"`
+	Skipped     int    `json:"skipped" xml:"skipped"`
+}
+
+// ImportResponse is the response format for user imports
+type ImportResponse struct {
+	Status  string        `json:"status" xml:"status"`
+	Message string        `json:"message,omitempty" xml:"message,omitempty"`
+	Summary ImportSummary `json:"summary" xml:"summary"`
 }
 
 // NewUser creates a new user with the given id and name
@@ -0,0 +1,160 @@
+package store
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/hashicorp/golang-lru/v2/expirable"
+
+	"github.com/kakkoyun/demo-web-service/models"
+)
+
+// Ensure CachingUserStore implements UserStore
+var _ UserStore = (*CachingUserStore)(nil)
+
+// CacheStats counts the lookups served by a CachingUserStore
+type CacheStats struct {
+	Hits   uint64
+	Misses uint64
+}
+
+// CachingUserStore is a UserStore that caches the users returned by Get in a
+// fixed-size LRU cache, each for at most a TTL. Writes through the cache
+// invalidate the users they change; writes made directly to the wrapped store
+// are only seen once the cached entries expire.
+// Every method is spelled out rather than promoted from the wrapped store, so
+// that a new write method can't bypass invalidation.
+type CachingUserStore struct {
+	users  UserStore
+	cache  *expirable.LRU[int, models.User]
+	hits   atomic.Uint64
+	misses atomic.Uint64
+	// mu guards generation, which changes on every write so that a lookup
+	// racing with a write doesn't cache the user as it was before the write
+	mu         sync.Mutex
+	generation uint64
+}
+
+// NewCachingUserStore wraps users with a cache holding up to size users for ttl each
+func NewCachingUserStore(users UserStore, size int, ttl time.Duration) *CachingUserStore {
+	return &CachingUserStore{
+		users: users,
+		cache: expirable.NewLRU[int, models.User](size, nil, ttl),
+	}
+}
+
+// List returns all users ordered by ID from the wrapped store
+func (s *CachingUserStore) List(ctx context.Context) ([]models.User, error) {
+	return s.users.List(ctx)
+}
+
+// ListAfter returns up to limit users with an ID greater than after from the wrapped store
+func (s *CachingUserStore) ListAfter(ctx context.Context, after, limit int) ([]models.User, error) {
+	return s.users.ListAfter(ctx, after, limit)
+}
+
+// Count returns the number of users whose name contains name from the wrapped store
+func (s *CachingUserStore) Count(ctx context.Context, name string) (int, error) {
+	return s.users.Count(ctx, name)
+}
+
+// Get returns the user with the given ID, from the cache when possible.
+// Failed lookups, including those for missing users, aren't cached.
+func (s *CachingUserStore) Get(ctx context.Context, id int) (models.User, error) {
+	if user, ok := s.cache.Get(id); ok {
+		s.hits.Add(1)
+		return user, nil
+	}
+	s.misses.Add(1)
+
+	generation := s.currentGeneration()
+	user, err := s.users.Get(ctx, id)
+	if err != nil {
+		return models.User{}, err
+	}
+
+	s.mu.Lock()
+	if s.generation == generation {
+		s.cache.Add(id, user)
+	}
+	s.mu.Unlock()
+
+	return user, nil
+}
+
+// Resolve returns the ID of the user with the given public ID from the wrapped store
+func (s *CachingUserStore) Resolve(ctx context.Context, publicID string) (int, error) {
+	return s.users.Resolve(ctx, publicID)
+}
+
+// Create stores a new user in the wrapped store. Nothing is invalidated, since
+// failed lookups aren't cached and so the new user can't be.
+func (s *CachingUserStore) Create(ctx context.Context, user models.User) (models.User, error) {
+	return s.users.Create(ctx, user)
+}
+
+// Update updates the user in the wrapped store and drops it from the cache
+func (s *CachingUserStore) Update(ctx context.Context, user models.User) (models.User, error) {
+	defer s.invalidate(user.ID)
+	return s.users.Update(ctx, user)
+}
+
+// Import imports users into the wrapped store and drops them from the cache,
+// since existing users may have been overwritten
+func (s *CachingUserStore) Import(ctx context.Context, users []models.User, policy DuplicatePolicy) (models.ImportSummary, error) {
+	ids := make([]int, len(users))
+	for i, user := range users {
+		ids[i] = user.ID
+	}
+	defer s.invalidate(ids...)
+
+	return s.users.Import(ctx, users, policy)
+}
+
+// Reset removes all users from the wrapped store and empties the cache.
+// It is the only way to delete users, since UserStore can't delete one alone.
+func (s *CachingUserStore) Reset(ctx context.Context) error {
+	defer func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		s.generation++
+		s.cache.Purge()
+	}()
+
+	return s.users.Reset(ctx)
+}
+
+// Ping reports whether the wrapped store can serve requests
+func (s *CachingUserStore) Ping(ctx context.Context) error {
+	return s.users.Ping(ctx)
+}
+
+// Stats returns the number of cache hits and misses so far
+func (s *CachingUserStore) Stats() CacheStats {
+	return CacheStats{
+		Hits:   s.hits.Load(),
+		Misses: s.misses.Load(),
+	}
+}
+
+// invalidate drops the users with the given IDs from the cache
+func (s *CachingUserStore) invalidate(ids ...int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.generation++
+	for _, id := range ids {
+		s.cache.Remove(id)
+	}
+}
+
+// currentGeneration returns the generation of the cache's contents
+func (s *CachingUserStore) currentGeneration() uint64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.generation
+}
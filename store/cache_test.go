@@ -0,0 +1,139 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/kakkoyun/demo-web-service/models"
+)
+
+// countingStore is a UserStore that counts the lookups reaching it
+type countingStore struct {
+	UserStore
+	gets int
+}
+
+func (s *countingStore) Get(ctx context.Context, id int) (models.User, error) {
+	s.gets++
+	return s.UserStore.Get(ctx, id)
+}
+
+func TestCachingUserStore(t *testing.T) {
+	ctx := context.Background()
+	backing := &countingStore{UserStore: NewMemoryStore(models.User{ID: 1, Name: "John Doe"})}
+	s := NewCachingUserStore(backing, 10, time.Minute)
+
+	// The second lookup is served from the cache
+	for range 2 {
+		user, err := s.Get(ctx, 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if user.Name != "John Doe" {
+			t.Errorf("store returned wrong user: got %+v", user)
+		}
+	}
+	if backing.gets != 1 {
+		t.Errorf("wrong number of lookups in the wrapped store: got %v want %v", backing.gets, 1)
+	}
+	if stats := s.Stats(); stats.Hits != 1 || stats.Misses != 1 {
+		t.Errorf("wrong cache stats: got %+v want 1 hit and 1 miss", stats)
+	}
+
+	// Updating the user drops it from the cache
+	if _, err := s.Update(ctx, models.User{ID: 1, Name: "Johnny Doe"}); err != nil {
+		t.Fatal(err)
+	}
+	user, err := s.Get(ctx, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if user.Name != "Johnny Doe" {
+		t.Errorf("store returned stale user after update: got %+v", user)
+	}
+	if backing.gets != 2 {
+		t.Errorf("wrong number of lookups in the wrapped store: got %v want %v", backing.gets, 2)
+	}
+}
+
+func TestCachingUserStoreInvalidation(t *testing.T) {
+	testCases := []struct {
+		write func(context.Context, *CachingUserStore) error
+		name  string
+	}{
+		{
+			name: "Update",
+			write: func(ctx context.Context, s *CachingUserStore) error {
+				_, err := s.Update(ctx, models.User{ID: 1, Name: "Updated"})
+				return err
+			},
+		},
+		{
+			name: "Import",
+			write: func(ctx context.Context, s *CachingUserStore) error {
+				_, err := s.Import(ctx, []models.User{{ID: 1, Name: "Imported"}}, DuplicateOverwrite)
+				return err
+			},
+		},
+		{
+			name:  "Reset",
+			write: func(ctx context.Context, s *CachingUserStore) error { return s.Reset(ctx) },
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := context.Background()
+			backing := &countingStore{UserStore: NewMemoryStore(models.User{ID: 1, Name: "John Doe"})}
+			s := NewCachingUserStore(backing, 10, time.Minute)
+
+			if _, err := s.Get(ctx, 1); err != nil {
+				t.Fatal(err)
+			}
+			if err := tc.write(ctx, s); err != nil {
+				t.Fatal(err)
+			}
+
+			// The lookup reaches the wrapped store again, whatever it finds there
+			_, _ = s.Get(ctx, 1)
+			if backing.gets != 2 {
+				t.Errorf("cached user was not invalidated: got %v lookups in the wrapped store want %v", backing.gets, 2)
+			}
+		})
+	}
+}
+
+func TestCachingUserStoreExpiry(t *testing.T) {
+	ctx := context.Background()
+	backing := &countingStore{UserStore: NewMemoryStore(models.User{ID: 1, Name: "John Doe"})}
+	s := NewCachingUserStore(backing, 10, 20*time.Millisecond)
+
+	if _, err := s.Get(ctx, 1); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(50 * time.Millisecond)
+	if _, err := s.Get(ctx, 1); err != nil {
+		t.Fatal(err)
+	}
+
+	if backing.gets != 2 {
+		t.Errorf("cached user did not expire: got %v lookups in the wrapped store want %v", backing.gets, 2)
+	}
+}
+
+func TestCachingUserStoreDoesNotCacheMisses(t *testing.T) {
+	ctx := context.Background()
+	backing := &countingStore{UserStore: NewMemoryStore()}
+	s := NewCachingUserStore(backing, 10, time.Minute)
+
+	for range 2 {
+		if _, err := s.Get(ctx, 42); !errors.Is(err, ErrNotFound) {
+			t.Fatalf("wrong error: got %v want %v", err, ErrNotFound)
+		}
+	}
+	if backing.gets != 2 {
+		t.Errorf("missing user was cached: got %v lookups in the wrapped store want %v", backing.gets, 2)
+	}
+}
@@ -0,0 +1,44 @@
+package store
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// IDGenerator generates the public IDs of newly stored users
+type IDGenerator interface {
+	// PublicID returns the public ID for a user with the given internal ID
+	PublicID(id int) string
+}
+
+// SequentialIDs is an IDGenerator that uses the sequential internal ID as the public ID
+type SequentialIDs struct{}
+
+// PublicID returns the internal ID in decimal
+func (SequentialIDs) PublicID(id int) string {
+	return strconv.Itoa(id)
+}
+
+// UUIDs is an IDGenerator that generates opaque random (version 4) UUIDs,
+// so public IDs don't reveal how many users exist
+type UUIDs struct{}
+
+// PublicID returns a new random UUID
+func (UUIDs) PublicID(int) string {
+	return uuid.NewString()
+}
+
+// NewIDGenerator returns the IDGenerator for a strategy name, either "sequential" or "uuid"
+func NewIDGenerator(strategy string) (IDGenerator, error) {
+	switch strings.ToLower(strings.TrimSpace(strategy)) {
+	case "sequential":
+		return SequentialIDs{}, nil
+	case "uuid":
+		return UUIDs{}, nil
+	default:
+		return nil, fmt.Errorf("unknown ID strategy %q: must be one of sequential or uuid", strategy)
+	}
+}
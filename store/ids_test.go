@@ -0,0 +1,116 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"strconv"
+	"testing"
+
+	"github.com/kakkoyun/demo-web-service/models"
+)
+
+func TestIDGenerators(t *testing.T) {
+	uuidPattern := regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+
+	testCases := []struct {
+		strategy string
+		pattern  *regexp.Regexp
+	}{
+		{strategy: "sequential", pattern: regexp.MustCompile(`^[1-9][0-9]*$`)},
+		{strategy: "uuid", pattern: uuidPattern},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.strategy, func(t *testing.T) {
+			ids, err := NewIDGenerator(tc.strategy)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			ctx := context.Background()
+			s := NewMemoryStoreWithIDs(ids, models.User{ID: 1, Name: "John Doe"})
+
+			seen := make(map[string]bool)
+			for range 100 {
+				user, err := s.Create(ctx, models.User{Name: "New User"})
+				if err != nil {
+					t.Fatal(err)
+				}
+
+				if !tc.pattern.MatchString(user.PublicID) {
+					t.Fatalf("public ID has wrong format: got %q", user.PublicID)
+				}
+				if seen[user.PublicID] {
+					t.Fatalf("public ID %q was generated twice", user.PublicID)
+				}
+				seen[user.PublicID] = true
+			}
+
+			// Seeded users get a public ID too
+			seeded, err := s.Get(ctx, 1)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !tc.pattern.MatchString(seeded.PublicID) || seen[seeded.PublicID] {
+				t.Errorf("seeded user has wrong public ID: got %q", seeded.PublicID)
+			}
+		})
+	}
+}
+
+func TestSequentialIDsMatchInternalIDs(t *testing.T) {
+	user, err := NewMemoryStore().Create(context.Background(), models.User{Name: "New User", PublicID: "chosen"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Clients can't pick their own public ID
+	if user.PublicID != "1" {
+		t.Errorf("wrong public ID: got %q want %q", user.PublicID, "1")
+	}
+}
+
+func TestNewIDGeneratorUnknownStrategy(t *testing.T) {
+	if _, err := NewIDGenerator("snowflake"); err == nil {
+		t.Error("expected an error for an unknown strategy")
+	}
+}
+
+func TestMemoryStoreResolve(t *testing.T) {
+	ctx := context.Background()
+	s := NewMemoryStoreWithIDs(UUIDs{}, models.User{ID: 1, Name: "John Doe"})
+
+	created, err := s.Create(ctx, models.User{Name: "New User"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	id, err := s.Resolve(ctx, created.PublicID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id != created.ID {
+		t.Errorf("resolved wrong ID: got %v want %v", id, created.ID)
+	}
+
+	// Internal IDs are not public IDs
+	if _, err := s.Resolve(ctx, strconv.Itoa(created.ID)); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound for an internal ID, got %v", err)
+	}
+
+	// A changed public ID no longer resolves
+	if _, err := s.Update(ctx, models.User{ID: created.ID, Name: "New User", PublicID: "renamed"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Resolve(ctx, created.PublicID); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound for a replaced public ID, got %v", err)
+	}
+
+	if err := s.Reset(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Resolve(ctx, "renamed"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound after a reset, got %v", err)
+	}
+}
@@ -0,0 +1,35 @@
+package store
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrDuplicateID is returned when an imported user's ID is already taken
+// and the import's DuplicatePolicy is DuplicateError, or when its public ID
+// belongs to another user whatever the policy
+var ErrDuplicateID = errors.New("duplicate user ID")
+
+// DuplicatePolicy decides what an import does with users whose ID is already taken
+type DuplicatePolicy string
+
+// Supported duplicate policies
+const (
+	// DuplicateSkip keeps the existing user and ignores the imported one
+	DuplicateSkip DuplicatePolicy = "skip"
+	// DuplicateOverwrite replaces the existing user with the imported one
+	DuplicateOverwrite DuplicatePolicy = "overwrite"
+	// DuplicateError rejects the whole import without storing any user
+	DuplicateError DuplicatePolicy = "error"
+)
+
+// ParseDuplicatePolicy parses a duplicate policy name, ignoring case
+func ParseDuplicatePolicy(s string) (DuplicatePolicy, error) {
+	switch p := DuplicatePolicy(strings.ToLower(strings.TrimSpace(s))); p {
+	case DuplicateSkip, DuplicateOverwrite, DuplicateError:
+		return p, nil
+	default:
+		return "", fmt.Errorf("unknown duplicate policy %q: must be one of skip, overwrite or error", s)
+	}
+}
@@ -0,0 +1,350 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/kakkoyun/demo-web-service/models"
+)
+
+// Ensure MemoryStore implements UserStore
+var _ UserStore = (*MemoryStore)(nil)
+
+// ErrNotFound is returned when a user does not exist in the store
+var ErrNotFound = errors.New("user not found")
+
+// ErrClosed is returned by Ping once a store has been closed
+var ErrClosed = errors.New("user store is closed")
+
+// MemoryStore is a concurrency-safe in-memory UserStore
+type MemoryStore struct {
+	ids   IDGenerator
+	users map[int]models.User
+	// publicIDs indexes the IDs of users by their public IDs
+	publicIDs map[string]int
+	// sortedIDs holds the IDs of all users in ascending order
+	sortedIDs []int
+	now       func() time.Time
+	mu        sync.RWMutex
+	nextID    int
+	closed    bool
+}
+
+// NewMemoryStore creates a new MemoryStore seeded with the given users,
+// using sequential public IDs.
+// IDs for newly created users continue after the highest seeded ID.
+func NewMemoryStore(seed ...models.User) *MemoryStore {
+	return NewMemoryStoreWithIDs(SequentialIDs{}, seed...)
+}
+
+// NewMemoryStoreWithIDs creates a new MemoryStore seeded with the given users,
+// generating public IDs with ids for users that don't have one
+func NewMemoryStoreWithIDs(ids IDGenerator, seed ...models.User) *MemoryStore {
+	s := &MemoryStore{
+		ids:       ids,
+		users:     make(map[int]models.User, len(seed)),
+		publicIDs: make(map[string]int, len(seed)),
+		now:       time.Now,
+	}
+
+	for _, user := range seed {
+		s.put(s.withPublicID(s.stamp(user, nil)))
+		if user.ID > s.nextID {
+			s.nextID = user.ID
+		}
+	}
+
+	return s
+}
+
+// SetClock sets the function the store reads the current time from when
+// timestamping users, e.g. a fixed time in tests
+func (s *MemoryStore) SetClock(now func() time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.now = now
+}
+
+// stamp sets a user's timestamps for storing it, replacing existing if not nil.
+// CreatedAt is kept from the existing user, or from the given one when it has it,
+// e.g. for imports; UpdatedAt is always the current time.
+// Times are stored in UTC, so they are unchanged by a round trip through JSON.
+func (s *MemoryStore) stamp(user models.User, existing *models.User) models.User {
+	now := s.now().UTC()
+
+	switch {
+	case existing != nil:
+		user.CreatedAt = existing.CreatedAt
+	case user.CreatedAt.IsZero():
+		user.CreatedAt = now
+	default:
+		user.CreatedAt = user.CreatedAt.UTC()
+	}
+	user.UpdatedAt = now
+
+	return user
+}
+
+// List returns all users ordered by ID
+func (s *MemoryStore) List(_ context.Context) ([]models.User, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	users := make([]models.User, len(s.sortedIDs))
+	for i, id := range s.sortedIDs {
+		users[i] = s.users[id]
+	}
+
+	return users, nil
+}
+
+// ListAfter returns up to limit users with an ID greater than after, ordered by ID.
+// The first ID is found by binary search in the sorted index, and only the
+// returned users are copied, so reading the store in batches stays cheap.
+func (s *MemoryStore) ListAfter(_ context.Context, after, limit int) ([]models.User, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	start, found := slices.BinarySearch(s.sortedIDs, after)
+	if found {
+		start++
+	}
+	ids := s.sortedIDs[start:]
+	if len(ids) > limit {
+		ids = ids[:max(limit, 0)]
+	}
+
+	users := make([]models.User, len(ids))
+	for i, id := range ids {
+		users[i] = s.users[id]
+	}
+
+	return users, nil
+}
+
+// Count returns the number of users whose name contains name, ignoring case,
+// without copying them
+func (s *MemoryStore) Count(_ context.Context, name string) (int, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if name == "" {
+		return len(s.users), nil
+	}
+
+	name = strings.ToLower(name)
+	count := 0
+	for _, user := range s.users {
+		if strings.Contains(strings.ToLower(user.Name), name) {
+			count++
+		}
+	}
+
+	return count, nil
+}
+
+// Get returns the user with the given ID
+func (s *MemoryStore) Get(_ context.Context, id int) (models.User, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	user, ok := s.users[id]
+	if !ok {
+		return models.User{}, ErrNotFound
+	}
+
+	return user, nil
+}
+
+// Create stores a new user, assigning it the next available ID and a generated public ID
+func (s *MemoryStore) Create(_ context.Context, user models.User) (models.User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.nextID++
+	user.ID = s.nextID
+	user.PublicID = ""
+	user.CreatedAt = time.Time{}
+	user = s.withPublicID(s.stamp(user, nil))
+	s.put(user)
+
+	return user, nil
+}
+
+// Update replaces the stored user with the same ID.
+// A user without a public ID keeps the one it already has.
+func (s *MemoryStore) Update(_ context.Context, user models.User) (models.User, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	existing, ok := s.users[user.ID]
+	if !ok {
+		return models.User{}, ErrNotFound
+	}
+
+	if user.PublicID == "" {
+		user.PublicID = existing.PublicID
+	}
+	user = s.stamp(user, &existing)
+	s.put(user)
+
+	return user, nil
+}
+
+// Resolve returns the ID of the user with the given public ID
+func (s *MemoryStore) Resolve(_ context.Context, publicID string) (int, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	id, ok := s.publicIDs[publicID]
+	if !ok {
+		return 0, ErrNotFound
+	}
+
+	return id, nil
+}
+
+// put stores user, indexing it by its public ID in place of the one it had
+// before, and adding its ID to the sorted index if it is new
+func (s *MemoryStore) put(user models.User) {
+	existing, ok := s.users[user.ID]
+	if ok && existing.PublicID != user.PublicID {
+		delete(s.publicIDs, existing.PublicID)
+	}
+	if !ok {
+		// New IDs are usually the highest yet, so this is mostly an append
+		i, _ := slices.BinarySearch(s.sortedIDs, user.ID)
+		s.sortedIDs = slices.Insert(s.sortedIDs, i, user.ID)
+	}
+	s.users[user.ID] = user
+	s.publicIDs[user.PublicID] = user.ID
+}
+
+// withPublicID assigns a generated public ID to a user that doesn't have one
+func (s *MemoryStore) withPublicID(user models.User) models.User {
+	if user.PublicID == "" {
+		user.PublicID = s.ids.PublicID(user.ID)
+	}
+	return user
+}
+
+// Import stores users with their explicit IDs.
+// With DuplicateError nothing is stored when any ID collides, so a failed
+// import can be fixed and retried as a whole.
+func (s *MemoryStore) Import(_ context.Context, users []models.User, policy DuplicatePolicy) (models.ImportSummary, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	summary := models.ImportSummary{Policy: string(policy)}
+
+	if policy == DuplicateError {
+		seen := make(map[int]bool, len(users))
+		for _, user := range users {
+			if _, ok := s.users[user.ID]; ok || seen[user.ID] {
+				return summary, fmt.Errorf("%w: %d", ErrDuplicateID, user.ID)
+			}
+			seen[user.ID] = true
+		}
+	}
+
+	if err := s.checkPublicIDs(users, policy); err != nil {
+		return summary, err
+	}
+
+	for _, user := range users {
+		existing, ok := s.users[user.ID]
+		if ok {
+			switch policy {
+			case DuplicateSkip:
+				summary.Skipped++
+				summary.SkippedIDs = append(summary.SkippedIDs, user.ID)
+				continue
+			case DuplicateOverwrite:
+				summary.Overwritten++
+				// Like Update, a user without a public ID keeps the one it has
+				if user.PublicID == "" {
+					user.PublicID = existing.PublicID
+				}
+			default:
+				return summary, fmt.Errorf("unknown duplicate policy %q", policy)
+			}
+			user = s.stamp(user, &existing)
+		} else {
+			summary.Imported++
+			user = s.stamp(user, nil)
+		}
+
+		s.put(s.withPublicID(user))
+		if user.ID > s.nextID {
+			s.nextID = user.ID
+		}
+	}
+
+	return summary, nil
+}
+
+// checkPublicIDs returns ErrDuplicateID if an imported user that would be
+// stored brings a public ID that belongs to another user, in the store or
+// earlier in the batch, so that an import can't take over someone's public ID
+func (s *MemoryStore) checkPublicIDs(users []models.User, policy DuplicatePolicy) error {
+	seen := make(map[int]bool, len(users))
+	claimed := make(map[string]int, len(users))
+	for _, user := range users {
+		_, exists := s.users[user.ID]
+		skipped := policy == DuplicateSkip && (exists || seen[user.ID])
+		seen[user.ID] = true
+		if user.PublicID == "" || skipped {
+			continue
+		}
+
+		owner, ok := claimed[user.PublicID]
+		if !ok {
+			owner, ok = s.publicIDs[user.PublicID]
+		}
+		if ok && owner != user.ID {
+			return fmt.Errorf("%w: public ID %q of user %d belongs to user %d", ErrDuplicateID, user.PublicID, user.ID, owner)
+		}
+		claimed[user.PublicID] = user.ID
+	}
+	return nil
+}
+
+// Close marks the store as closed, so that Ping fails and the service stops
+// reporting ready. Users stay readable, so requests in flight can finish.
+func (s *MemoryStore) Close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.closed = true
+}
+
+// Ping reports ErrClosed once the store has been closed; an in-memory store is
+// otherwise always reachable
+func (s *MemoryStore) Ping(_ context.Context) error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if s.closed {
+		return ErrClosed
+	}
+	return nil
+}
+
+// Reset removes all users and restarts ID assignment from the beginning
+func (s *MemoryStore) Reset(_ context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.users = make(map[int]models.User)
+	s.publicIDs = make(map[string]int)
+	s.sortedIDs = nil
+	s.nextID = 0
+
+	return nil
+}
@@ -0,0 +1,382 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/kakkoyun/demo-web-service/models"
+)
+
+func TestMemoryStore(t *testing.T) {
+	ctx := context.Background()
+	s := NewMemoryStore(
+		models.User{ID: 2, Name: "Jane Smith"},
+		models.User{ID: 1, Name: "John Doe"},
+	)
+
+	// Seeded users are listed in ID order
+	users, err := s.List(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(users) != 2 || users[0].ID != 1 || users[1].ID != 2 {
+		t.Fatalf("store returned wrong users: got %+v", users)
+	}
+
+	// New users continue after the highest seeded ID
+	created, err := s.Create(ctx, models.User{Name: "New User"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if created.ID != 3 {
+		t.Errorf("store assigned wrong ID: got %v want %v", created.ID, 3)
+	}
+
+	got, err := s.Get(ctx, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Name != "New User" {
+		t.Errorf("store returned wrong user name: got %v want %v", got.Name, "New User")
+	}
+
+	if _, err := s.Get(ctx, 42); !errors.Is(err, ErrNotFound) {
+		t.Errorf("store returned wrong error for missing user: got %v want %v", err, ErrNotFound)
+	}
+}
+
+func TestMemoryStoreListAfter(t *testing.T) {
+	s := NewMemoryStore(
+		models.User{ID: 5, Name: "Eve"},
+		models.User{ID: 1, Name: "Alice"},
+		models.User{ID: 3, Name: "Carol"},
+		models.User{ID: 2, Name: "Bob"},
+	)
+
+	testCases := []struct {
+		name     string
+		after    int
+		limit    int
+		expected []int
+	}{
+		{name: "From The Start", after: 0, limit: 2, expected: []int{1, 2}},
+		{name: "After An ID", after: 2, limit: 2, expected: []int{3, 5}},
+		{name: "After A Gap", after: 3, limit: 10, expected: []int{5}},
+		{name: "Past The End", after: 5, limit: 10, expected: []int{}},
+		{name: "Zero Limit", after: 0, limit: 0, expected: []int{}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			users, err := s.ListAfter(context.Background(), tc.after, tc.limit)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			ids := make([]int, len(users))
+			for i, user := range users {
+				ids[i] = user.ID
+			}
+			if !slices.Equal(ids, tc.expected) {
+				t.Errorf("store returned wrong users: got %v want %v", ids, tc.expected)
+			}
+		})
+	}
+}
+
+func TestMemoryStoreListAfterSeesWrites(t *testing.T) {
+	ctx := context.Background()
+	s := NewMemoryStore(models.User{ID: 2, Name: "Bob"}, models.User{ID: 8, Name: "Heidi"})
+
+	// Imported IDs land between existing ones, and updates add no IDs
+	if _, err := s.Import(ctx, []models.User{{ID: 5, Name: "Eve"}, {ID: 1, Name: "Alice"}}, DuplicateError); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Update(ctx, models.User{ID: 5, Name: "Eve Updated"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Create(ctx, models.User{Name: "Ivan"}); err != nil {
+		t.Fatal(err)
+	}
+
+	users, err := s.ListAfter(ctx, 1, 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ids := make([]int, len(users))
+	for i, user := range users {
+		ids[i] = user.ID
+	}
+	if expected := []int{2, 5, 8, 9}; !slices.Equal(ids, expected) {
+		t.Errorf("store returned wrong users: got %v want %v", ids, expected)
+	}
+}
+
+func TestMemoryStoreReset(t *testing.T) {
+	ctx := context.Background()
+	s := NewMemoryStore(models.User{ID: 1, Name: "John Doe"})
+
+	if _, err := s.Create(ctx, models.User{Name: "New User"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := s.Reset(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	users, err := s.List(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(users) != 0 {
+		t.Errorf("store was not cleared: got %v users", len(users))
+	}
+
+	// The ID counter starts over after a reset
+	created, err := s.Create(ctx, models.User{Name: "First Again"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if created.ID != 1 {
+		t.Errorf("ID counter was not reset: got %v want %v", created.ID, 1)
+	}
+}
+
+func TestMemoryStoreUpdate(t *testing.T) {
+	ctx := context.Background()
+	s := NewMemoryStore(models.User{ID: 1, Name: "John Doe"})
+
+	before, err := s.Get(ctx, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	updated, err := s.Update(ctx, models.User{ID: 1, Name: "Johnny Doe"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if updated.Name != "Johnny Doe" {
+		t.Errorf("store returned wrong user name: got %v want %v", updated.Name, "Johnny Doe")
+	}
+	// The public ID is kept when the update doesn't carry one
+	if updated.PublicID != before.PublicID {
+		t.Errorf("store changed the public ID: got %v want %v", updated.PublicID, before.PublicID)
+	}
+
+	if _, err := s.Update(ctx, models.User{ID: 42, Name: "Nobody"}); !errors.Is(err, ErrNotFound) {
+		t.Errorf("store returned wrong error for missing user: got %v want %v", err, ErrNotFound)
+	}
+}
+
+func TestMemoryStoreTimestamps(t *testing.T) {
+	ctx := context.Background()
+	s := NewMemoryStore()
+
+	created := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	now := created
+	s.SetClock(func() time.Time { return now })
+
+	user, err := s.Create(ctx, models.User{Name: "New User"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !user.CreatedAt.Equal(created) || !user.UpdatedAt.Equal(created) {
+		t.Errorf("store set wrong timestamps on create: got %v and %v want %v", user.CreatedAt, user.UpdatedAt, created)
+	}
+
+	now = created.Add(time.Hour)
+	user.Name = "Renamed User"
+	updated, err := s.Update(ctx, user)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !updated.CreatedAt.Equal(created) {
+		t.Errorf("store changed CreatedAt on update: got %v want %v", updated.CreatedAt, created)
+	}
+	if !updated.UpdatedAt.Equal(now) {
+		t.Errorf("store set wrong UpdatedAt on update: got %v want %v", updated.UpdatedAt, now)
+	}
+
+	// Overwriting a user by import is an update too
+	now = created.Add(2 * time.Hour)
+	if _, err := s.Import(ctx, []models.User{{ID: user.ID, Name: "Imported User"}}, DuplicateOverwrite); err != nil {
+		t.Fatal(err)
+	}
+	imported, err := s.Get(ctx, user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !imported.CreatedAt.Equal(created) || !imported.UpdatedAt.Equal(now) {
+		t.Errorf("store set wrong timestamps on import: got %v and %v want %v and %v", imported.CreatedAt, imported.UpdatedAt, created, now)
+	}
+}
+
+func TestMemoryStoreImport(t *testing.T) {
+	testCases := []struct {
+		expectedErr   error
+		policy        DuplicatePolicy
+		expectedName  string
+		expected      models.ImportSummary
+		expectedNext  int
+		expectedCount int
+	}{
+		{
+			policy:        DuplicateSkip,
+			expected:      models.ImportSummary{Policy: "skip", Imported: 1, Skipped: 1, SkippedIDs: []int{1}},
+			expectedName:  "John Doe",
+			expectedCount: 3,
+			expectedNext:  6,
+		},
+		{
+			policy:        DuplicateOverwrite,
+			expected:      models.ImportSummary{Policy: "overwrite", Imported: 1, Overwritten: 1},
+			expectedName:  "Johnny Doe",
+			expectedCount: 3,
+			expectedNext:  6,
+		},
+		{
+			policy:        DuplicateError,
+			expected:      models.ImportSummary{Policy: "error"},
+			expectedErr:   ErrDuplicateID,
+			expectedName:  "John Doe",
+			expectedCount: 2,
+			expectedNext:  3,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(string(tc.policy), func(t *testing.T) {
+			ctx := context.Background()
+			s := NewDemoStore()
+
+			// ID 1 collides with John Doe, ID 5 is new
+			summary, err := s.Import(ctx, []models.User{
+				{ID: 5, Name: "Imported User"},
+				{ID: 1, Name: "Johnny Doe"},
+			}, tc.policy)
+			if !errors.Is(err, tc.expectedErr) {
+				t.Fatalf("import returned wrong error: got %v want %v", err, tc.expectedErr)
+			}
+
+			if summary.Policy != tc.expected.Policy ||
+				summary.Imported != tc.expected.Imported ||
+				summary.Overwritten != tc.expected.Overwritten ||
+				summary.Skipped != tc.expected.Skipped ||
+				len(summary.SkippedIDs) != len(tc.expected.SkippedIDs) {
+				t.Errorf("import returned wrong summary: got %+v want %+v", summary, tc.expected)
+			}
+
+			got, err := s.Get(ctx, 1)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.Name != tc.expectedName {
+				t.Errorf("colliding user has wrong name: got %v want %v", got.Name, tc.expectedName)
+			}
+
+			users, err := s.List(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(users) != tc.expectedCount {
+				t.Errorf("store has wrong number of users: got %v want %v", len(users), tc.expectedCount)
+			}
+
+			// New users continue after the highest imported ID
+			created, err := s.Create(ctx, models.User{Name: "New User"})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if created.ID != tc.expectedNext {
+				t.Errorf("store assigned wrong ID: got %v want %v", created.ID, tc.expectedNext)
+			}
+		})
+	}
+}
+
+func TestMemoryStoreImportDuplicatesWithinBatch(t *testing.T) {
+	s := NewMemoryStore()
+
+	users := []models.User{{ID: 1, Name: "First"}, {ID: 1, Name: "Second"}}
+	if _, err := s.Import(context.Background(), users, DuplicateError); !errors.Is(err, ErrDuplicateID) {
+		t.Errorf("import returned wrong error: got %v want %v", err, ErrDuplicateID)
+	}
+
+	summary, err := s.Import(context.Background(), users, DuplicateSkip)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if summary.Imported != 1 || summary.Skipped != 1 {
+		t.Errorf("import returned wrong summary: got %+v", summary)
+	}
+}
+
+func TestMemoryStoreImportPublicIDs(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Collision", func(t *testing.T) {
+		s := NewMemoryStoreWithIDs(UUIDs{}, models.User{ID: 1, Name: "Alice"})
+		alice, err := s.Get(ctx, 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for _, policy := range []DuplicatePolicy{DuplicateSkip, DuplicateOverwrite, DuplicateError} {
+			users := []models.User{{ID: 2, Name: "Bob"}, {ID: 99, Name: "Mallory", PublicID: alice.PublicID}}
+			if _, err := s.Import(ctx, users, policy); !errors.Is(err, ErrDuplicateID) {
+				t.Errorf("import with %s returned wrong error: got %v want %v", policy, err, ErrDuplicateID)
+			}
+		}
+
+		// Alice's public ID still resolves to her, and nothing was imported
+		if id, err := s.Resolve(ctx, alice.PublicID); err != nil || id != 1 {
+			t.Errorf("public ID resolved to wrong user: got %v, %v want 1", id, err)
+		}
+		if _, err := s.Get(ctx, 2); !errors.Is(err, ErrNotFound) {
+			t.Errorf("user of a rejected import was stored: got %v want %v", err, ErrNotFound)
+		}
+	})
+
+	t.Run("Collision Within Batch", func(t *testing.T) {
+		s := NewMemoryStore()
+
+		users := []models.User{{ID: 1, Name: "First", PublicID: "shared"}, {ID: 2, Name: "Second", PublicID: "shared"}}
+		if _, err := s.Import(ctx, users, DuplicateOverwrite); !errors.Is(err, ErrDuplicateID) {
+			t.Errorf("import returned wrong error: got %v want %v", err, ErrDuplicateID)
+		}
+	})
+
+	t.Run("Overwrite Keeps Public ID", func(t *testing.T) {
+		s := NewMemoryStoreWithIDs(UUIDs{}, models.User{ID: 1, Name: "Alice"})
+		alice, err := s.Get(ctx, 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := s.Import(ctx, []models.User{{ID: 1, Name: "Alicia"}}, DuplicateOverwrite); err != nil {
+			t.Fatal(err)
+		}
+		got, err := s.Get(ctx, 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.Name != "Alicia" || got.PublicID != alice.PublicID {
+			t.Errorf("overwritten user is wrong: got %+v want public ID %q", got, alice.PublicID)
+		}
+	})
+}
+
+func TestParseDuplicatePolicy(t *testing.T) {
+	for _, s := range []string{"skip", "Overwrite", " ERROR "} {
+		if _, err := ParseDuplicatePolicy(s); err != nil {
+			t.Errorf("unexpected error for %q: %v", s, err)
+		}
+	}
+
+	if _, err := ParseDuplicatePolicy("merge"); err == nil {
+		t.Error("expected an error for an unknown policy")
+	}
+}
@@ -0,0 +1,105 @@
+package store
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/kakkoyun/demo-web-service/models"
+)
+
+// snapshot is the on-disk format of a MemoryStore
+type snapshot struct {
+	Users  []models.User `json:"users"`
+	NextID int           `json:"next_id"`
+}
+
+// SaveSnapshot writes the store's users to a JSON file.
+// The file is replaced atomically, so a crash while saving leaves the
+// previous snapshot intact.
+func (s *MemoryStore) SaveSnapshot(path string) error {
+	s.mu.RLock()
+	snap := snapshot{
+		Users:  make([]models.User, 0, len(s.users)),
+		NextID: s.nextID,
+	}
+	for _, user := range s.users {
+		snap.Users = append(snap.Users, user)
+	}
+	s.mu.RUnlock()
+
+	// Sorted, so that snapshots of the same users are identical
+	sort.Slice(snap.Users, func(i, j int) bool {
+		return snap.Users[i].ID < snap.Users[j].ID
+	})
+
+	data, err := json.Marshal(snap)
+	if err != nil {
+		return fmt.Errorf("encoding snapshot: %w", err)
+	}
+
+	return writeFileAtomic(path, data)
+}
+
+// LoadSnapshot replaces the store's users with those of a file written by SaveSnapshot
+func (s *MemoryStore) LoadSnapshot(path string) error {
+	data, err := os.ReadFile(filepath.Clean(path))
+	if err != nil {
+		return fmt.Errorf("reading snapshot: %w", err)
+	}
+
+	var snap snapshot
+	if err := json.Unmarshal(data, &snap); err != nil {
+		return fmt.Errorf("decoding snapshot %s: %w", path, err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.users = make(map[int]models.User, len(snap.Users))
+	s.publicIDs = make(map[string]int, len(snap.Users))
+	s.sortedIDs = make([]int, 0, len(snap.Users))
+	s.nextID = snap.NextID
+	for _, user := range snap.Users {
+		s.put(s.withPublicID(user))
+		if user.ID > s.nextID {
+			s.nextID = user.ID
+		}
+	}
+
+	return nil
+}
+
+// writeFileAtomic writes data to a temporary file next to path and renames it into place
+func writeFileAtomic(path string, data []byte) (err error) {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("creating temporary snapshot file: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			_ = os.Remove(tmp.Name())
+		}
+	}()
+
+	if _, err := tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("writing snapshot: %w", err)
+	}
+	// Flush to disk before the rename makes the file visible
+	if err := tmp.Sync(); err != nil {
+		_ = tmp.Close()
+		return fmt.Errorf("syncing snapshot: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("closing snapshot: %w", err)
+	}
+
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("replacing snapshot: %w", err)
+	}
+
+	return nil
+}
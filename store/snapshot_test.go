@@ -0,0 +1,85 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/kakkoyun/demo-web-service/models"
+)
+
+func TestMemoryStoreSnapshot(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "users.json")
+
+	s := NewDemoStore()
+	created, err := s.Create(ctx, models.User{Name: "New User"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := s.SaveSnapshot(path); err != nil {
+		t.Fatal(err)
+	}
+
+	restored := NewMemoryStore()
+	if err := restored.LoadSnapshot(path); err != nil {
+		t.Fatal(err)
+	}
+
+	want, err := s.List(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := restored.List(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != len(want) {
+		t.Fatalf("store restored wrong number of users: got %v want %v", len(got), len(want))
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("store restored wrong user: got %+v want %+v", got[i], want[i])
+		}
+	}
+
+	// ID assignment continues after the restored users
+	next, err := restored.Create(ctx, models.User{Name: "Next User"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if next.ID != created.ID+1 {
+		t.Errorf("store assigned wrong ID after restore: got %v want %v", next.ID, created.ID+1)
+	}
+
+	// No temporary files are left behind
+	entries, err := os.ReadDir(filepath.Dir(path))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("snapshot left extra files: got %v entries", len(entries))
+	}
+}
+
+func TestMemoryStoreLoadMissingSnapshot(t *testing.T) {
+	s := NewDemoStore()
+
+	err := s.LoadSnapshot(filepath.Join(t.TempDir(), "missing.json"))
+	if !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("load returned wrong error: got %v want %v", err, fs.ErrNotExist)
+	}
+
+	// The store is left untouched
+	users, err := s.List(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(users) != 2 {
+		t.Errorf("store was modified: got %v users", len(users))
+	}
+}
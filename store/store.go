@@ -0,0 +1,52 @@
+package store
+
+import (
+	"context"
+
+	"github.com/kakkoyun/demo-web-service/models"
+)
+
+// UserStore is the interface implemented by user persistence backends
+type UserStore interface {
+	// List returns all users ordered by ID
+	List(ctx context.Context) ([]models.User, error)
+	// ListAfter returns up to limit users with an ID greater than after,
+	// ordered by ID, so the store can be read in batches of bounded size
+	ListAfter(ctx context.Context, after, limit int) ([]models.User, error)
+	// Count returns the number of users whose name contains name, ignoring
+	// case, or of all users when name is empty
+	Count(ctx context.Context, name string) (int, error)
+	// Get returns the user with the given ID, or an error wrapping ErrNotFound
+	// when the lookup succeeded but there is no such user.
+	// Any other error means the lookup itself failed.
+	Get(ctx context.Context, id int) (models.User, error)
+	// Resolve returns the ID of the user with the given public ID, or an
+	// error wrapping ErrNotFound when there is no such user
+	Resolve(ctx context.Context, publicID string) (int, error)
+	// Create stores a new user and returns it with its assigned ID
+	Create(ctx context.Context, user models.User) (models.User, error)
+	// Update replaces the stored user with the same ID, or returns ErrNotFound
+	Update(ctx context.Context, user models.User) (models.User, error)
+	// Import stores users with explicit IDs, resolving ID collisions
+	// with existing or earlier imported users according to the policy
+	Import(ctx context.Context, users []models.User, policy DuplicatePolicy) (models.ImportSummary, error)
+	// Reset removes all users
+	Reset(ctx context.Context) error
+	// Ping reports whether the store can serve requests, e.g. whether its
+	// database is reachable; it backs the store's readiness check
+	Ping(ctx context.Context) error
+}
+
+// NewDemoStore creates a MemoryStore seeded with the demo users
+func NewDemoStore() *MemoryStore {
+	return NewDemoStoreWithIDs(SequentialIDs{})
+}
+
+// NewDemoStoreWithIDs creates a MemoryStore seeded with the demo users,
+// generating public IDs with ids
+func NewDemoStoreWithIDs(ids IDGenerator) *MemoryStore {
+	return NewMemoryStoreWithIDs(ids,
+		models.User{ID: 1, Name: "John Doe"},
+		models.User{ID: 2, Name: "Jane Smith"},
+	)
+}
@@ -0,0 +1,133 @@
+package store
+
+import (
+	"context"
+	"errors"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/kakkoyun/demo-web-service/models"
+)
+
+// tracerName identifies the spans started by this package
+const tracerName = "github.com/kakkoyun/demo-web-service/store"
+
+// TracingUserStore is a UserStore that traces every call to the store it wraps
+// with a child span of the span in the call's context
+type TracingUserStore struct {
+	users  UserStore
+	tracer trace.Tracer
+}
+
+// NewTracingUserStore wraps users, starting spans with tracers from tp
+func NewTracingUserStore(users UserStore, tp trace.TracerProvider) *TracingUserStore {
+	return &TracingUserStore{
+		users:  users,
+		tracer: tp.Tracer(tracerName),
+	}
+}
+
+// start starts a span for the store method op
+func (s *TracingUserStore) start(ctx context.Context, op string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return s.tracer.Start(ctx, "UserStore."+op, trace.WithAttributes(attrs...))
+}
+
+// endSpan ends span, marking it as failed when err is an error other than ErrNotFound,
+// which is an expected outcome rather than a failure of the store
+func endSpan(span trace.Span, err error) {
+	if err != nil && !errors.Is(err, ErrNotFound) {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// List returns all users ordered by ID
+func (s *TracingUserStore) List(ctx context.Context) ([]models.User, error) {
+	ctx, span := s.start(ctx, "List")
+	users, err := s.users.List(ctx)
+	span.SetAttributes(attribute.Int("users.count", len(users)))
+	endSpan(span, err)
+	return users, err
+}
+
+// ListAfter returns up to limit users with an ID greater than after
+func (s *TracingUserStore) ListAfter(ctx context.Context, after, limit int) ([]models.User, error) {
+	ctx, span := s.start(ctx, "ListAfter", attribute.Int("users.after", after), attribute.Int("users.limit", limit))
+	users, err := s.users.ListAfter(ctx, after, limit)
+	span.SetAttributes(attribute.Int("users.count", len(users)))
+	endSpan(span, err)
+	return users, err
+}
+
+// Count returns the number of users whose name contains name
+func (s *TracingUserStore) Count(ctx context.Context, name string) (int, error) {
+	ctx, span := s.start(ctx, "Count", attribute.Bool("filtered", name != ""))
+	count, err := s.users.Count(ctx, name)
+	span.SetAttributes(attribute.Int("users.count", count))
+	endSpan(span, err)
+	return count, err
+}
+
+// Get returns the user with the given ID
+func (s *TracingUserStore) Get(ctx context.Context, id int) (models.User, error) {
+	ctx, span := s.start(ctx, "Get", attribute.Int("user.id", id))
+	user, err := s.users.Get(ctx, id)
+	endSpan(span, err)
+	return user, err
+}
+
+// Resolve returns the ID of the user with the given public ID
+func (s *TracingUserStore) Resolve(ctx context.Context, publicID string) (int, error) {
+	ctx, span := s.start(ctx, "Resolve")
+	id, err := s.users.Resolve(ctx, publicID)
+	span.SetAttributes(attribute.Int("user.id", id))
+	endSpan(span, err)
+	return id, err
+}
+
+// Create stores a new user and returns it with its assigned ID
+func (s *TracingUserStore) Create(ctx context.Context, user models.User) (models.User, error) {
+	ctx, span := s.start(ctx, "Create")
+	created, err := s.users.Create(ctx, user)
+	span.SetAttributes(attribute.Int("user.id", created.ID))
+	endSpan(span, err)
+	return created, err
+}
+
+// Update replaces the stored user with the same ID
+func (s *TracingUserStore) Update(ctx context.Context, user models.User) (models.User, error) {
+	ctx, span := s.start(ctx, "Update", attribute.Int("user.id", user.ID))
+	updated, err := s.users.Update(ctx, user)
+	endSpan(span, err)
+	return updated, err
+}
+
+// Import stores users with explicit IDs
+func (s *TracingUserStore) Import(ctx context.Context, users []models.User, policy DuplicatePolicy) (models.ImportSummary, error) {
+	ctx, span := s.start(ctx, "Import",
+		attribute.Int("users.count", len(users)),
+		attribute.String("import.policy", string(policy)),
+	)
+	summary, err := s.users.Import(ctx, users, policy)
+	endSpan(span, err)
+	return summary, err
+}
+
+// Reset removes all users
+func (s *TracingUserStore) Reset(ctx context.Context) error {
+	ctx, span := s.start(ctx, "Reset")
+	err := s.users.Reset(ctx)
+	endSpan(span, err)
+	return err
+}
+
+// Ping reports whether the wrapped store can serve requests
+func (s *TracingUserStore) Ping(ctx context.Context) error {
+	ctx, span := s.start(ctx, "Ping")
+	err := s.users.Ping(ctx)
+	endSpan(span, err)
+	return err
+}
@@ -5,41 +5,22 @@ import (
 	"io"
 	"net/http"
 	"net/http/httptest"
-	"os"
 	"strings"
 	"testing"
 
 	"github.com/kakkoyun/demo-web-service/handlers"
 	"github.com/kakkoyun/demo-web-service/models"
+	"github.com/kakkoyun/demo-web-service/store"
 )
 
-// TestMain sets up the testing environment
-func TestMain(m *testing.M) {
-	// Enable test mode to disable random failures
-	handlers.TestMode = true
-
-	// Run all tests
-	exitCode := m.Run()
-
-	// Exit with the same code
-	os.Exit(exitCode)
-}
-
 // setupAPITest creates a test server with the application's routes
 func setupAPITest() *httptest.Server {
-	// Set up the routes similar to how main.go does it
-	mux := http.NewServeMux()
-
-	// Set up routes with Go 1.22 pattern syntax (via handler mapping)
-	mux.HandleFunc("GET /", handlers.HomeHandler)
-	mux.HandleFunc("GET /api/health", handlers.HealthCheckHandler)
-	mux.HandleFunc("GET /api/users", handlers.GetUsersHandler)
-	mux.HandleFunc("POST /api/users", handlers.CreateUserHandler)
-	mux.HandleFunc("GET /api/users/{id}", handlers.GetUserHandler)
+	// Set up the API similar to how main.go does it, without simulated failures
+	api := handlers.NewAPI(store.NewDemoStore(), nil, handlers.WithFaultInjector(handlers.NoFaults{}))
 
 	// Apply middleware
-	var handler http.Handler = mux
-	handler = handlers.LoggingMiddleware(handler)
+	handler := api.Routes()
+	handler = handlers.LoggingMiddleware(0)(handler)
 
 	// Create a test server
 	return httptest.NewServer(handler)
@@ -125,8 +106,8 @@ func TestAPIEndpoints(t *testing.T) {
 			t.Errorf("Expected user ID 1, got %d", response.User.ID)
 		}
 
-		if response.User.Name != "User 1" {
-			t.Errorf("Expected user name 'User 1', got %s", response.User.Name)
+		if response.User.Name != "John Doe" {
+			t.Errorf("Expected user name 'John Doe', got %s", response.User.Name)
 		}
 	})
 
@@ -223,14 +204,19 @@ func TestAPIEndpoints(t *testing.T) {
 			t.Fatalf("Failed to read response body: %v", err)
 		}
 
-		var response map[string]string
+		var response models.HealthResponse
 		if err := json.Unmarshal(body, &response); err != nil {
 			t.Fatalf("Failed to parse response JSON: %v", err)
 		}
 
 		// Verify response data
-		if response["status"] != "healthy" {
-			t.Errorf("Expected status 'healthy', got %v", response["status"])
+		if response.Status != "healthy" {
+			t.Errorf("Expected status 'healthy', got %v", response.Status)
+		}
+
+		// The previous test cases went through the logging middleware
+		if response.RequestsServed < 4 {
+			t.Errorf("Expected at least 4 requests served, got %d", response.RequestsServed)
 		}
 	})
 }